@@ -0,0 +1,99 @@
+package extract
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/dkotik/htadaptor/middleware/bearer"
+)
+
+var (
+	_ RequestValueExtractor = (singleClaimsValue)("")
+	_ StringValueExtractor  = (singleClaimsValue)("")
+	_ RequestValueExtractor = (multiClaimsValue)(nil)
+	_ StringValueExtractor  = (multiClaimsValue)(nil)
+)
+
+// NewClaimsValueExtractor is a [Extractor] extractor that pulls
+// out verified [bearer.Claims] values by claim name from an
+// [http.Request] context.
+//
+// Claims are trusted just like session values, so the same two
+// constraints are enforced:
+//
+// 1. Claims value extractors must be at the end of extractor lists.
+// 2. If claim is empty, any other values with the same name
+// are removed.
+func NewClaimsValueExtractor(keys ...string) (Extractor, error) {
+	total := len(keys)
+	if total == 0 {
+		return nil, errors.New("claims value extractor requires at least one claim name")
+	}
+	if err := uniqueNonEmptyValueNames(keys); err != nil {
+		return nil, err
+	}
+	if total == 1 {
+		return singleClaimsValue(keys[0]), nil
+	}
+	return multiClaimsValue(keys), nil
+}
+
+type singleClaimsValue string
+
+func (e singleClaimsValue) ExtractRequestValue(vs url.Values, r *http.Request) error {
+	desired := string(e)
+	claims, ok := bearer.ClaimsFromContext(r.Context())
+	if !ok {
+		delete(vs, desired) // important to prevent value ghosting
+		return nil
+	}
+	if value := claims.String(desired); value != "" {
+		vs[desired] = []string{value}
+	} else {
+		delete(vs, desired) // important to prevent value ghosting
+	}
+	return nil
+}
+
+func (e singleClaimsValue) ExtractStringValue(r *http.Request) (string, error) {
+	claims, ok := bearer.ClaimsFromContext(r.Context())
+	if !ok {
+		return "", ErrNoStringValue
+	}
+	if value := claims.String(string(e)); value != "" {
+		return value, nil
+	}
+	return "", ErrNoStringValue
+}
+
+type multiClaimsValue []string
+
+func (e multiClaimsValue) ExtractRequestValue(vs url.Values, r *http.Request) error {
+	claims, ok := bearer.ClaimsFromContext(r.Context())
+	for _, desired := range e {
+		if !ok {
+			delete(vs, desired) // important to prevent value ghosting
+			continue
+		}
+		if value := claims.String(desired); value != "" {
+			vs[desired] = []string{value}
+		} else {
+			delete(vs, desired) // important to prevent value ghosting
+		}
+	}
+	return nil
+}
+
+func (e multiClaimsValue) ExtractStringValue(r *http.Request) (string, error) {
+	claims, ok := bearer.ClaimsFromContext(r.Context())
+	if !ok {
+		return "", ErrNoStringValue
+	}
+	for _, desired := range e {
+		if value := claims.String(desired); value != "" {
+			return value, nil
+		}
+	}
+	return "", ErrNoStringValue
+}
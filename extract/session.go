@@ -16,15 +16,21 @@ var (
 	_ StringValueExtractor  = (multiSessionValue)(nil)
 )
 
-func IsSessionExtractor(extractor any) (ok bool) {
-	_, ok = extractor.(multiSessionValue)
-	if ok {
+// IsSessionExtractor returns true for extractors of trusted
+// values: session values, bearer token claims, and tenants.
+func IsSessionExtractor(extractor any) bool {
+	switch extractor.(type) {
+	case multiSessionValue, singleSessionValue, multiClaimsValue, singleClaimsValue, tenantValue:
 		return true
+	default:
+		return false
 	}
-	_, ok = extractor.(singleSessionValue)
-	return ok
 }
 
+// AreSessionExtractorsLast returns true if no other kind of extractor
+// follows a session value, a bearer token claims, or a tenant
+// extractor, even when nested in a [Sequence]. Otherwise, untrusted
+// request values could override trusted ones.
 func AreSessionExtractorsLast(extractors ...RequestValueExtractor) bool {
 	seenSessionExtractor := false
 	for _, extractor := range extractors {
@@ -35,7 +41,7 @@ func AreSessionExtractorsLast(extractors ...RequestValueExtractor) bool {
 			} else {
 				return false
 			}
-		case multiSessionValue, singleSessionValue:
+		case multiSessionValue, singleSessionValue, multiClaimsValue, singleClaimsValue, tenantValue:
 			seenSessionExtractor = true
 		default:
 			if seenSessionExtractor {
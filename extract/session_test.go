@@ -34,6 +34,35 @@ func TestEnsureSessionExtractorsAreLast(t *testing.T) {
 			},
 			Expected: false,
 		},
+		{
+			Sequence: []RequestValueExtractor{
+				singleQuery("test"),
+				singleClaimsValue("sub"),
+				singleSessionValue("test"),
+			},
+			Expected: true,
+		},
+		{
+			Sequence: []RequestValueExtractor{
+				multiClaimsValue([]string{"sub", "role"}),
+				singleCookie("test"),
+			},
+			Expected: false,
+		},
+		{
+			Sequence: []RequestValueExtractor{
+				singleHeader{RequestName: "X-Tenant", SchemaName: "tenant"},
+				tenantValue("tenant"),
+			},
+			Expected: true,
+		},
+		{
+			Sequence: []RequestValueExtractor{
+				tenantValue("tenant"),
+				singleHeader{RequestName: "X-Tenant", SchemaName: "tenant"},
+			},
+			Expected: false,
+		},
 	}
 
 	for i, c := range cases {
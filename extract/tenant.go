@@ -0,0 +1,50 @@
+package extract
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/dkotik/htadaptor/middleware/tenant"
+)
+
+var (
+	_ RequestValueExtractor = (tenantValue)("")
+	_ StringValueExtractor  = (tenantValue)("")
+)
+
+// NewTenantValueExtractor is a [Extractor] extractor that injects
+// the identifier of the [tenant.Tenant] resolved by the tenant
+// middleware under the given name.
+//
+// Tenants are trusted just like session values, so the same two
+// constraints are enforced:
+//
+// 1. Tenant value extractors must be at the end of extractor lists.
+// 2. If there is no tenant, any other values with the same name
+// are removed.
+func NewTenantValueExtractor(name string) (Extractor, error) {
+	if name == "" {
+		return nil, errors.New("tenant value extractor requires a parameter name")
+	}
+	return tenantValue(name), nil
+}
+
+type tenantValue string
+
+func (e tenantValue) ExtractRequestValue(vs url.Values, r *http.Request) error {
+	desired := string(e)
+	if t, ok := tenant.TenantFromContext(r.Context()); ok && t.ID != "" {
+		vs[desired] = []string{t.ID}
+	} else {
+		delete(vs, desired) // important to prevent value ghosting
+	}
+	return nil
+}
+
+func (e tenantValue) ExtractStringValue(r *http.Request) (string, error) {
+	if t, ok := tenant.TenantFromContext(r.Context()); ok && t.ID != "" {
+		return t.ID, nil
+	}
+	return "", ErrNoStringValue
+}
@@ -0,0 +1,223 @@
+package htadaptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dkotik/htadaptor/middleware/authorize"
+)
+
+// FileResponse is a file provided by a domain call to
+// [Adaptor.AdaptFileFunc]. The content is closed after serving,
+// if it implements [io.Closer].
+type FileResponse struct {
+	Content io.ReadSeeker
+	// Name is suggested to the client as the file name.
+	Name string
+	// ModTime enables If-Modified-Since requests, unless zero.
+	ModTime time.Time
+	// Size limits the content to the given number of bytes,
+	// unless zero, in which case the content is served to the end.
+	Size int64
+	// ContentType is detected from the name or contents, if empty.
+	ContentType string
+	// Inline asks browsers to display the file instead of saving it.
+	Inline bool
+}
+
+// AdaptFileFunc creates a new adaptor for a function that takes
+// a validatable struct and returns a file. The file is served
+// like [http.ServeContent], which answers range and conditional
+// requests, so that interrupted downloads can be resumed.
+func (a Adaptor) AdaptFileFunc[T any, V Validatable[T]](
+	domainCall func(context.Context, V) (FileResponse, error),
+	withOptions ...Option,
+) (http.Handler, error) {
+	if domainCall == nil {
+		return nil, errors.New("nil domain call")
+	}
+	o, err := a.initialize(withOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &FileFuncAdaptor[T, V]{
+		domainCall:    domainCall,
+		decoder:       o.Decoder,
+		errorHandler:  o.ErrorHandler,
+		authorization: o.Authorization,
+		instrument:    o.Instrument,
+	}, nil
+}
+
+// FileFuncAdaptor extracts a struct from request
+// and calls a domain function with it expecting
+// a file response.
+type FileFuncAdaptor[T any, V Validatable[T]] struct {
+	domainCall    func(context.Context, V) (FileResponse, error)
+	decoder       Decoder
+	errorHandler  ErrorHandler
+	authorization authorize.Policy
+	instrument    Instrument
+}
+
+func (a *FileFuncAdaptor[T, V]) executeDomainCall(
+	w http.ResponseWriter,
+	r *http.Request,
+) (err error) {
+	ctx := r.Context()
+	var request V = new(T)
+	_, end := beginStage(ctx, a.instrument, StageDecode)
+	if err = a.decoder.Decode(request, r); err != nil {
+		err = NewDecodingError(err)
+	}
+	if end(err); err != nil {
+		return err
+	}
+
+	_, end = beginStage(ctx, a.instrument, StageValidate)
+	err = request.Validate(ctx)
+	if end(err); err != nil {
+		return err
+	}
+	if err = authorizeRequest(ctx, a.instrument, a.authorization, request); err != nil {
+		return err
+	}
+	domainCtx, end := beginStage(ctx, a.instrument, StageDomainCall)
+	file, err := a.domainCall(domainCtx, request)
+	if end(err); err != nil {
+		return err
+	}
+	_, end = beginStage(ctx, a.instrument, StageEncode)
+	err = serveFile(w, r, file)
+	end(err)
+	return err
+}
+
+func serveFile(w http.ResponseWriter, r *http.Request, file FileResponse) (err error) {
+	if file.Content == nil {
+		return NewEncodingError(errors.New("file response has no content"))
+	}
+	if closer, ok := file.Content.(io.Closer); ok {
+		defer func() {
+			err = errors.Join(err, closer.Close())
+		}()
+	}
+	if file.Size < 0 {
+		return NewEncodingError(errors.New("file response size is negative"))
+	}
+	content := file.Content
+	if file.Size > 0 {
+		content = &limitedReadSeeker{ReadSeeker: file.Content, size: file.Size}
+	}
+
+	header := w.Header()
+	if file.ContentType != "" {
+		header.Set("content-type", file.ContentType)
+	}
+	disposition := "attachment"
+	if file.Inline {
+		disposition = "inline"
+	}
+	if file.Name != "" {
+		disposition = ContentDisposition(disposition, file.Name)
+	}
+	header.Set("content-disposition", disposition)
+	http.ServeContent(w, r, file.Name, file.ModTime, content)
+	return nil
+}
+
+// ContentDisposition formats a Content-Disposition header value
+// with an ASCII file name for older clients and an RFC 5987
+// encoded UTF-8 file name for the others. The encoded name is only
+// added when the ASCII name had to replace a character.
+func ContentDisposition(disposition, name string) string {
+	b := &strings.Builder{}
+	b.WriteString(disposition)
+	b.WriteString(`; filename="`)
+	altered := false
+	for _, c := range name {
+		if c == '"' || c == '\\' || c < 0x20 || c > 0x7e {
+			b.WriteByte('_')
+			altered = true
+			continue
+		}
+		b.WriteRune(c)
+	}
+	b.WriteByte('"')
+	if !altered {
+		return b.String()
+	}
+	b.WriteString("; filename*=UTF-8''")
+	const hex = "0123456789ABCDEF"
+	for _, c := range []byte(name) {
+		if isAttributeCharacter(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+	return b.String()
+}
+
+// isAttributeCharacter reports whether RFC 5987 allows
+// the byte in an extended value without percent encoding.
+func isAttributeCharacter(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
+
+// limitedReadSeeker presents the first size bytes of the content.
+type limitedReadSeeker struct {
+	io.ReadSeeker
+	size   int64
+	offset int64
+}
+
+func (l *limitedReadSeeker) Read(b []byte) (n int, err error) {
+	if l.offset >= l.size {
+		return 0, io.EOF
+	}
+	if remaining := l.size - l.offset; int64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+	n, err = l.ReadSeeker.Read(b)
+	l.offset += int64(n)
+	return n, err
+}
+
+func (l *limitedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += l.offset
+	case io.SeekEnd:
+		offset += l.size
+	}
+	if offset < 0 {
+		return 0, errors.New("cannot seek before the start of the file")
+	}
+	if _, err := l.ReadSeeker.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	l.offset = offset
+	return offset, nil
+}
+
+// ServeHTTP satisfies [http.Handler] interface.
+func (a *FileFuncAdaptor[T, V]) ServeHTTP(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	err := a.executeDomainCall(w, r)
+	if err != nil {
+		err = a.errorHandler.HandleError(w, r, err)
+	}
+}
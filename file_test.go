@@ -0,0 +1,84 @@
+package htadaptor_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkotik/htadaptor"
+)
+
+func TestFileRequest(t *testing.T) {
+	modified := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	mux := http.NewServeMux()
+	mux.Handle(
+		"GET /files/{UUID}",
+		htadaptor.Must(
+			htadaptor.New().AdaptFileFunc(
+				func(ctx context.Context, r *testRequest) (htadaptor.FileResponse, error) {
+					if r.UUID == "forbidden" {
+						return htadaptor.FileResponse{}, errors.New("access denied")
+					}
+					return htadaptor.FileResponse{
+						Content:     strings.NewReader("0123456789 and trailing bytes"),
+						Name:        "отчёт 2024.txt",
+						ModTime:     modified,
+						Size:        10,
+						ContentType: "text/plain; charset=utf-8",
+					}, nil
+				},
+				htadaptor.WithPathValues("UUID"),
+			),
+		),
+	)
+	serve := func(target string, header http.Header) *http.Response {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	response := serve("/files/report", nil)
+	body, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK || string(body) != "0123456789" {
+		t.Fatalf("unexpected response %d: %q", response.StatusCode, body)
+	}
+	if disposition := response.Header.Get("Content-Disposition"); disposition !=
+		`attachment; filename="_____ 2024.txt"; filename*=UTF-8''%D0%BE%D1%82%D1%87%D1%91%D1%82%202024.txt` {
+		t.Fatal("unexpected content disposition:", disposition)
+	}
+
+	response = serve("/files/report", http.Header{"Range": {"bytes=5-"}})
+	body, _ = io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusPartialContent || string(body) != "56789" {
+		t.Fatalf("unexpected partial response %d: %q", response.StatusCode, body)
+	}
+	response = serve("/files/report", http.Header{"If-Modified-Since": {modified.Format(http.TimeFormat)}})
+	if response.StatusCode != http.StatusNotModified {
+		t.Fatal("conditional request was not answered:", response.StatusCode)
+	}
+	if response = serve("/files/forbidden", nil); response.StatusCode != http.StatusInternalServerError {
+		t.Fatal("domain error was not handled:", response.StatusCode)
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	for name, expected := range map[string]string{
+		"report.pdf":   `inline; filename="report.pdf"`,
+		`say "hi".txt`: `inline; filename="say _hi_.txt"; filename*=UTF-8''say%20%22hi%22.txt`,
+		`a\b.txt`:      `inline; filename="a_b.txt"; filename*=UTF-8''a%5Cb.txt`,
+		"€ rates.csv":  `inline; filename="_ rates.csv"; filename*=UTF-8''%E2%82%AC%20rates.csv`,
+	} {
+		if actual := htadaptor.ContentDisposition("inline", name); actual != expected {
+			t.Fatalf("%q: expected %s, got %s", name, expected, actual)
+		}
+	}
+}
@@ -3,11 +3,15 @@ module github.com/dkotik/htadaptor
 go 1.27.0
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/coreos/go-systemd v0.0.0-20191104093116-d3cd4ed1dbcf
 	github.com/golang-jwt/jwt/v5 v5.2.0
 	github.com/gorilla/securecookie v1.1.2
 	github.com/lmittmann/tint v1.0.4
 	github.com/mitchellh/hashstructure/v2 v2.0.2
 	github.com/nicksnyder/go-i18n/v2 v2.4.0
-	golang.org/x/text v0.14.0
+	golang.org/x/crypto v0.57.0
+	golang.org/x/net v0.58.0
+	golang.org/x/text v0.42.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -1,14 +1,25 @@
 package htadaptor
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
 
-	"log/slog"
+	"golang.org/x/net/idna"
 )
 
+// HostSubdomainPathValue names the request path value that holds
+// the subdomain matched by a wildcard host name. Extract it using
+// [WithPathValues] or read it using [HostSubdomain].
+const HostSubdomainPathValue = "subdomain"
+
 // HostMuxAssociation assigns a handler to a host for [NewHostMux]
 // initialization.
 //
@@ -16,6 +27,14 @@ import (
 // the intended order of hosts is preserved in case
 // the resulting handler uses a list implementation internally.
 // Golang maps do not preserve the order of their keys or values.
+//
+// The Name is an exact host name, such as "example.com", a wildcard,
+// such as "*.example.com", which matches any depth of subdomains,
+// or "*", which handles every host that matches nothing else.
+// A name with a port, such as "example.com:8080", only matches
+// requests to that port and takes precedence over the same name
+// without one. Internationalized names are compared in their
+// ASCII form, so "bücher.example" matches "xn--bcher-kva.example".
 type HostMuxAssociation struct {
 	Name    string
 	Handler http.Handler
@@ -29,6 +48,8 @@ func NewHostMux(hostHandlers ...HostMuxAssociation) (http.Handler, error) {
 	}
 
 	handlers := make(mapHostMux)
+	exact := make(listHostMux, 0, len(hostHandlers))
+	mux := &hostMux{}
 	for _, association := range hostHandlers {
 		if association.Name == "" {
 			return nil, errors.New("cannot create host mux: cannot use an empty host name")
@@ -36,18 +57,142 @@ func NewHostMux(hostHandlers ...HostMuxAssociation) (http.Handler, error) {
 		if association.Handler == nil {
 			return nil, fmt.Errorf("cannot create host mux: host <%s> has a <nil> handler", association.Name)
 		}
-		if _, ok := handlers[association.Name]; ok {
+		if association.Name == "*" {
+			if mux.fallback != nil {
+				return nil, errors.New("cannot create host mux: fallback host already has a handler")
+			}
+			mux.fallback = association.Handler
+			continue
+		}
+		name, err := normalizeHostPattern(association.Name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create host mux: %w", err)
+		}
+		if suffix, ok := strings.CutPrefix(name, "*."); ok {
+			for _, existing := range mux.wildcards {
+				if existing.suffix == suffix {
+					return nil, fmt.Errorf("cannot create host mux: host <%s> already has a handler", association.Name)
+				}
+			}
+			mux.wildcards = append(mux.wildcards, wildcardHost{
+				suffix:  suffix,
+				handler: association.Handler,
+			})
+			continue
+		}
+		if _, ok := handlers[name]; ok {
 			return nil, fmt.Errorf("cannot create host mux: host <%s> already has a handler", association.Name)
 		}
-		handlers[association.Name] = association.Handler
+		handlers[name] = association.Handler
+		exact = append(exact, HostMuxAssociation{Name: name, Handler: association.Handler})
 	}
 
 	// mapHostMux will be faster than list at 8 entries
 	if len(handlers) >= 8 {
-		return handlers, nil
+		mux.exact = handlers
+	} else {
+		// preserves the original given order
+		mux.exact = exact
+	}
+	if len(mux.wildcards) == 0 && mux.fallback == nil {
+		return mux.exact, nil
+	}
+	// most specific suffix first
+	slices.SortStableFunc(mux.wildcards, func(a, b wildcardHost) int {
+		return cmp.Compare(len(b.suffix), len(a.suffix))
+	})
+	return mux, nil
+}
+
+// NewCanonicalHostRedirect permanently redirects requests to
+// the same path on the canonical host, such as from "www.example.com"
+// to "example.com". The scheme of the request is preserved, and
+// so is its port, unless the canonical host specifies one.
+func NewCanonicalHostRedirect(host string) (http.Handler, error) {
+	if host == "" {
+		return nil, errors.New("cannot redirect to an empty host name")
+	}
+	name, err := normalizeHostPattern(host)
+	if err != nil {
+		return nil, err
 	}
-	// preserves the original given order
-	return listHostMux(hostHandlers), nil
+	if strings.Contains(name, "*") {
+		return nil, fmt.Errorf("cannot redirect to a wildcard host <%s>", host)
+	}
+	return canonicalHostRedirect(name), nil
+}
+
+type canonicalHostRedirect string
+
+func (c canonicalHostRedirect) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	scheme := "http://"
+	if r.TLS != nil {
+		scheme = "https://"
+	}
+	host := string(c)
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		if _, port := requestHost(r); port != "" {
+			host = net.JoinHostPort(host, port)
+		}
+	}
+	NewPermanentRedirect(scheme+host+r.URL.RequestURI()).ServeHTTP(w, r)
+}
+
+type hostSubdomainKey struct{}
+
+// HostSubdomain returns the subdomain matched by a wildcard
+// host name of [NewHostMux], such as "tenant" for "tenant.example.com"
+// matched by "*.example.com".
+func HostSubdomain(ctx context.Context) string {
+	subdomain, _ := ctx.Value(hostSubdomainKey{}).(string)
+	return subdomain
+}
+
+// normalizeHost lowercases a host name, removes the trailing dot,
+// and converts internationalized names to their ASCII form.
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for i := 0; i < len(host); i++ {
+		if host[i] >= 0x80 {
+			return idna.Lookup.ToASCII(host)
+		}
+	}
+	return host, nil
+}
+
+func normalizeHostPattern(pattern string) (string, error) {
+	name, port := pattern, ""
+	if host, p, err := net.SplitHostPort(pattern); err == nil {
+		name, port = host, p
+	}
+	wildcard := strings.HasPrefix(name, "*.")
+	if wildcard {
+		name = name[2:]
+	}
+	name, err := normalizeHost(name)
+	if err != nil || name == "" || strings.Contains(name, "*") {
+		return "", fmt.Errorf("invalid host name <%s>", pattern)
+	}
+	if wildcard {
+		name = "*." + name
+	}
+	if port != "" {
+		return name + ":" + port, nil
+	}
+	return name, nil
+}
+
+// requestHost splits the normalized host name and the port,
+// which is empty, unless the client specified it.
+func requestHost(r *http.Request) (name, port string) {
+	name = r.Host
+	if host, p, err := net.SplitHostPort(r.Host); err == nil {
+		name, port = host, p
+	}
+	if normalized, err := normalizeHost(name); err == nil {
+		name = normalized
+	}
+	return name, port
 }
 
 func reportUnknownHost(w http.ResponseWriter, r *http.Request) {
@@ -57,16 +202,32 @@ func reportUnknownHost(w http.ResponseWriter, r *http.Request) {
 		r.Context(),
 		"received unknown host request",
 		slog.String("host", r.Host),
-		// TODO: unwind the request fields using a helper method.
-		// or should request be in context and injected using slog.Handler?
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("remote_address", r.RemoteAddr),
 	)
 }
 
+// hostTable finds handlers by exact host names.
+type hostTable interface {
+	http.Handler
+	lookup(name, port string) (http.Handler, bool)
+}
+
 type mapHostMux map[string]http.Handler
 
-func (h mapHostMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	name := r.URL.Hostname()
+func (h mapHostMux) lookup(name, port string) (http.Handler, bool) {
+	if port != "" {
+		if handler, ok := h[name+":"+port]; ok {
+			return handler, true
+		}
+	}
 	handler, ok := h[name]
+	return handler, ok
+}
+
+func (h mapHostMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, ok := h.lookup(requestHost(r))
 	if !ok {
 		reportUnknownHost(w, r)
 		return
@@ -76,13 +237,71 @@ func (h mapHostMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 type listHostMux []HostMuxAssociation
 
-func (l listHostMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	name := r.URL.Hostname()
+func (l listHostMux) lookup(name, port string) (http.Handler, bool) {
+	if port != "" {
+		withPort := name + ":" + port
+		for _, association := range l {
+			if association.Name == withPort {
+				return association.Handler, true
+			}
+		}
+	}
 	for _, association := range l {
 		if association.Name == name {
-			association.Handler.ServeHTTP(w, r)
+			return association.Handler, true
+		}
+	}
+	return nil, false
+}
+
+func (l listHostMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, ok := l.lookup(requestHost(r))
+	if !ok {
+		reportUnknownHost(w, r)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// wildcardHost matches subdomains of the suffix,
+// which may end with a port.
+type wildcardHost struct {
+	suffix  string
+	handler http.Handler
+}
+
+// hostMux adds wildcard and fallback hosts to exact host names.
+type hostMux struct {
+	exact     hostTable
+	wildcards []wildcardHost
+	fallback  http.Handler
+}
+
+func (h *hostMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, port := requestHost(r)
+	if handler, ok := h.exact.lookup(name, port); ok {
+		handler.ServeHTTP(w, r)
+		return
+	}
+	candidates := []string{name}
+	if port != "" {
+		candidates = []string{name + ":" + port, name}
+	}
+	for _, candidate := range candidates {
+		for _, wildcard := range h.wildcards {
+			subdomain, ok := strings.CutSuffix(candidate, "."+wildcard.suffix)
+			if !ok || subdomain == "" {
+				continue
+			}
+			r = r.WithContext(context.WithValue(r.Context(), hostSubdomainKey{}, subdomain))
+			r.SetPathValue(HostSubdomainPathValue, subdomain)
+			wildcard.handler.ServeHTTP(w, r)
 			return
 		}
 	}
+	if h.fallback != nil {
+		h.fallback.ServeHTTP(w, r)
+		return
+	}
 	reportUnknownHost(w, r)
 }
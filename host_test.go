@@ -3,6 +3,7 @@ package htadaptor
 import (
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -46,3 +47,132 @@ func TestHostMuxCreation(t *testing.T) {
 		t.Fatal("created mux is not a mapHostMux")
 	}
 }
+
+func TestHostMuxMatching(t *testing.T) {
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name+":"+r.PathValue(HostSubdomainPathValue)+":"+HostSubdomain(r.Context()))
+		})
+	}
+
+	mux, err := NewHostMux(
+		HostMuxAssociation{Name: "Example.com", Handler: named("apex")},
+		HostMuxAssociation{Name: "example.com:8080", Handler: named("port")},
+		HostMuxAssociation{Name: "*.example.com", Handler: named("tenant")},
+		HostMuxAssociation{Name: "*.eu.example.com", Handler: named("eu")},
+		HostMuxAssociation{Name: "*.example.com:9090", Handler: named("admin")},
+		HostMuxAssociation{Name: "bücher.example", Handler: named("idn")},
+		HostMuxAssociation{Name: "*", Handler: named("fallback")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for host, expected := range map[string]string{
+		"example.com":            "apex::",
+		"EXAMPLE.COM.":           "apex::",
+		"example.com:443":        "apex::",
+		"example.com:8080":       "port::",
+		"acme.example.com":       "tenant:acme:acme",
+		"a.b.example.com":        "tenant:a.b:a.b",
+		"acme.eu.example.com":    "eu:acme:acme",
+		"acme.example.com:9090":  "admin:acme:acme",
+		"xn--bcher-kva.example":  "idn::",
+		"unknown.org":            "fallback::",
+		"example.com.evil.org":   "fallback::",
+		"notexample.com":         "fallback::",
+		"[::1]:8080":             "fallback::",
+		"acme.example.com:12345": "tenant:acme:acme",
+	} {
+		t.Run(host, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Host = host
+			mux.ServeHTTP(w, r)
+			if body := w.Body.String(); body != expected {
+				t.Errorf("expected %q, got %q", expected, body)
+			}
+		})
+	}
+}
+
+func TestHostMuxUnknownHost(t *testing.T) {
+	mux, err := NewHostMux(HostMuxAssociation{
+		Name:    "example.com",
+		Handler: http.NotFoundHandler(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://other.com/", nil)
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	for _, invalid := range [][]HostMuxAssociation{
+		{{Name: "", Handler: http.NotFoundHandler()}},
+		{{Name: "example.com", Handler: nil}},
+		{{Name: "*.*.example.com", Handler: http.NotFoundHandler()}},
+		{
+			{Name: "*", Handler: http.NotFoundHandler()},
+			{Name: "*", Handler: http.NotFoundHandler()},
+		},
+		{
+			{Name: "*.example.com", Handler: http.NotFoundHandler()},
+			{Name: "*.EXAMPLE.com", Handler: http.NotFoundHandler()},
+		},
+	} {
+		if _, err = NewHostMux(invalid...); err == nil {
+			t.Errorf("expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestCanonicalHostRedirect(t *testing.T) {
+	redirect, err := NewCanonicalHostRedirect("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux, err := NewHostMux(HostMuxAssociation{Name: "www.example.com", Handler: redirect})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "https://www.example.com/docs?page=2", nil)
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusPermanentRedirect {
+		t.Errorf("expected status %d, got %d", http.StatusPermanentRedirect, w.Code)
+	}
+	if location := w.Header().Get("Location"); location != "https://example.com/docs?page=2" {
+		t.Errorf("unexpected location %q", location)
+	}
+
+	for host, expected := range map[string]string{
+		"www.example.com:8443": "https://example.com:8443/docs",
+		"www.example.com:443":  "https://example.com:443/docs",
+	} {
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(http.MethodGet, "https://"+host+"/docs", nil)
+		redirect.ServeHTTP(w, r)
+		if location := w.Header().Get("Location"); location != expected {
+			t.Errorf("expected location %q for host %q, got %q", expected, host, location)
+		}
+	}
+
+	withPort, err := NewCanonicalHostRedirect("example.com:9443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "https://www.example.com:8443/docs", nil)
+	withPort.ServeHTTP(w, r)
+	if location := w.Header().Get("Location"); location != "https://example.com:9443/docs" {
+		t.Errorf("unexpected location %q", location)
+	}
+
+	if _, err = NewCanonicalHostRedirect("*.example.com"); err == nil {
+		t.Error("expected an error for a wildcard host")
+	}
+}
@@ -0,0 +1,71 @@
+package htadaptor
+
+import (
+	"context"
+	"errors"
+)
+
+// Stage names a step that adaptors take to handle a request.
+// It is an alias, so that packages imported by htadaptor
+// can satisfy [Instrument] without an import cycle.
+type Stage = string
+
+const (
+	StageDecode     Stage = "decode"
+	StageValidate   Stage = "validate"
+	StageAuthorize  Stage = "authorize"
+	StageDomainCall Stage = "domain"
+	StageEncode     Stage = "encode"
+)
+
+// Instrument observes the stages of request handling in adaptors
+// for collecting metrics and tracing. BeginStage is called when
+// a stage starts. The returned function is called when the stage
+// ends with the error that the adaptor will report, if any.
+// The returned context is passed to the domain call.
+type Instrument interface {
+	BeginStage(context.Context, Stage) (context.Context, func(error))
+}
+
+// WithInstrument observes adaptor stages. Several instruments
+// are called in the order they were added.
+func WithInstrument(i Instrument) Option {
+	return func(o *options) error {
+		if i == nil {
+			return errors.New("cannot use a <nil> instrument")
+		}
+		if o.Instrument == nil {
+			o.Instrument = i
+			return nil
+		}
+		if existing, ok := o.Instrument.(instruments); ok {
+			o.Instrument = append(existing[:len(existing):len(existing)], i)
+			return nil
+		}
+		o.Instrument = instruments{o.Instrument, i}
+		return nil
+	}
+}
+
+type instruments []Instrument
+
+func (all instruments) BeginStage(ctx context.Context, s Stage) (context.Context, func(error)) {
+	ends := make([]func(error), len(all))
+	for i, instrument := range all {
+		ctx, ends[i] = instrument.BeginStage(ctx, s)
+	}
+	return ctx, func(err error) {
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](err)
+		}
+	}
+}
+
+func endStageNoop(error) {}
+
+func beginStage(ctx context.Context, i Instrument, s Stage) (context.Context, func(error)) {
+	if i == nil {
+		return ctx, endStageNoop
+	}
+	return i.BeginStage(ctx, s)
+}
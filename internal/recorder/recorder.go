@@ -0,0 +1,83 @@
+/*
+Package recorder captures the status code and the size of
+responses for the middleware that reports them, such as metrics,
+tracing, and access logs.
+*/
+package recorder
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter records the status code and the size of the response
+// body written through it. It passes [http.Flusher] and
+// [http.Hijacker] calls to the underlying writer and exposes it to
+// [http.ResponseController].
+type ResponseWriter struct {
+	http.ResponseWriter
+	code    int
+	written int64
+}
+
+// New wraps an [http.ResponseWriter].
+func New(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+// WriteHeader records the first final status code.
+// Informational responses, such as 103 Early Hints, are skipped.
+func (r *ResponseWriter) WriteHeader(code int) {
+	if r.code == 0 && (code >= http.StatusOK || code == http.StatusSwitchingProtocols) {
+		r.code = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *ResponseWriter) Write(b []byte) (n int, err error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	n, err = r.ResponseWriter.Write(b)
+	r.written += int64(n)
+	return n, err
+}
+
+// Flush supports streaming handlers that type assert [http.Flusher].
+func (r *ResponseWriter) Flush() {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack supports handlers that type assert [http.Hijacker],
+// such as WebSocket upgrades. A hijacked connection without
+// a status code is recorded as switching protocols.
+func (r *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && r.code == 0 {
+		r.code = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap exposes the underlying writer to [http.ResponseController].
+func (r *ResponseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// StatusCode returns the recorded status code
+// or [http.StatusOK], if the handler wrote nothing.
+func (r *ResponseWriter) StatusCode() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
+
+// Written returns the number of response body bytes.
+func (r *ResponseWriter) Written() int64 {
+	return r.written
+}
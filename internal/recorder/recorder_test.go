@@ -0,0 +1,45 @@
+package recorder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseWriter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := New(w)
+		rec.WriteHeader(http.StatusEarlyHints)
+		rec.WriteHeader(http.StatusCreated)
+		_, _ = rec.Write([]byte("created"))
+		if code := rec.StatusCode(); code != http.StatusCreated {
+			t.Errorf("recorded status %d instead of %d", code, http.StatusCreated)
+		}
+		if written := rec.Written(); written != 7 {
+			t.Errorf("recorded %d bytes instead of 7", written)
+		}
+	}))
+	defer server.Close()
+	if response, err := http.Get(server.URL); err == nil {
+		_ = response.Body.Close()
+	}
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := New(w)
+		conn, _, err := http.NewResponseController(rec).Hijack()
+		if err != nil {
+			t.Error("cannot hijack connection:", err)
+			return
+		}
+		_ = conn.Close()
+		if code := rec.StatusCode(); code != http.StatusSwitchingProtocols {
+			t.Errorf("recorded status %d for a hijacked connection", code)
+		}
+	}))
+	defer server.Close()
+	if response, err := http.Get(server.URL); err == nil {
+		_ = response.Body.Close()
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Package accesslog provides [htadaptor.Middleware] that records
+every request with its status, response size, and duration.
+
+	logger, err := accesslog.New(
+	  accesslog.WithFormat(accesslog.NewCombinedFormat(os.Stdout)),
+	  accesslog.WithSkip(health.IsProbe),
+	  accesslog.WithSampleRatio(0.1),
+	)
+	handler := sessions(tracer.Middleware()(logger(mux)))
+
+Place the middleware inside of the traceid and session middleware
+to include trace, session, and user attributes, and wrap
+the [http.ServeMux] directly to record the matched route pattern.
+
+[htadaptor.Middleware]: https://pkg.go.dev/github.com/dkotik/htadaptor#Middleware
+*/
+package accesslog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dkotik/htadaptor/internal/recorder"
+	"github.com/dkotik/htadaptor/middleware/session"
+	"github.com/dkotik/htadaptor/middleware/traceid"
+)
+
+// Entry describes a completed request.
+type Entry struct {
+	Time          time.Time
+	Duration      time.Duration
+	Method        string
+	Host          string
+	Path          string
+	Query         string
+	Protocol      string
+	Pattern       string
+	RemoteAddress string
+	Referer       string
+	UserAgent     string
+	Status        int
+	Bytes         int64
+	Headers       http.Header
+	// Attributes carry trace, session, and user identifiers.
+	Attributes []slog.Attr
+}
+
+// User returns the user identifier from the session attributes.
+func (e *Entry) User() string {
+	for _, attr := range e.Attributes {
+		if attr.Key == "user_id" {
+			return attr.Value.String()
+		}
+	}
+	return ""
+}
+
+// RemoteHost returns the remote address without the port.
+func (e *Entry) RemoteHost() string {
+	host, _, err := net.SplitHostPort(e.RemoteAddress)
+	if err != nil {
+		return e.RemoteAddress
+	}
+	return host
+}
+
+// URI returns the path with the redacted query.
+func (e *Entry) URI() string {
+	if e.Query == "" {
+		return e.Path
+	}
+	return e.Path + "?" + e.Query
+}
+
+type redactor struct {
+	headers         map[string]struct{}
+	queryParameters map[string]struct{}
+}
+
+// query replaces values of sensitive parameters preserving
+// the order of the raw query.
+func (r redactor) query(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		key, _, hasValue := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if _, ok := r.queryParameters[strings.ToLower(name)]; ok && hasValue {
+			pairs[i] = key + "=" + Redacted
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func (r redactor) referer(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	u.RawQuery = r.query(u.RawQuery)
+	return u.String()
+}
+
+func (r redactor) header(h http.Header, names []string) http.Header {
+	if len(names) == 0 {
+		return nil
+	}
+	selected := make(http.Header, len(names))
+	for _, name := range names {
+		values := h.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if _, ok := r.headers[name]; ok {
+			selected[name] = []string{Redacted}
+			continue
+		}
+		selected[name] = values
+	}
+	return selected
+}
+
+func contextAttributes(ctx context.Context) []slog.Attr {
+	if attrs := session.Attributes(ctx); len(attrs) > 0 {
+		return attrs
+	}
+	if c := traceid.SpanContextFromContext(ctx); c.IsValid() {
+		return []slog.Attr{
+			slog.String("trace_id", c.TraceID.String()),
+			slog.String("span_id", c.SpanID.String()),
+		}
+	}
+	return nil
+}
+
+// New creates middleware that writes an [Entry] for each
+// request using the chosen [Format].
+func New(withOptions ...Option) (func(http.Handler) http.Handler, error) {
+	o := &options{}
+	var err error
+	for _, option := range append(
+		withOptions,
+		WithDefaultFormat(),
+		WithDefaultSampleRatio(),
+		WithRedactedHeaders(DefaultRedactedHeaders...),
+		WithRedactedQueryParameters(DefaultRedactedQueryParameters...),
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create access log middleware: %w", err)
+		}
+	}
+
+	format := o.Format
+	sampleRatio := o.SampleRatio
+	skip := o.Skip
+	headers := o.Headers
+	redact := redactor{
+		headers:         o.RedactedHeaders,
+		queryParameters: o.RedactedQueryParameters,
+	}
+	return func(next http.Handler) http.Handler {
+		if next == nil {
+			panic("cannot use a <nil> next handler")
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, isSkipped := range skip {
+				if isSkipped(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			start := time.Now()
+			rec := recorder.New(w)
+			next.ServeHTTP(rec, r)
+			status := rec.StatusCode()
+			if status < http.StatusInternalServerError && sampleRatio < 1 && rand.Float64() >= sampleRatio {
+				return
+			}
+
+			ctx := r.Context()
+			_ = format.WriteEntry(ctx, &Entry{
+				Time:          start,
+				Duration:      time.Since(start),
+				Method:        r.Method,
+				Host:          r.Host,
+				Path:          r.URL.Path,
+				Query:         redact.query(r.URL.RawQuery),
+				Protocol:      r.Proto,
+				Pattern:       r.Pattern,
+				RemoteAddress: r.RemoteAddr,
+				Referer:       redact.referer(r.Referer()),
+				UserAgent:     r.UserAgent(),
+				Status:        status,
+				Bytes:         rec.Written(),
+				Headers:       redact.header(r.Header, headers),
+				Attributes:    contextAttributes(ctx),
+			})
+		})
+	}, nil
+}
@@ -0,0 +1,174 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dkotik/htadaptor/middleware/session"
+	"github.com/dkotik/htadaptor/middleware/traceid"
+)
+
+func newTestHandler(t *testing.T, withOptions ...Option) http.Handler {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("item"))
+	})
+	mux.HandleFunc("GET /fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "failure", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {})
+	logger, err := New(withOptions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger(mux)
+}
+
+func newTestRequest(target string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("user-agent", `test "agent"`)
+	r.Header.Set("referer", "https://example.com/login?token=secret&page=2")
+	r.Header.Set("authorization", "Bearer secret")
+	r.Header.Set("accept", "text/plain")
+	return r
+}
+
+func TestCombinedFormat(t *testing.T) {
+	b := &bytes.Buffer{}
+	h := newTestHandler(t, WithFormat(NewCombinedFormat(b)))
+	h.ServeHTTP(httptest.NewRecorder(), newTestRequest("/items/1?Password=hunter2&sort=asc"))
+
+	expected := regexp.MustCompile(`^192\.0\.2\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+		`"GET /items/1\?Password=REDACTED&sort=asc HTTP/1\.1" 200 4 ` +
+		`"https://example\.com/login\?token=REDACTED&page=2" "test \\"agent\\""\n$`)
+	if !expected.MatchString(b.String()) {
+		t.Fatalf("unexpected combined log line: %q", b.String())
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	b := &bytes.Buffer{}
+	h := newTestHandler(t,
+		WithFormat(NewJSONFormat(b)),
+		WithHeaders("authorization", "accept"),
+	)
+	tracer, err := traceid.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer.Middleware()(h).ServeHTTP(httptest.NewRecorder(), newTestRequest("/items/1"))
+
+	entry := make(map[string]any)
+	if err = json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["pattern"] != "GET /items/{id}" || entry["status"] != float64(200) || entry["bytes"] != float64(4) {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+	headers := entry["headers"].(map[string]any)
+	if headers["Authorization"].([]any)[0] != Redacted {
+		t.Fatal("authorization header was not redacted:", headers)
+	}
+	if headers["Accept"].([]any)[0] != "text/plain" {
+		t.Fatal("accept header was not logged:", headers)
+	}
+	if traceID, _ := entry["trace_id"].(string); len(traceID) != 32 {
+		t.Fatal("trace identifier is missing:", entry)
+	}
+}
+
+func TestStructuredFormat(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(b, nil))
+	sessions, err := session.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newTestHandler(t,
+		WithFormat(NewStructuredFormat(logger)),
+		WithSkip(func(r *http.Request) bool {
+			return strings.HasSuffix(r.URL.Path, "/livez")
+		}),
+	)
+
+	w := httptest.NewRecorder()
+	sessions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := session.Write(r.Context(), func(s session.Session) error {
+			s.SetUserID("gopher")
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := w.Result().Cookies()
+
+	h = sessions(h)
+	for _, target := range []string{"/livez", "/fail"} {
+		r := newTestRequest(target)
+		for _, cookie := range cookies {
+			r.AddCookie(cookie)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected one log line, got: %q", b.String())
+	}
+	record := make(map[string]any)
+	if err = json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["level"] != "ERROR" || record["status"] != float64(500) {
+		t.Fatalf("server error was not logged as an error: %+v", record)
+	}
+	if record["user_id"] != "gopher" {
+		t.Fatalf("session attributes are missing: %+v", record)
+	}
+	if _, ok := record["session_id"]; !ok {
+		t.Fatalf("session identifier is missing: %+v", record)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	b := &bytes.Buffer{}
+	h := newTestHandler(t,
+		WithFormat(NewJSONFormat(b)),
+		WithSampleRatio(0.000001),
+		WithExcludedPaths("/livez"),
+	)
+	for range 100 {
+		h.ServeHTTP(httptest.NewRecorder(), newTestRequest("/items/1"))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), newTestRequest("/livez"))
+	h.ServeHTTP(httptest.NewRecorder(), newTestRequest("/fail"))
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"status":500`) {
+		t.Fatalf("only the server error should be logged: %q", b.String())
+	}
+}
+
+func TestAttributesDoNotCreateSessions(t *testing.T) {
+	sessions, err := session.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	sessions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attrs := session.Attributes(r.Context()); attrs != nil {
+			t.Fatal("attributes of a missing session:", attrs)
+		}
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(w.Result().Cookies()) != 0 {
+		t.Fatal("reading attributes started a session")
+	}
+}
@@ -0,0 +1,175 @@
+package accesslog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Format writes access log entries.
+type Format interface {
+	WriteEntry(context.Context, *Entry) error
+}
+
+type structuredFormat struct {
+	logger *slog.Logger
+}
+
+// NewStructuredFormat logs each entry as a [slog.Record] with
+// [slog.LevelError] for server errors and [slog.LevelInfo] otherwise.
+func NewStructuredFormat(logger *slog.Logger) Format {
+	if logger == nil {
+		panic("cannot use a <nil> structured logger")
+	}
+	return &structuredFormat{logger: logger}
+}
+
+func (f *structuredFormat) WriteEntry(ctx context.Context, e *Entry) error {
+	level := slog.LevelInfo
+	if e.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	attrs := make([]slog.Attr, 0, 12+len(e.Attributes))
+	attrs = append(attrs,
+		slog.String("method", e.Method),
+		slog.String("host", e.Host),
+		slog.String("path", e.Path),
+	)
+	if e.Query != "" {
+		attrs = append(attrs, slog.String("query", e.Query))
+	}
+	if e.Pattern != "" {
+		attrs = append(attrs, slog.String("pattern", e.Pattern))
+	}
+	attrs = append(attrs,
+		slog.Int("status", e.Status),
+		slog.Int64("bytes", e.Bytes),
+		slog.Duration("duration", e.Duration),
+		slog.String("protocol", e.Protocol),
+		slog.String("remote_address", e.RemoteAddress),
+		slog.String("user_agent", e.UserAgent),
+	)
+	if e.Referer != "" {
+		attrs = append(attrs, slog.String("referer", e.Referer))
+	}
+	if len(e.Headers) > 0 {
+		headers := make([]any, 0, len(e.Headers))
+		for name, values := range e.Headers {
+			headers = append(headers, slog.String(name, strings.Join(values, ", ")))
+		}
+		attrs = append(attrs, slog.Group("headers", headers...))
+	}
+	attrs = append(attrs, e.Attributes...)
+	f.logger.LogAttrs(ctx, level, "HTTP request", attrs...)
+	return nil
+}
+
+type combinedFormat struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewCombinedFormat writes entries in the Apache Combined Log Format.
+// The user field is filled from the session user identifier.
+func NewCombinedFormat(w io.Writer) Format {
+	if w == nil {
+		panic("cannot use a <nil> writer")
+	}
+	return &combinedFormat{w: w}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// quote escapes characters that would break the quoted fields.
+func quote(s string) string {
+	quoted := strconv.Quote(orDash(s))
+	return quoted[1 : len(quoted)-1]
+}
+
+func (f *combinedFormat) WriteEntry(_ context.Context, e *Entry) error {
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = strconv.FormatInt(e.Bytes, 10)
+	}
+	b := &strings.Builder{}
+	b.WriteString(orDash(e.RemoteHost()))
+	b.WriteString(" - ")
+	b.WriteString(orDash(strings.ReplaceAll(e.User(), " ", "_")))
+	b.WriteString(" [")
+	b.WriteString(e.Time.Format("02/Jan/2006:15:04:05 -0700"))
+	b.WriteString(`] "`)
+	b.WriteString(quote(e.Method + " " + e.URI() + " " + e.Protocol))
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(e.Status))
+	b.WriteByte(' ')
+	b.WriteString(bytes)
+	b.WriteString(` "`)
+	b.WriteString(quote(e.Referer))
+	b.WriteString(`" "`)
+	b.WriteString(quote(e.UserAgent))
+	b.WriteString("\"\n")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := io.WriteString(f.w, b.String())
+	return err
+}
+
+type jsonFormat struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONFormat writes each entry as a JSON object on its own line.
+func NewJSONFormat(w io.Writer) Format {
+	if w == nil {
+		panic("cannot use a <nil> writer")
+	}
+	return &jsonFormat{encoder: json.NewEncoder(w)}
+}
+
+func (f *jsonFormat) WriteEntry(_ context.Context, e *Entry) error {
+	line := map[string]any{
+		"time":           e.Time,
+		"duration":       e.Duration.Seconds(),
+		"method":         e.Method,
+		"host":           e.Host,
+		"path":           e.Path,
+		"protocol":       e.Protocol,
+		"status":         e.Status,
+		"bytes":          e.Bytes,
+		"remote_address": e.RemoteAddress,
+		"user_agent":     e.UserAgent,
+	}
+	for key, value := range map[string]string{
+		"query":   e.Query,
+		"pattern": e.Pattern,
+		"referer": e.Referer,
+	} {
+		if value != "" {
+			line[key] = value
+		}
+	}
+	if len(e.Headers) > 0 {
+		line["headers"] = e.Headers
+	}
+	for _, attr := range e.Attributes {
+		if _, ok := line[attr.Key]; !ok { // entry fields take precedence
+			line[attr.Key] = attr.Value.Resolve().Any()
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.encoder.Encode(line)
+}
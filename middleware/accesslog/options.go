@@ -0,0 +1,174 @@
+package accesslog
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces values of sensitive headers and query parameters.
+const Redacted = "REDACTED"
+
+// Headers and query parameters that are always redacted.
+var (
+	DefaultRedactedHeaders = []string{
+		"Authorization",
+		"Proxy-Authorization",
+		"Cookie",
+		"Set-Cookie",
+		"X-Api-Key",
+		"X-Csrf-Token",
+	}
+	DefaultRedactedQueryParameters = []string{
+		"access_token",
+		"api_key",
+		"code",
+		"key",
+		"password",
+		"secret",
+		"token",
+	}
+)
+
+type options struct {
+	Format                  Format
+	SampleRatio             float64
+	Skip                    []func(*http.Request) bool
+	Headers                 []string
+	RedactedHeaders         map[string]struct{}
+	RedactedQueryParameters map[string]struct{}
+}
+
+type Option func(*options) error
+
+// WithFormat chooses how entries are written.
+func WithFormat(f Format) Option {
+	return func(o *options) error {
+		if f == nil {
+			return errors.New("cannot use a <nil> format")
+		}
+		if o.Format != nil {
+			return errors.New("format is already set")
+		}
+		o.Format = f
+		return nil
+	}
+}
+
+// WithDefaultFormat logs structured records to [slog.Default].
+func WithDefaultFormat() Option {
+	return func(o *options) error {
+		if o.Format != nil {
+			return nil
+		}
+		return WithFormat(NewStructuredFormat(slog.Default()))(o)
+	}
+}
+
+// WithSampleRatio logs the given fraction of requests. Responses
+// with server error status codes are always logged.
+func WithSampleRatio(ratio float64) Option {
+	return func(o *options) error {
+		if o.SampleRatio != 0 {
+			return errors.New("sample ratio is already set")
+		}
+		if ratio <= 0 || ratio > 1 || math.IsNaN(ratio) {
+			return errors.New("sample ratio must be above 0 and not greater than 1")
+		}
+		o.SampleRatio = ratio
+		return nil
+	}
+}
+
+func WithDefaultSampleRatio() Option {
+	return func(o *options) error {
+		if o.SampleRatio != 0 {
+			return nil
+		}
+		return WithSampleRatio(1)(o)
+	}
+}
+
+// WithSkip does not log requests that match the predicate.
+// Pass [health.IsProbe] to exclude health checks.
+//
+// [health.IsProbe]: https://pkg.go.dev/github.com/dkotik/htadaptor/service/health#IsProbe
+func WithSkip(isSkipped func(*http.Request) bool) Option {
+	return func(o *options) error {
+		if isSkipped == nil {
+			return errors.New("cannot use a <nil> skip predicate")
+		}
+		o.Skip = append(o.Skip, isSkipped)
+		return nil
+	}
+}
+
+// WithExcludedPaths does not log requests to the given paths.
+func WithExcludedPaths(paths ...string) Option {
+	return func(o *options) error {
+		if len(paths) == 0 {
+			return errors.New("cannot exclude an empty list of paths")
+		}
+		excluded := make(map[string]struct{}, len(paths))
+		for _, p := range paths {
+			if !strings.HasPrefix(p, "/") {
+				return errors.New("excluded path must begin with a slash")
+			}
+			excluded[p] = struct{}{}
+		}
+		return WithSkip(func(r *http.Request) bool {
+			_, ok := excluded[r.URL.Path]
+			return ok
+		})(o)
+	}
+}
+
+// WithHeaders includes the named request headers in each entry.
+func WithHeaders(names ...string) Option {
+	return func(o *options) error {
+		for _, name := range names {
+			if name == "" {
+				return errors.New("cannot use an empty header name")
+			}
+			o.Headers = append(o.Headers, http.CanonicalHeaderKey(name))
+		}
+		return nil
+	}
+}
+
+// WithRedactedHeaders hides values of the named headers in addition
+// to [DefaultRedactedHeaders].
+func WithRedactedHeaders(names ...string) Option {
+	return func(o *options) error {
+		if o.RedactedHeaders == nil {
+			o.RedactedHeaders = make(map[string]struct{})
+		}
+		for _, name := range names {
+			if name == "" {
+				return errors.New("cannot use an empty header name")
+			}
+			o.RedactedHeaders[http.CanonicalHeaderKey(name)] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// WithRedactedQueryParameters hides values of the named query
+// parameters in addition to [DefaultRedactedQueryParameters].
+// Parameter names are matched regardless of case.
+func WithRedactedQueryParameters(names ...string) Option {
+	return func(o *options) error {
+		if o.RedactedQueryParameters == nil {
+			o.RedactedQueryParameters = make(map[string]struct{})
+		}
+		for _, name := range names {
+			if name == "" {
+				return errors.New("cannot use an empty query parameter name")
+			}
+			o.RedactedQueryParameters[strings.ToLower(name)] = struct{}{}
+		}
+		return nil
+	}
+}
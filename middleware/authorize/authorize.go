@@ -0,0 +1,134 @@
+/*
+Package authorize provides declarative access [Policy] rules evaluated
+over the requesting [Subject] role, user ID, and the fields of decoded
+request structs.
+
+Policies guard adapted handlers using [htadaptor.WithAuthorization]
+option, which evaluates them after request decoding and validation
+but before the domain call. Route-wide guards that do not depend
+on request fields can use the middleware created by [New].
+*/
+package authorize
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorHandler reports authorization failures. It matches
+// [htadaptor.ErrorHandler], so any adaptor error handler can be used.
+type ErrorHandler interface {
+	HandleError(http.ResponseWriter, *http.Request, error) error
+}
+
+// ErrorHandlerFunc is a functional implementation of [ErrorHandler].
+type ErrorHandlerFunc func(http.ResponseWriter, *http.Request, error) error
+
+// HandleError satisfies [ErrorHandler] interface.
+func (f ErrorHandlerFunc) HandleError(w http.ResponseWriter, r *http.Request, err error) error {
+	return f(w, r, err)
+}
+
+type options struct {
+	Policy       Policy
+	ErrorHandler ErrorHandler
+}
+
+type Option func(*options) error
+
+// WithPolicy adds a [Policy] to the middleware. Several policies
+// are combined using [AllOf].
+func WithPolicy(p Policy) Option {
+	return func(o *options) error {
+		if p == nil {
+			return errors.New("cannot use a <nil> policy")
+		}
+		if o.Policy != nil {
+			o.Policy = AllOf(o.Policy, p)
+			return nil
+		}
+		o.Policy = p
+		return nil
+	}
+}
+
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(o *options) error {
+		if h == nil {
+			return errors.New("cannot use a <nil> error handler")
+		}
+		if o.ErrorHandler != nil {
+			return errors.New("error handler is already set")
+		}
+		o.ErrorHandler = h
+		return nil
+	}
+}
+
+func WithDefaultErrorHandler() Option {
+	return func(o *options) error {
+		if o.ErrorHandler != nil {
+			return nil
+		}
+		o.ErrorHandler = ErrorHandlerFunc(
+			func(w http.ResponseWriter, r *http.Request, err error) error {
+				code := http.StatusInternalServerError
+				var authorizationError Error
+				if errors.As(err, &authorizationError) {
+					code = authorizationError.HyperTextStatusCode()
+				}
+				http.Error(w, http.StatusText(code), code)
+				return err
+			},
+		)
+		return nil
+	}
+}
+
+type guard struct {
+	next         http.Handler
+	policy       Policy
+	errorHandler ErrorHandler
+}
+
+// New creates a middleware that evaluates [Policy] rules with
+// a <nil> request before passing the request to the next handler.
+func New(withOptions ...Option) (func(http.Handler) http.Handler, error) {
+	o := &options{}
+	var err error
+	for _, option := range append(
+		withOptions,
+		WithDefaultErrorHandler(),
+		func(o *options) error {
+			if o.Policy == nil {
+				return errors.New("at least one policy is required")
+			}
+			return nil
+		},
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create authorization middleware: %w", err)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		if next == nil {
+			panic("cannot use a <nil> next handler")
+		}
+		return &guard{
+			next:         next,
+			policy:       o.Policy,
+			errorHandler: o.ErrorHandler,
+		}
+	}, nil
+}
+
+func (g *guard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := g.policy.Authorize(ctx, SubjectFromContext(ctx), nil); err != nil {
+		_ = g.errorHandler.HandleError(w, r, err)
+		return
+	}
+	g.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,37 @@
+package authorize
+
+import "net/http"
+
+// Error signals that a [Policy] denied access.
+type Error uint8
+
+const (
+	// ErrUnauthenticated means the subject must identify itself first.
+	ErrUnauthenticated Error = iota + 1
+	// ErrForbidden means the identified subject lacks permission.
+	ErrForbidden
+)
+
+// HyperTextStatusCode satisfies [htadaptor.Error] interface.
+func (e Error) HyperTextStatusCode() int {
+	switch e {
+	case ErrUnauthenticated:
+		return http.StatusUnauthorized
+	case ErrForbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error satisfies [error] interface.
+func (e Error) Error() string {
+	switch e {
+	case ErrUnauthenticated:
+		return "authentication is required"
+	case ErrForbidden:
+		return "access is forbidden"
+	default:
+		return "unknown authorization error"
+	}
+}
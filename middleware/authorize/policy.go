@@ -0,0 +1,144 @@
+package authorize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Policy decides whether a [Subject] may proceed with a request.
+// The request is the decoded and validated domain request struct
+// when the policy is evaluated by an adaptor, a string for string
+// adaptors, and <nil> when evaluated by the middleware.
+//
+// Policies return <nil> to allow access, [ErrUnauthenticated] or
+// [ErrForbidden] to deny it, or any other error to signal failure.
+type Policy interface {
+	Authorize(context.Context, Subject, any) error
+}
+
+// PolicyFunc is a functional implementation of a [Policy].
+type PolicyFunc func(context.Context, Subject, any) error
+
+// Authorize satisfies [Policy] interface.
+func (f PolicyFunc) Authorize(ctx context.Context, s Subject, request any) error {
+	return f(ctx, s, request)
+}
+
+// NewRequestPolicy creates a [Policy] from a rule that expects
+// a particular request type. Requests of any other type fail
+// with an error that is neither [ErrUnauthenticated] nor
+// [ErrForbidden], because the policy was attached to the wrong
+// handler.
+func NewRequestPolicy[T any](rule func(context.Context, Subject, T) error) Policy {
+	if rule == nil {
+		panic("cannot use a <nil> request rule")
+	}
+	return PolicyFunc(func(ctx context.Context, s Subject, request any) error {
+		typed, ok := request.(T)
+		if !ok {
+			return fmt.Errorf("policy expects request of type %s, got %T", reflect.TypeFor[T](), request)
+		}
+		return rule(ctx, s, typed)
+	})
+}
+
+// RequireAuthentication allows any identified subject.
+func RequireAuthentication() Policy {
+	return PolicyFunc(func(_ context.Context, s Subject, _ any) error {
+		if !s.IsAuthenticated() {
+			return ErrUnauthenticated
+		}
+		return nil
+	})
+}
+
+// RequireRole allows identified subjects with any of the given roles.
+func RequireRole(roles ...string) Policy {
+	if len(roles) == 0 {
+		panic("provide at least one role")
+	}
+	return PolicyFunc(func(_ context.Context, s Subject, _ any) error {
+		if !s.IsAuthenticated() {
+			return ErrUnauthenticated
+		}
+		if !slices.Contains(roles, s.Role) {
+			return ErrForbidden
+		}
+		return nil
+	})
+}
+
+// RequireOwnership allows identified subjects whose user ID matches
+// the named string field of the decoded request struct. For example,
+// a request struct with an "OwnerID" field is only accessible by
+// its owner.
+func RequireOwnership(field string) Policy {
+	if field == "" {
+		panic("cannot use an empty field name")
+	}
+	return PolicyFunc(func(_ context.Context, s Subject, request any) error {
+		if !s.IsAuthenticated() {
+			return ErrUnauthenticated
+		}
+		v := reflect.Indirect(reflect.ValueOf(request))
+		if v.Kind() != reflect.Struct {
+			return fmt.Errorf("ownership policy requires a request struct, got %T", request)
+		}
+		f := v.FieldByName(field)
+		if !f.IsValid() || f.Kind() != reflect.String {
+			return fmt.Errorf("request %T does not have string field %q", request, field)
+		}
+		if owner := f.String(); owner == "" || owner != s.UserID {
+			return ErrForbidden
+		}
+		return nil
+	})
+}
+
+// AllOf allows access only when every policy allows it.
+// Returns the first denial.
+func AllOf(policies ...Policy) Policy {
+	ensureNonNil(policies)
+	return PolicyFunc(func(ctx context.Context, s Subject, request any) (err error) {
+		for _, policy := range policies {
+			if err = policy.Authorize(ctx, s, request); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AnyOf allows access when at least one policy allows it.
+// When all policies deny access, a forbidden denial takes precedence
+// over an unauthenticated one, because it is more specific.
+func AnyOf(policies ...Policy) Policy {
+	ensureNonNil(policies)
+	return PolicyFunc(func(ctx context.Context, s Subject, request any) (err error) {
+		var denial error
+		for _, policy := range policies {
+			err = policy.Authorize(ctx, s, request)
+			if err == nil {
+				return nil
+			}
+			if denial == nil || errors.Is(err, ErrForbidden) && !errors.Is(denial, ErrForbidden) {
+				denial = err
+			}
+		}
+		return denial
+	})
+}
+
+func ensureNonNil(policies []Policy) {
+	if len(policies) == 0 {
+		panic("provide at least one policy")
+	}
+	for i, policy := range policies {
+		if policy == nil {
+			panic(fmt.Sprintf("policy #%d is <nil>", i))
+		}
+	}
+}
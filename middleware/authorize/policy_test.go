@@ -0,0 +1,159 @@
+package authorize_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkotik/htadaptor"
+	"github.com/dkotik/htadaptor/middleware/authorize"
+)
+
+type documentRequest struct {
+	OwnerID string
+	Title   string
+}
+
+func (d *documentRequest) Validate(ctx context.Context) error {
+	if d.Title == "" {
+		return errors.New("title is required")
+	}
+	return nil
+}
+
+func TestPolicies(t *testing.T) {
+	guest := authorize.Subject{}
+	owner := authorize.Subject{UserID: "owner", Role: "user"}
+	admin := authorize.Subject{UserID: "admin", Role: "admin"}
+	document := &documentRequest{OwnerID: "owner", Title: "test"}
+
+	ownerOrAdmin := authorize.AnyOf(
+		authorize.RequireRole("admin"),
+		authorize.RequireOwnership("OwnerID"),
+	)
+	typedOwnership := authorize.NewRequestPolicy(
+		func(_ context.Context, s authorize.Subject, r *documentRequest) error {
+			if r.OwnerID != s.UserID {
+				return authorize.ErrForbidden
+			}
+			return nil
+		},
+	)
+
+	cases := []struct {
+		Name     string
+		Policy   authorize.Policy
+		Subject  authorize.Subject
+		Request  any
+		Expected error
+	}{
+		{"guest needs authentication", authorize.RequireAuthentication(), guest, nil, authorize.ErrUnauthenticated},
+		{"owner is authenticated", authorize.RequireAuthentication(), owner, nil, nil},
+		{"owner is not admin", authorize.RequireRole("admin"), owner, nil, authorize.ErrForbidden},
+		{"admin has role", authorize.RequireRole("editor", "admin"), admin, nil, nil},
+		{"owner owns document", ownerOrAdmin, owner, document, nil},
+		{"admin overrides ownership", ownerOrAdmin, admin, document, nil},
+		{"stranger is forbidden", ownerOrAdmin, authorize.Subject{UserID: "other"}, document, authorize.ErrForbidden},
+		{"guest is unauthenticated", ownerOrAdmin, guest, document, authorize.ErrUnauthenticated},
+		{"all of requires both", authorize.AllOf(
+			authorize.RequireRole("user"),
+			authorize.RequireOwnership("OwnerID"),
+		), owner, &documentRequest{OwnerID: "other"}, authorize.ErrForbidden},
+		{"typed ownership", typedOwnership, owner, document, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			err := c.Policy.Authorize(context.Background(), c.Subject, c.Request)
+			if c.Expected == nil {
+				if err != nil {
+					t.Fatal("unexpected denial:", err)
+				}
+				return
+			}
+			if !errors.Is(err, c.Expected) {
+				t.Fatalf("expected %q, got: %v", c.Expected, err)
+			}
+		})
+	}
+}
+
+func TestRequestPolicyTypeMismatch(t *testing.T) {
+	policy := authorize.NewRequestPolicy(
+		func(context.Context, authorize.Subject, *documentRequest) error {
+			return nil
+		},
+	)
+	err := policy.Authorize(context.Background(), authorize.Subject{UserID: "owner"}, "document")
+	if err == nil {
+		t.Fatal("request of the wrong type was allowed")
+	}
+	if errors.Is(err, authorize.ErrForbidden) || errors.Is(err, authorize.ErrUnauthenticated) {
+		t.Fatal("request type mismatch must not look like a denial:", err)
+	}
+}
+
+func TestAdaptorAuthorization(t *testing.T) {
+	called := false
+	h := htadaptor.Must(htadaptor.New(
+		htadaptor.WithAuthorization(authorize.RequireAuthentication()),
+	).AdaptVoidFunc(
+		func(ctx context.Context, r *documentRequest) error {
+			called = true
+			return nil
+		},
+		htadaptor.WithAuthorization(authorize.RequireOwnership("OwnerID")),
+		htadaptor.WithQueryValues("OwnerID", "Title"),
+	))
+
+	cases := []struct {
+		Name       string
+		Subject    authorize.Subject
+		Query      string
+		StatusCode int
+	}{
+		{"validation runs first", authorize.Subject{}, "OwnerID=owner", http.StatusInternalServerError},
+		{"guest", authorize.Subject{}, "OwnerID=owner&Title=test", http.StatusUnauthorized},
+		{"stranger", authorize.Subject{UserID: "other"}, "OwnerID=owner&Title=test", http.StatusForbidden},
+		{"owner", authorize.Subject{UserID: "owner"}, "OwnerID=owner&Title=test", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			called = false
+			r := httptest.NewRequest(http.MethodGet, "/?"+c.Query, nil)
+			r = r.WithContext(authorize.ContextWithSubject(r.Context(), c.Subject))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != c.StatusCode {
+				t.Fatalf("status code %d does not match expected %d: %s", w.Code, c.StatusCode, w.Body.String())
+			}
+			if called != (c.StatusCode == http.StatusNoContent) {
+				t.Fatal("domain call must only run for authorized requests")
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	mw, err := authorize.New(authorize.WithPolicy(authorize.RequireRole("admin")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for subject, expected := range map[authorize.Subject]int{
+		{}:                              http.StatusUnauthorized,
+		{UserID: "user", Role: "user"}:  http.StatusForbidden,
+		{UserID: "root", Role: "admin"}: http.StatusOK,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(authorize.ContextWithSubject(r.Context(), subject))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Fatalf("subject %+v: status code %d does not match expected %d", subject, w.Code, expected)
+		}
+	}
+}
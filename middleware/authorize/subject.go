@@ -0,0 +1,52 @@
+package authorize
+
+import (
+	"context"
+
+	"github.com/dkotik/htadaptor/middleware/bearer"
+	"github.com/dkotik/htadaptor/middleware/session"
+)
+
+type contextKeyType struct{}
+
+var contextKey = contextKeyType{}
+
+// Subject is the party requesting access to a resource.
+type Subject struct {
+	UserID string
+	Role   string
+}
+
+// IsAuthenticated returns true if the subject is a known user.
+func (s Subject) IsAuthenticated() bool {
+	return s.UserID != ""
+}
+
+// ContextWithSubject overrides the subject recovered by
+// [SubjectFromContext]. Use it to plug in other authentication
+// schemes or to exercise policies in tests.
+func ContextWithSubject(parent context.Context, s Subject) context.Context {
+	return context.WithValue(parent, contextKey, s)
+}
+
+// SubjectFromContext identifies the requesting party. A subject
+// placed using [ContextWithSubject] takes precedence over verified
+// [bearer.Claims], which take precedence over the [session.Session].
+// Returns an anonymous subject if none of them are present.
+func SubjectFromContext(ctx context.Context) (s Subject) {
+	if s, ok := ctx.Value(contextKey).(Subject); ok {
+		return s
+	}
+	if claims, ok := bearer.ClaimsFromContext(ctx); ok {
+		return Subject{
+			UserID: claims.Subject,
+			Role:   claims.Role,
+		}
+	}
+	_ = session.Read(ctx, func(current session.Session) error {
+		s.UserID = current.UserID()
+		s.Role = current.Role()
+		return nil
+	})
+	return s
+}
@@ -0,0 +1,168 @@
+/*
+Package bearer provides a middleware that authenticates requests
+carrying an "Authorization: Bearer" header, which suits API clients
+that cannot use a cookie-based [session].
+
+Verified [Claims] are placed into request [context.Context] and can
+be recovered using [ClaimsFromContext] or injected into decoded
+request structs using [extract.NewClaimsValueExtractor].
+*/
+package bearer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dkotik/htadaptor/middleware/session"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// leewayDecoder is implemented by tokenizers that validate
+// time-based claims themselves, such as the JWT tokenizers,
+// so that they tolerate the same clock skew as [WithLeeway].
+type leewayDecoder interface {
+	DecodeWithLeeway(data any, token string, leeway time.Duration) error
+}
+
+type authenticator struct {
+	next         http.Handler
+	tokenizer    session.Tokenizer
+	realm        string
+	issuers      []string
+	audience     string
+	leeway       time.Duration
+	errorHandler ErrorHandler
+}
+
+// New creates a middleware that rejects requests without a valid
+// bearer token with [http.StatusUnauthorized] and a matching
+// WWW-Authenticate challenge.
+func New(withOptions ...Option) (func(http.Handler) http.Handler, error) {
+	o := &options{}
+	var err error
+	for _, option := range append(
+		withOptions,
+		WithDefaultRealm(),
+		WithDefaultLeeway(),
+		WithDefaultErrorHandler(),
+		func(o *options) error {
+			if o.Tokenizer == nil {
+				return errors.New("tokenizer is required")
+			}
+			return nil
+		},
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create bearer token middleware: %w", err)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		if next == nil {
+			panic("cannot use a <nil> next handler")
+		}
+		return &authenticator{
+			next:         next,
+			tokenizer:    o.Tokenizer,
+			realm:        o.Realm,
+			issuers:      o.Issuers,
+			audience:     o.Audience,
+			leeway:       o.Leeway,
+			errorHandler: o.ErrorHandler,
+		}
+	}, nil
+}
+
+func (a *authenticator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	claims, err := a.authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", a.challenge(err))
+		_ = a.errorHandler.HandleError(w, r, err)
+		return
+	}
+	a.next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+}
+
+// authenticate recovers verified [Claims] from the request.
+func (a *authenticator) authenticate(r *http.Request) (*Claims, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, ErrMissingToken
+	}
+	scheme, token, _ := strings.Cut(header, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		// other schemes carry no bearer token, see RFC 6750 section 3.1
+		return nil, ErrMissingToken
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrMalformedHeader
+	}
+
+	var (
+		values map[string]any
+		err    error
+	)
+	if decoder, ok := a.tokenizer.(leewayDecoder); ok {
+		err = decoder.DecodeWithLeeway(&values, token, a.leeway)
+	} else {
+		err = a.tokenizer.Decode(&values, token)
+	}
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, ErrExpiredToken
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return nil, ErrPrematureToken
+	case err != nil:
+		return nil, errors.Join(ErrInvalidToken, err)
+	}
+	if len(values) == 0 {
+		// tokenizers leave values empty when signatures do not match
+		return nil, ErrInvalidToken
+	}
+
+	claims := NewClaims(values)
+	now := time.Now()
+	if claims.ExpiresAt.IsZero() {
+		return nil, ErrInvalidToken // tokens must expire
+	}
+	if now.After(claims.ExpiresAt.Add(a.leeway)) {
+		return nil, ErrExpiredToken
+	}
+	if !claims.NotBefore.IsZero() && now.Before(claims.NotBefore.Add(-a.leeway)) {
+		return nil, ErrPrematureToken
+	}
+	if len(a.issuers) > 0 && !slices.Contains(a.issuers, claims.Issuer) {
+		return nil, ErrIssuerMismatch
+	}
+	if a.audience != "" && !claims.HasAudience(a.audience) {
+		return nil, ErrAudienceMismatch
+	}
+	return claims, nil
+}
+
+// challenge builds the WWW-Authenticate header value
+// according to RFC 6750 section 3.
+func (a *authenticator) challenge(err error) string {
+	b := &strings.Builder{}
+	b.WriteString(`Bearer realm="`)
+	b.WriteString(a.realm)
+	b.WriteRune('"')
+
+	var bearerError Error
+	if !errors.As(err, &bearerError) {
+		bearerError = ErrInvalidToken
+	}
+	if code := bearerError.Code(); code != "" {
+		b.WriteString(`, error="`)
+		b.WriteString(code)
+		b.WriteString(`", error_description="`)
+		b.WriteString(bearerError.Error())
+		b.WriteRune('"')
+	}
+	return b.String()
+}
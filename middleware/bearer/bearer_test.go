@@ -0,0 +1,166 @@
+package bearer_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkotik/htadaptor"
+	"github.com/dkotik/htadaptor/middleware/bearer"
+	"github.com/dkotik/htadaptor/middleware/session/token/jwt"
+)
+
+type profileRequest struct {
+	UserID string `schema:"sub"`
+}
+
+func (r *profileRequest) Validate(ctx context.Context) error {
+	if r.UserID == "" {
+		return errors.New("user ID is required")
+	}
+	return nil
+}
+
+func TestBearerAuthentication(t *testing.T) {
+	tokens := jwt.New()
+	mw, err := bearer.New(
+		bearer.WithTokenizer(tokens),
+		bearer.WithIssuers("issuer"),
+		bearer.WithAudience("api"),
+		bearer.WithLeeway(time.Second*10),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(htadaptor.Must(htadaptor.New().AdaptFunc(
+		func(ctx context.Context, r *profileRequest) (string, error) {
+			claims, ok := bearer.ClaimsFromContext(ctx)
+			if !ok || claims.Subject != r.UserID {
+				return "", errors.New("claims were not placed into context")
+			}
+			return r.UserID, nil
+		},
+		htadaptor.WithQueryValues("sub"),
+		htadaptor.WithClaimsValues("sub"),
+	)))
+
+	issue := func(claims map[string]any) string {
+		if _, ok := claims["exp"]; !ok {
+			claims["exp"] = float64(time.Now().Add(time.Hour).Unix())
+		}
+		token, err := tokens.Encode(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	cases := []struct {
+		Name          string
+		Authorization string
+		StatusCode    int
+		Challenge     string
+		Body          string
+	}{
+		{
+			Name:       "missing token",
+			StatusCode: http.StatusUnauthorized,
+			Challenge:  `Bearer realm="api"`,
+		},
+		{
+			Name:          "wrong scheme",
+			Authorization: "Basic dXNlcjpwYXNz",
+			StatusCode:    http.StatusUnauthorized,
+			Challenge:     `Bearer realm="api"`,
+		},
+		{
+			Name:          "empty bearer token",
+			Authorization: "Bearer ",
+			StatusCode:    http.StatusBadRequest,
+			Challenge:     `error="invalid_request"`,
+		},
+		{
+			Name:          "garbage token",
+			Authorization: "Bearer garbage",
+			StatusCode:    http.StatusUnauthorized,
+			Challenge:     `error="invalid_token"`,
+			Body:          "bearer token is invalid",
+		},
+		{
+			Name: "wrong audience",
+			Authorization: "Bearer " + issue(map[string]any{
+				"sub": "user", "iss": "issuer", "aud": "other",
+			}),
+			StatusCode: http.StatusUnauthorized,
+			Challenge:  `error_description="bearer token was issued for a different audience"`,
+		},
+		{
+			Name: "untrusted issuer",
+			Authorization: "Bearer " + issue(map[string]any{
+				"sub": "user", "iss": "stranger", "aud": "api",
+			}),
+			StatusCode: http.StatusUnauthorized,
+			Challenge:  `error="invalid_token"`,
+		},
+		{
+			Name: "premature token",
+			Authorization: "Bearer " + issue(map[string]any{
+				"sub": "user", "iss": "issuer", "aud": "api",
+				"nbf": float64(time.Now().Add(time.Minute).Unix()),
+			}),
+			StatusCode: http.StatusUnauthorized,
+			Challenge:  `error_description="bearer token is not valid yet"`,
+		},
+		{
+			Name: "not before within leeway",
+			Authorization: "Bearer " + issue(map[string]any{
+				"sub": "user", "iss": "issuer", "aud": []string{"api", "web"},
+				"nbf": float64(time.Now().Add(time.Second * 5).Unix()),
+			}),
+			StatusCode: http.StatusOK,
+			Body:       `"user"`,
+		},
+		{
+			Name: "expired token",
+			Authorization: "Bearer " + issue(map[string]any{
+				"sub": "user", "iss": "issuer", "aud": "api",
+				"exp": float64(time.Now().Add(-time.Minute).Unix()),
+			}),
+			StatusCode: http.StatusUnauthorized,
+			Challenge:  `error_description="bearer token is expired"`,
+		},
+		{
+			Name: "expired within leeway",
+			Authorization: "Bearer " + issue(map[string]any{
+				"sub": "user", "iss": "issuer", "aud": "api",
+				"exp": float64(time.Now().Add(-time.Second * 5).Unix()),
+			}),
+			StatusCode: http.StatusOK,
+			Body:       `"user"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?sub=impostor", nil)
+			if c.Authorization != "" {
+				r.Header.Set("Authorization", c.Authorization)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != c.StatusCode {
+				t.Fatalf("status code %d does not match expected %d: %s", w.Code, c.StatusCode, w.Body.String())
+			}
+			if challenge := w.Header().Get("WWW-Authenticate"); !strings.Contains(challenge, c.Challenge) {
+				t.Fatalf("challenge %q does not contain %q", challenge, c.Challenge)
+			}
+			if c.Body != "" && strings.TrimSpace(w.Body.String()) != c.Body {
+				t.Fatalf("response %q does not match %q", w.Body.String(), c.Body)
+			}
+		})
+	}
+}
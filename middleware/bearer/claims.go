@@ -0,0 +1,132 @@
+package bearer
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+type contextKeyType struct{}
+
+var contextKey = contextKeyType{}
+
+// Claims are the verified contents of a bearer token.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	Role      string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	Values    map[string]any
+}
+
+// NewClaims parses registered claims from decoded token values.
+// Session tokens are supported as well: "expires" stands in for
+// "exp" and "user_id" for "sub".
+func NewClaims(values map[string]any) *Claims {
+	c := &Claims{Values: values}
+	c.Subject, _ = values["sub"].(string)
+	if c.Subject == "" {
+		c.Subject, _ = values["user_id"].(string)
+	}
+	c.Issuer, _ = values["iss"].(string)
+	c.Role, _ = values["role"].(string)
+	switch aud := values["aud"].(type) {
+	case string:
+		c.Audience = []string{aud}
+	case []string:
+		c.Audience = aud
+	case []any:
+		for _, v := range aud {
+			if s, ok := v.(string); ok {
+				c.Audience = append(c.Audience, s)
+			}
+		}
+	}
+	if exp, ok := numericDate(values["exp"]); ok {
+		c.ExpiresAt = exp
+	} else if exp, ok = numericDate(values["expires"]); ok {
+		c.ExpiresAt = exp
+	}
+	c.NotBefore, _ = numericDate(values["nbf"])
+	c.IssuedAt, _ = numericDate(values["iat"])
+	return c
+}
+
+func numericDate(v any) (time.Time, bool) {
+	// type switch is really important
+	// JWT tokens use float64, gob tokens preserve int64
+	switch v := v.(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case int64:
+		return time.Unix(v, 0), true
+	case int:
+		return time.Unix(int64(v), 0), true
+	case string:
+		cast, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(cast, 0), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// HasAudience returns true if the token was issued for the
+// given audience.
+func (c *Claims) HasAudience(audience string) bool {
+	for _, aud := range c.Audience {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns a raw claim value by name.
+func (c *Claims) Get(key string) any {
+	value, ok := c.Values[key]
+	if !ok {
+		return nil
+	}
+	return value
+}
+
+// String returns a claim value formatted as a string. Numbers
+// are formatted without exponents. Returns an empty string
+// if the claim is not present.
+func (c *Claims) String(key string) string {
+	switch v := c.Values[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
+
+// ContextWithClaims adds verified claims into context as a value.
+// Use [ClaimsFromContext] to recover them later.
+func ContextWithClaims(parent context.Context, c *Claims) context.Context {
+	return context.WithValue(parent, contextKey, c)
+}
+
+// ClaimsFromContext raises request-scoped claims placed
+// by the bearer middleware.
+func ClaimsFromContext(ctx context.Context) (c *Claims, ok bool) {
+	c, ok = ctx.Value(contextKey).(*Claims)
+	return c, ok && c != nil
+}
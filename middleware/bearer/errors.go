@@ -0,0 +1,63 @@
+package bearer
+
+import (
+	"net/http"
+)
+
+// Error signals a failure to authenticate a bearer token.
+type Error uint8
+
+const (
+	ErrMissingToken Error = iota
+	ErrMalformedHeader
+	ErrInvalidToken
+	ErrExpiredToken
+	ErrPrematureToken
+	ErrIssuerMismatch
+	ErrAudienceMismatch
+)
+
+// HyperTextStatusCode satisfies [htadaptor.Error] interface.
+func (e Error) HyperTextStatusCode() int {
+	switch e {
+	case ErrMalformedHeader:
+		return http.StatusBadRequest
+	default:
+		return http.StatusUnauthorized
+	}
+}
+
+// Error satisfies [error] interface.
+func (e Error) Error() string {
+	switch e {
+	case ErrMissingToken:
+		return "bearer token is required"
+	case ErrMalformedHeader:
+		return "authorization header has an empty bearer token"
+	case ErrInvalidToken:
+		return "bearer token is invalid"
+	case ErrExpiredToken:
+		return "bearer token is expired"
+	case ErrPrematureToken:
+		return "bearer token is not valid yet"
+	case ErrIssuerMismatch:
+		return "bearer token was issued by an untrusted party"
+	case ErrAudienceMismatch:
+		return "bearer token was issued for a different audience"
+	default:
+		return "unknown bearer token error"
+	}
+}
+
+// Code returns RFC 6750 error code for the
+// WWW-Authenticate challenge.
+func (e Error) Code() string {
+	switch e {
+	case ErrMissingToken:
+		return "" // section 3.1: no error information for missing credentials
+	case ErrMalformedHeader:
+		return "invalid_request"
+	default:
+		return "invalid_token"
+	}
+}
@@ -0,0 +1,171 @@
+package bearer
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dkotik/htadaptor/middleware/session"
+)
+
+// ErrorHandler reports authentication failures. It matches
+// [htadaptor.ErrorHandler], so any adaptor error handler can be used.
+type ErrorHandler interface {
+	HandleError(http.ResponseWriter, *http.Request, error) error
+}
+
+// ErrorHandlerFunc is a functional implementation of [ErrorHandler].
+type ErrorHandlerFunc func(http.ResponseWriter, *http.Request, error) error
+
+// HandleError satisfies [ErrorHandler] interface.
+func (f ErrorHandlerFunc) HandleError(w http.ResponseWriter, r *http.Request, err error) error {
+	return f(w, r, err)
+}
+
+type options struct {
+	Tokenizer    session.Tokenizer
+	Realm        string
+	Issuers      []string
+	Audience     string
+	Leeway       time.Duration
+	ErrorHandler ErrorHandler
+}
+
+type Option func(*options) error
+
+// WithTokenizer sets the [session.Tokenizer] that decodes and verifies
+// bearer tokens. Any JWT, HMAC, or verifier-only tokenizer will do.
+func WithTokenizer(t session.Tokenizer) Option {
+	return func(o *options) error {
+		if t == nil {
+			return errors.New("cannot use <nil> tokenizer")
+		}
+		if o.Tokenizer != nil {
+			return errors.New("tokenizer is already set")
+		}
+		o.Tokenizer = t
+		return nil
+	}
+}
+
+// WithRealm names the protection space in the WWW-Authenticate challenge.
+func WithRealm(realm string) Option {
+	return func(o *options) error {
+		if realm == "" {
+			return errors.New("cannot use an empty realm")
+		}
+		if strings.ContainsAny(realm, `"\`) {
+			return errors.New("realm cannot contain quotes or backslashes")
+		}
+		if o.Realm != "" {
+			return errors.New("realm is already set")
+		}
+		o.Realm = realm
+		return nil
+	}
+}
+
+func WithDefaultRealm() Option {
+	return func(o *options) error {
+		if o.Realm != "" {
+			return nil
+		}
+		o.Realm = "api"
+		return nil
+	}
+}
+
+// WithIssuers requires the "iss" claim to match one of the given values.
+func WithIssuers(issuers ...string) Option {
+	return func(o *options) error {
+		if len(issuers) == 0 {
+			return errors.New("provide at least one trusted issuer")
+		}
+		for _, issuer := range issuers {
+			if issuer == "" {
+				return errors.New("cannot use an empty issuer")
+			}
+		}
+		o.Issuers = append(o.Issuers, issuers...)
+		return nil
+	}
+}
+
+// WithAudience requires the "aud" claim to include the given value.
+func WithAudience(audience string) Option {
+	return func(o *options) error {
+		if audience == "" {
+			return errors.New("cannot use an empty audience")
+		}
+		if o.Audience != "" {
+			return errors.New("audience is already set")
+		}
+		o.Audience = audience
+		return nil
+	}
+}
+
+// WithLeeway tolerates clock skew between the issuer and this
+// service when checking "exp" and "nbf" claims.
+func WithLeeway(d time.Duration) Option {
+	return func(o *options) error {
+		if d < 0 {
+			return errors.New("cannot use a negative leeway")
+		}
+		if d > time.Minute*5 {
+			return errors.New("cannot use leeway greater than five minutes")
+		}
+		if o.Leeway != 0 {
+			return errors.New("leeway is already set")
+		}
+		o.Leeway = d
+		return nil
+	}
+}
+
+func WithDefaultLeeway() Option {
+	return func(o *options) error {
+		if o.Leeway != 0 {
+			return nil
+		}
+		o.Leeway = time.Second * 30
+		return nil
+	}
+}
+
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(o *options) error {
+		if h == nil {
+			return errors.New("cannot use a <nil> error handler")
+		}
+		if o.ErrorHandler != nil {
+			return errors.New("error handler is already set")
+		}
+		o.ErrorHandler = h
+		return nil
+	}
+}
+
+// WithDefaultErrorHandler responds with the message of [Error]
+// only. The returned error keeps the cause, such as the token
+// verification failure, for logging.
+func WithDefaultErrorHandler() Option {
+	return func(o *options) error {
+		if o.ErrorHandler != nil {
+			return nil
+		}
+		o.ErrorHandler = ErrorHandlerFunc(
+			func(w http.ResponseWriter, r *http.Request, err error) error {
+				code, message := http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized)
+				var bearerError Error
+				if errors.As(err, &bearerError) {
+					code, message = bearerError.HyperTextStatusCode(), bearerError.Error()
+				}
+				http.Error(w, message, code)
+				return err
+			},
+		)
+		return nil
+	}
+}
@@ -17,9 +17,16 @@ import (
 // for each [http.Request] and shuts down
 // an [http.Server] when the timer runs out. Use it for services
 // that are meant to scale down to zero or few instances.
+//
+// Requests matching any of the ignore predicates do not count
+// as activity. Pass [health.IsProbe] to keep health probes
+// from holding the service up.
+//
+// [health.IsProbe]: https://pkg.go.dev/github.com/dkotik/htadaptor/service/health#IsProbe
 func New(
 	parent context.Context,
 	shutdownAfter time.Duration,
+	ignore ...func(*http.Request) bool,
 ) (context.Context, htadaptor.Middleware) {
 	if shutdownAfter < time.Second {
 		panic("idle duration must be greater than zero")
@@ -34,7 +41,8 @@ func New(
 		case <-ctx.Done():
 			// nothing
 		case <-waitingOn:
-			cancel(fmt.Errorf("HTTP handlers were idle for more than %.2f minutes", float32(d)/float32(time.Minute)))
+			// wraps [context.Canceled], because idle shutdown is ordinary
+			cancel(fmt.Errorf("%w: HTTP handlers were idle for more than %.2f minutes", context.Canceled, float32(d)/float32(time.Minute)))
 		}
 	}(ctx, timer.C, shutdownAfter)
 
@@ -44,6 +52,12 @@ func New(
 		}
 		return http.HandlerFunc(
 			func(w http.ResponseWriter, r *http.Request) {
+				for _, isIgnored := range ignore {
+					if isIgnored(r) {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
 				// OPTIMIZE: documentation seems unclear if Reset is concurrency safe?
 				timer.Reset(shutdownAfter)
 				// if ResponseWriter is a reverse proxy, see:
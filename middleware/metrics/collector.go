@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// labelSeparator cannot appear in valid UTF-8 label values.
+const labelSeparator = "\xff"
+
+type series struct {
+	labels []string
+	value  float64
+}
+
+// vector is a family of series that share a name and label names.
+type vector struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func newVector(name, help, kind string, labels ...string) *vector {
+	return &vector{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+}
+
+func (v *vector) add(delta float64, values ...string) {
+	key := strings.Join(values, labelSeparator)
+	v.mu.Lock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labels: values}
+		v.series[key] = s
+	}
+	s.value += delta
+	v.mu.Unlock()
+}
+
+func (v *vector) writeTo(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	writeHeader(w, v.name, v.help, v.kind)
+	for _, key := range sortedKeys(v.series) {
+		s := v.series[key]
+		writeSample(w, v.name, v.labels, s.labels, "", "", s.value)
+	}
+}
+
+type histogramSeries struct {
+	labels  []string
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// histogram counts observations into cumulative buckets.
+type histogram struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+func newHistogram(name, help string, buckets []float64, labels ...string) *histogram {
+	return &histogram{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+}
+
+func (h *histogram) observe(value float64, values ...string) {
+	key := strings.Join(values, labelSeparator)
+	h.mu.Lock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labels:  values,
+			buckets: make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+	for i, upperBound := range h.buckets {
+		if value <= upperBound {
+			s.buckets[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+	h.mu.Unlock()
+}
+
+func (h *histogram) writeTo(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		for i, upperBound := range h.buckets {
+			writeSample(w, h.name+"_bucket", h.labels, s.labels, "le", formatFloat(upperBound), float64(s.buckets[i]))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, s.labels, "le", "+Inf", float64(s.count))
+		writeSample(w, h.name+"_sum", h.labels, s.labels, "", "", s.sum)
+		writeSample(w, h.name+"_count", h.labels, s.labels, "", "", float64(s.count))
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	_, _ = w.WriteString("# HELP " + name + " ")
+	_, _ = w.WriteString(strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	_, _ = w.WriteString("\n# TYPE " + name + " " + kind + "\n")
+}
+
+func writeSample(
+	w *bufio.Writer,
+	name string,
+	labels, values []string,
+	extraLabel, extraValue string,
+	value float64,
+) {
+	_, _ = w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		_ = w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				_ = w.WriteByte(',')
+			}
+			writeLabel(w, label, values[i])
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				_ = w.WriteByte(',')
+			}
+			writeLabel(w, extraLabel, extraValue)
+		}
+		_ = w.WriteByte('}')
+	}
+	_ = w.WriteByte(' ')
+	_, _ = w.WriteString(formatFloat(value))
+	_ = w.WriteByte('\n')
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func writeLabel(w *bufio.Writer, label, value string) {
+	_, _ = w.WriteString(label)
+	_, _ = w.WriteString(`="`)
+	_, _ = labelValueEscaper.WriteString(w, value)
+	_ = w.WriteByte('"')
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
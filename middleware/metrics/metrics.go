@@ -0,0 +1,237 @@
+/*
+Package metrics provides [htadaptor.Middleware] that records
+request metrics and a handler that exposes them in Prometheus
+text format without depending on the Prometheus client.
+
+	m, err := metrics.New()
+	adaptor := htadaptor.New(htadaptor.WithInstrument(m))
+	mux.Handle("GET /metrics", m.Handler())
+	handler := m.Middleware()(mux)
+
+Requests are labeled by the [http.Request.Pattern] that the
+[http.ServeMux] matched, which keeps the number of series bounded.
+Unmatched requests are labeled as "unmatched".
+*/
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/dkotik/htadaptor"
+	"github.com/dkotik/htadaptor/internal/recorder"
+)
+
+// ContentType of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Label values used when the real value would create
+// an unbounded number of series.
+const (
+	UnmatchedPattern = "unmatched"
+	OtherMethod      = "other"
+)
+
+var (
+	// DefaultLatencyBuckets in seconds match the Prometheus client defaults.
+	DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// DefaultSizeBuckets in bytes grow from 100B to 100MB.
+	DefaultSizeBuckets = []float64{100, 1000, 10_000, 100_000, 1_000_000, 10_000_000, 100_000_000}
+)
+
+type options struct {
+	LatencyBuckets []float64
+	SizeBuckets    []float64
+}
+
+type Option func(*options) error
+
+func validateBuckets(buckets []float64) error {
+	if len(buckets) == 0 {
+		return errors.New("cannot use empty buckets")
+	}
+	if !slices.IsSorted(buckets) {
+		return errors.New("buckets must be sorted in increasing order")
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] == buckets[i-1] {
+			return fmt.Errorf("bucket %s is repeated", formatFloat(buckets[i]))
+		}
+	}
+	return nil
+}
+
+// WithLatencyBuckets sets upper bounds in seconds of the request
+// duration histogram.
+func WithLatencyBuckets(buckets ...float64) Option {
+	return func(o *options) error {
+		if o.LatencyBuckets != nil {
+			return errors.New("latency buckets are already set")
+		}
+		if err := validateBuckets(buckets); err != nil {
+			return err
+		}
+		o.LatencyBuckets = slices.Clone(buckets)
+		return nil
+	}
+}
+
+func WithDefaultLatencyBuckets() Option {
+	return func(o *options) error {
+		if o.LatencyBuckets != nil {
+			return nil
+		}
+		return WithLatencyBuckets(DefaultLatencyBuckets...)(o)
+	}
+}
+
+// WithSizeBuckets sets upper bounds in bytes of the response
+// size histogram.
+func WithSizeBuckets(buckets ...float64) Option {
+	return func(o *options) error {
+		if o.SizeBuckets != nil {
+			return errors.New("size buckets are already set")
+		}
+		if err := validateBuckets(buckets); err != nil {
+			return err
+		}
+		o.SizeBuckets = slices.Clone(buckets)
+		return nil
+	}
+}
+
+func WithDefaultSizeBuckets() Option {
+	return func(o *options) error {
+		if o.SizeBuckets != nil {
+			return nil
+		}
+		return WithSizeBuckets(DefaultSizeBuckets...)(o)
+	}
+}
+
+// Registry collects request and adaptor metrics. It satisfies
+// [htadaptor.Instrument] to count failures of adaptor stages.
+type Registry struct {
+	requests *vector
+	inFlight *vector
+	failures *vector
+	latency  *histogram
+	size     *histogram
+}
+
+func New(withOptions ...Option) (*Registry, error) {
+	o := &options{}
+	var err error
+	for _, option := range append(
+		withOptions,
+		WithDefaultLatencyBuckets(),
+		WithDefaultSizeBuckets(),
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create metrics registry: %w", err)
+		}
+	}
+	return &Registry{
+		requests: newVector(
+			"http_requests_total",
+			"Total number of completed HTTP requests.",
+			"counter", "pattern", "method", "code",
+		),
+		inFlight: newVector(
+			"http_requests_in_flight",
+			"Number of HTTP requests being served.",
+			"gauge", "method",
+		),
+		failures: newVector(
+			"htadaptor_stage_failures_total",
+			"Total number of adaptor stage failures by response status code.",
+			"counter", "stage", "code",
+		),
+		latency: newHistogram(
+			"http_request_duration_seconds",
+			"Duration of HTTP requests in seconds.",
+			o.LatencyBuckets, "pattern", "method", "code",
+		),
+		size: newHistogram(
+			"http_response_size_bytes",
+			"Size of HTTP response bodies in bytes.",
+			o.SizeBuckets, "pattern", "method", "code",
+		),
+	}, nil
+}
+
+// normalizeMethod prevents clients from creating series
+// with arbitrary method names.
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost,
+		http.MethodPut, http.MethodPatch, http.MethodDelete,
+		http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	case "":
+		return http.MethodGet
+	default:
+		return OtherMethod
+	}
+}
+
+// Middleware records request count, duration, and response size.
+// Place it outside of the [http.ServeMux], which sets the matched
+// [http.Request.Pattern] in place during routing.
+func (m *Registry) Middleware() htadaptor.Middleware {
+	return func(next http.Handler) http.Handler {
+		if next == nil {
+			panic("cannot use a <nil> next handler")
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := normalizeMethod(r.Method)
+			m.inFlight.add(1, method)
+			defer m.inFlight.add(-1, method)
+
+			start := time.Now()
+			rec := recorder.New(w)
+			next.ServeHTTP(rec, r)
+
+			pattern := r.Pattern
+			if pattern == "" {
+				pattern = UnmatchedPattern
+			}
+			code := strconv.Itoa(rec.StatusCode())
+			m.requests.add(1, pattern, method, code)
+			m.latency.observe(time.Since(start).Seconds(), pattern, method, code)
+			m.size.observe(float64(rec.Written()), pattern, method, code)
+		})
+	}
+}
+
+// BeginStage satisfies [htadaptor.Instrument]. Failed stages
+// are counted by the status code that the error maps to
+// according to [htadaptor.GetHyperTextStatusCode].
+func (m *Registry) BeginStage(ctx context.Context, s htadaptor.Stage) (context.Context, func(error)) {
+	return ctx, func(err error) {
+		if err != nil {
+			m.failures.add(1, s, strconv.Itoa(htadaptor.GetHyperTextStatusCode(err)))
+		}
+	}
+}
+
+// Handler serves collected metrics in the text exposition format.
+func (m *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", ContentType)
+		w.Header().Set("cache-control", "no-store")
+		b := bufio.NewWriter(w)
+		m.requests.writeTo(b)
+		m.latency.writeTo(b)
+		m.size.writeTo(b)
+		m.inFlight.writeTo(b)
+		m.failures.writeTo(b)
+		_ = b.Flush()
+	})
+}
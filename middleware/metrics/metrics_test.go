@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dkotik/htadaptor"
+)
+
+type testRequest struct {
+	Name string
+}
+
+func (t *testRequest) Validate(ctx context.Context) error {
+	if t.Name == "" {
+		return errors.New("name is empty")
+	}
+	return nil
+}
+
+func TestMetrics(t *testing.T) {
+	m, err := New(WithLatencyBuckets(0.5, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	adaptor := htadaptor.New(htadaptor.WithInstrument(m))
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /greet", htadaptor.Must(adaptor.AdaptFunc(
+		func(ctx context.Context, r *testRequest) (string, error) {
+			if r.Name == "nobody" {
+				return "", htadaptor.NewNotFoundError("nobody")
+			}
+			return "hello " + r.Name, nil
+		},
+	)))
+	mux.Handle("GET /metrics", m.Handler())
+	h := m.Middleware()(mux)
+
+	for body, expectedCode := range map[string]int{
+		`{"Name":"gopher"}`: http.StatusOK,
+		`{"Name":"nobody"}`: http.StatusNotFound,
+		`{"Name":""}`:       http.StatusInternalServerError,
+		`{`:                 http.StatusUnprocessableEntity,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(body))
+		r.Header.Set("content-type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != expectedCode {
+			t.Fatalf("request %q status code %d does not match %d", body, w.Code, expectedCode)
+		}
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/coffee", nil))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Header().Get("content-type") != ContentType {
+		t.Fatal("unexpected content type:", w.Header().Get("content-type"))
+	}
+	exposition := w.Body.String()
+	for _, expected := range []string{
+		`http_requests_total{pattern="POST /greet",method="POST",code="200"} 1`,
+		`http_requests_total{pattern="POST /greet",method="POST",code="404"} 1`,
+		`http_requests_total{pattern="unmatched",method="other",code="404"} 1`,
+		`http_request_duration_seconds_bucket{pattern="POST /greet",method="POST",code="200",le="+Inf"} 1`,
+		`http_request_duration_seconds_count{pattern="POST /greet",method="POST",code="422"} 1`,
+		`http_response_size_bytes_count{pattern="POST /greet",method="POST",code="500"} 1`,
+		`http_requests_in_flight{method="GET"} 1`,
+		`htadaptor_stage_failures_total{stage="decode",code="422"} 1`,
+		`htadaptor_stage_failures_total{stage="validate",code="500"} 1`,
+		`htadaptor_stage_failures_total{stage="domain",code="404"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+	} {
+		if !strings.Contains(exposition, expected) {
+			t.Fatalf("exposition is missing %q:\n%s", expected, exposition)
+		}
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	m, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.requests.add(1, "GET /\"quoted\"\\\n", "GET", "200")
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	expected := `http_requests_total{pattern="GET /\"quoted\"\\\n",method="GET",code="200"} 1`
+	if !strings.Contains(w.Body.String(), expected) {
+		t.Fatalf("label value was not escaped:\n%s", w.Body.String())
+	}
+}
+
+func TestBucketValidation(t *testing.T) {
+	for _, buckets := range [][]float64{
+		{},
+		{1, 0.5},
+		{1, 1},
+	} {
+		if _, err := New(WithLatencyBuckets(buckets...)); err == nil {
+			t.Fatalf("buckets %v were accepted", buckets)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package session
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dkotik/htadaptor/middleware/session/secrets"
+)
+
+// aeadTokenizer encrypts gob-encoded session values with AES-GCM,
+// so that tokens can be neither read nor forged by clients. Nonces
+// are random and managed by the cipher. Token layout before base64
+// encoding is:
+//
+//	secret ID ∥ nonce ∥ ciphertext ∥ tag
+//
+// The secret ID selects the present or past key during rotation.
+// The associated data binds the token to the cookie name, so that
+// a token issued for one cookie is rejected by another.
+type aeadTokenizer struct {
+	associatedData []byte
+
+	wmu   *sync.Mutex
+	write *aeadKey
+
+	rmu     *sync.Mutex
+	present *aeadKey
+	past    *aeadKey
+}
+
+type aeadKey struct {
+	ID   []byte
+	AEAD cipher.AEAD
+}
+
+func newAEADKey(secret *secrets.Secret) (*aeadKey, error) {
+	if secret == nil {
+		return nil, errors.New("cannot use a <nil> secret")
+	}
+	block, err := aes.NewCipher(secret.Entropy)
+	if err != nil {
+		return nil, fmt.Errorf("secret entropy must be 16, 24, or 32 bytes long: %w", err)
+	}
+	aead, err := cipher.NewGCMWithRandomNonce(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aeadKey{ID: secret.ID, AEAD: aead}, nil
+}
+
+func (k *aeadKey) open(b, associatedData []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(b, k.ID) {
+		return nil, false
+	}
+	b = b[len(k.ID):]
+	if len(b) < k.AEAD.Overhead() {
+		return nil, false
+	}
+	plain, err := k.AEAD.Open(nil, nil, b, associatedData)
+	if err != nil {
+		return nil, false
+	}
+	return plain, true
+}
+
+// NewEncryptedTokenizer creates a [Tokenizer] that encrypts session
+// values using AES-GCM authenticated encryption with rotating secrets.
+// The name should match the cookie name, because it is used as
+// associated data that binds each token to its cookie. The secret
+// entropy size must be 16, 24, or 32 bytes, which selects AES-128,
+// AES-192, or AES-256. The default entropy size of 32 selects AES-256.
+func NewEncryptedTokenizer(name string, withOptions ...secrets.Option) (Tokenizer, error) {
+	if name == "" {
+		return nil, errors.New("cannot create encrypted tokenizer: name is required")
+	}
+	t := &aeadTokenizer{
+		associatedData: []byte(name),
+		wmu:            &sync.Mutex{},
+		rmu:            &sync.Mutex{},
+	}
+	if err := secrets.NewRotation(t.Rotate, withOptions...); err != nil {
+		return nil, fmt.Errorf("cannot create encrypted tokenizer: %w", err)
+	}
+	return t, nil
+}
+
+func (a *aeadTokenizer) Encode(data any) (string, error) {
+	b := &bytes.Buffer{}
+	if err := gob.NewEncoder(b).Encode(data); err != nil {
+		return "", err
+	}
+
+	a.wmu.Lock()
+	key := a.write
+	a.wmu.Unlock()
+
+	sealed := make([]byte, len(key.ID), len(key.ID)+b.Len()+key.AEAD.Overhead())
+	copy(sealed, key.ID)
+	sealed = key.AEAD.Seal(sealed, nil, b.Bytes(), a.associatedData)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decode leaves data unchanged when the token cannot be decrypted
+// by either the present or the past key.
+func (a *aeadTokenizer) Decode(data any, token string) error {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil
+	}
+
+	a.rmu.Lock()
+	present, past := a.present, a.past
+	a.rmu.Unlock()
+
+	plain, ok := present.open(b, a.associatedData)
+	if !ok {
+		if plain, ok = past.open(b, a.associatedData); !ok {
+			return nil
+		}
+	}
+	return gob.NewDecoder(bytes.NewReader(plain)).Decode(data)
+}
+
+func (a *aeadTokenizer) Rotate(present, past *secrets.Secret) error {
+	presentKey, err := newAEADKey(present)
+	if err != nil {
+		return err
+	}
+	pastKey, err := newAEADKey(past)
+	if err != nil {
+		return err
+	}
+
+	a.rmu.Lock()
+	a.present = presentKey
+	a.past = pastKey
+	a.rmu.Unlock()
+
+	a.wmu.Lock()
+	a.write = presentKey
+	a.wmu.Unlock()
+	return nil
+}
@@ -7,7 +7,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/dkotik/htadaptor/middleware/session/secrets"
+	"github.com/dkotik/htadaptor/middleware/traceid"
 )
 
 type contextKeyType struct{}
@@ -75,9 +75,8 @@ type sessionContext struct {
 	r      *http.Request
 	values map[string]any
 
-	id      string
-	traceID string
-	isNew   bool
+	id    string
+	isNew bool
 }
 
 func (c *sessionContext) readCookieToken() error {
@@ -96,6 +95,9 @@ func (c *sessionContext) writeCookieToken() error {
 	if err != nil {
 		return err
 	}
+	if writer, ok := c.cookies.(requestCookieWriter); ok {
+		return writer.writeRequestCookie(c.w, c.r, token, c.Expires())
+	}
 	return c.cookies.WriteCookie(c.w, token, c.Expires())
 }
 
@@ -106,11 +108,10 @@ func (c *sessionContext) ID() string {
 	return c.id
 }
 
+// TraceID matches the trace of the current [traceid.Span]
+// or is empty, if the session middleware was not wrapped by one.
 func (c *sessionContext) TraceID() string {
-	if c.traceID == "" {
-		c.traceID = string(secrets.NewID(8))
-	}
-	return c.traceID
+	return traceid.FromContext(c.Context)
 }
 
 func (c *sessionContext) Role() (s string) {
@@ -1,23 +1,57 @@
 package session
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // A browser should be able to accept at least 300 cookies with a maximum size of 4096 bytes, as stipulated by RFC 2109 (#6.3), RFC 2965 (#5.3), and RFC 6265.
 const MaximumCookieSize = 4096
 
+// MaximumCookieChunks limits how many numbered cookies can carry
+// a single value that does not fit into [MaximumCookieSize].
+// Browsers keep about fifty cookies per domain.
+const MaximumCookieChunks = 8
+
+const (
+	defaultCookieName = "session"
+
+	// CookiePrefixHost requires the cookie to be [http.Cookie.Secure],
+	// have path "/", and no domain, which locks it to the host.
+	CookiePrefixHost = "__Host-"
+	// CookiePrefixSecure requires the cookie to be [http.Cookie.Secure].
+	CookiePrefixSecure = "__Secure-"
+
+	// chunkMarker begins the value of the named cookie when the rest
+	// of the value is spread across numbered cookies. Tokens never
+	// begin with it, because it is not a base64 character.
+	chunkMarker = "~"
+)
+
 type CookieCodec interface {
 	WriteCookie(http.ResponseWriter, string, time.Time) error
 	ReadCookie(*http.Request) string
 }
 
+// cookieNamer is implemented by cookie codecs that report the name
+// of their cookie, so that encrypted tokens can be bound to it.
+type cookieNamer interface {
+	CookieName() string
+}
+
 type strictCookieCodec struct {
 	Name string
 	Path string
 }
 
+// NewStrictCookieCodec creates a [CookieCodec] with strict same site
+// policy that can be sent over insecure connections and read
+// by scripts. It is the default. Prefer [NewCookieCodec], which
+// is secure by default, where every client uses HTTPS.
 func NewStrictCookieCodec(name, path string) CookieCodec {
 	return &strictCookieCodec{
 		Name: name,
@@ -44,6 +78,11 @@ func (c *strictCookieCodec) WriteCookie(
 	return nil
 }
 
+// CookieName returns the name of the cookie.
+func (c *strictCookieCodec) CookieName() string {
+	return c.Name
+}
+
 func (c *strictCookieCodec) ReadCookie(r *http.Request) string {
 	cookie, err := r.Cookie(c.Name)
 	if err != nil {
@@ -55,3 +94,154 @@ func (c *strictCookieCodec) ReadCookie(r *http.Request) string {
 	}
 	return cookie.Value
 }
+
+// cookieCodec writes values that exceed [MaximumCookieSize] as
+// numbered chunks. The named cookie then holds the [chunkMarker]
+// followed by the number of chunks, which keeps stale chunks
+// from previous responses from corrupting the value.
+type cookieCodec struct {
+	template http.Cookie
+}
+
+// NewCookieCodec creates a [CookieCodec] that is secure by default:
+// cookies are [http.Cookie.Secure], [http.Cookie.HttpOnly], use strict
+// same site policy, and receive the strongest name prefix allowed
+// by their attributes, which is [CookiePrefixHost] or
+// [CookiePrefixSecure].
+func NewCookieCodec(withOptions ...CookieOption) (CookieCodec, error) {
+	o := &cookieOptions{}
+	var err error
+	for _, option := range append(
+		withOptions,
+		WithDefaultCookieName(),
+		WithDefaultCookiePath(),
+		WithDefaultCookieSameSite(),
+		func(o *cookieOptions) error {
+			if o.Partitioned && o.Insecure {
+				return errors.New("partitioned cookies must be secure")
+			}
+			return nil
+		},
+		withCookiePrefix(),
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create cookie codec: %w", err)
+		}
+	}
+
+	return &cookieCodec{
+		template: http.Cookie{
+			Name:        o.Name,
+			Path:        o.Path,
+			Domain:      o.Domain,
+			Secure:      !o.Insecure,
+			HttpOnly:    !o.ScriptAccess,
+			SameSite:    o.SameSite,
+			Partitioned: o.Partitioned,
+		},
+	}, nil
+}
+
+func (c *cookieCodec) format(name, value string, expires time.Time) string {
+	cookie := c.template
+	cookie.Name = name
+	cookie.Value = value
+	cookie.Expires = expires
+	return cookie.String()
+}
+
+func (c *cookieCodec) chunkName(n int) string {
+	return c.template.Name + "." + strconv.Itoa(n)
+}
+
+// requestCookieWriter is implemented by cookie codecs that clean up
+// cookies left by previous responses, which only the request reveals.
+type requestCookieWriter interface {
+	writeRequestCookie(http.ResponseWriter, *http.Request, string, time.Time) error
+}
+
+func (c *cookieCodec) WriteCookie(
+	w http.ResponseWriter,
+	value string,
+	expires time.Time,
+) error {
+	return c.writeRequestCookie(w, nil, value, expires)
+}
+
+// writeRequestCookie expires the numbered chunks that the request
+// carries, but the new value no longer uses, so that clients stop
+// sending them.
+func (c *cookieCodec) writeRequestCookie(
+	w http.ResponseWriter,
+	r *http.Request,
+	value string,
+	expires time.Time,
+) error {
+	header := w.Header()
+	total := 0
+	if cookie := c.format(c.template.Name, value, expires); len(cookie) <= MaximumCookieSize {
+		header.Add("Set-Cookie", cookie)
+	} else {
+		// the last chunk has the longest name
+		capacity := MaximumCookieSize - len(c.format(c.chunkName(MaximumCookieChunks-1), "", expires))
+		total = (len(value) + capacity - 1) / capacity
+		if total > MaximumCookieChunks {
+			return ErrLargeCookie
+		}
+		header.Add("Set-Cookie", c.format(
+			c.template.Name,
+			chunkMarker+strconv.Itoa(total),
+			expires,
+		))
+		for i := range total {
+			header.Add("Set-Cookie", c.format(
+				c.chunkName(i),
+				value[i*capacity:min(len(value), (i+1)*capacity)],
+				expires,
+			))
+		}
+	}
+	if r == nil {
+		return nil
+	}
+	for i := total; i < MaximumCookieChunks; i++ {
+		name := c.chunkName(i)
+		if _, err := r.Cookie(name); err != nil {
+			continue
+		}
+		cookie := c.template
+		cookie.Name = name
+		cookie.MaxAge = -1
+		header.Add("Set-Cookie", cookie.String())
+	}
+	return nil
+}
+
+// CookieName returns the name of the cookie, including its prefix.
+func (c *cookieCodec) CookieName() string {
+	return c.template.Name
+}
+
+func (c *cookieCodec) ReadCookie(r *http.Request) string {
+	cookie, err := r.Cookie(c.template.Name)
+	if err != nil {
+		return ""
+	}
+	count, ok := strings.CutPrefix(cookie.Value, chunkMarker)
+	if !ok {
+		return cookie.Value
+	}
+	total, err := strconv.Atoi(count)
+	if err != nil || total < 1 || total > MaximumCookieChunks {
+		return ""
+	}
+	b := &strings.Builder{}
+	for i := range total {
+		chunk, err := r.Cookie(c.chunkName(i))
+		if err != nil {
+			return ""
+		}
+		b.WriteString(chunk.Value)
+	}
+	return b.String()
+}
@@ -1,7 +1,215 @@
 package session
 
-import "testing"
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func roundTripCookie(t *testing.T, c CookieCodec, value string) (string, []*http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if err := c.WriteCookie(w, value, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range cookies {
+		if len(cookie.String()) > MaximumCookieSize {
+			t.Fatalf("cookie %q is larger than %d bytes", cookie.Name, MaximumCookieSize)
+		}
+		r.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return c.ReadCookie(r), cookies
+}
 
 func TestCookieCodec(t *testing.T) {
-	t.Skip("implement")
+	cases := []struct {
+		Name     string
+		Options  []CookieOption
+		Expected http.Cookie
+		Error    bool
+	}{
+		{
+			Name: "secure by default",
+			Expected: http.Cookie{
+				Name:     "__Host-session",
+				Path:     "/",
+				Secure:   true,
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+			},
+		},
+		{
+			Name: "domain cookie",
+			Options: []CookieOption{
+				WithCookieName("id"),
+				WithCookieDomain("example.com"),
+				WithCookieSameSite(http.SameSiteLaxMode),
+			},
+			Expected: http.Cookie{
+				Name:     "__Secure-id",
+				Path:     "/",
+				Domain:   "example.com",
+				Secure:   true,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			},
+		},
+		{
+			Name: "partitioned",
+			Options: []CookieOption{
+				WithCookieSameSite(http.SameSiteNoneMode),
+				WithCookiePartitioned(),
+			},
+			Expected: http.Cookie{
+				Name:        "__Host-session",
+				Path:        "/",
+				Secure:      true,
+				HttpOnly:    true,
+				SameSite:    http.SameSiteNoneMode,
+				Partitioned: true,
+			},
+		},
+		{
+			Name: "insecure",
+			Options: []CookieOption{
+				WithCookiePath("/app"),
+				WithCookieInsecure(),
+				WithCookieScriptAccess(),
+			},
+			Expected: http.Cookie{
+				Name:     "session",
+				Path:     "/app",
+				SameSite: http.SameSiteStrictMode,
+			},
+		},
+		{
+			Name: "host prefix with path",
+			Options: []CookieOption{
+				WithCookieName(CookiePrefixHost + "session"),
+				WithCookiePath("/app"),
+			},
+			Error: true,
+		},
+		{
+			Name: "secure prefix without security",
+			Options: []CookieOption{
+				WithCookieName(CookiePrefixSecure + "session"),
+				WithCookieInsecure(),
+			},
+			Error: true,
+		},
+		{
+			Name: "insecure partitioned",
+			Options: []CookieOption{
+				WithCookiePartitioned(),
+				WithCookieInsecure(),
+			},
+			Error: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			codec, err := NewCookieCodec(c.Options...)
+			if c.Error {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, cookies := roundTripCookie(t, codec, "token")
+			if value != "token" {
+				t.Fatalf("cookie value %q does not match", value)
+			}
+			if len(cookies) != 1 {
+				t.Fatalf("expected one cookie, got %d", len(cookies))
+			}
+			cookie := cookies[0]
+			if cookie.Name != c.Expected.Name ||
+				cookie.Path != c.Expected.Path ||
+				cookie.Domain != c.Expected.Domain ||
+				cookie.Secure != c.Expected.Secure ||
+				cookie.HttpOnly != c.Expected.HttpOnly ||
+				cookie.SameSite != c.Expected.SameSite ||
+				cookie.Partitioned != c.Expected.Partitioned {
+				t.Fatalf("cookie %+v does not match expected %+v", cookie, c.Expected)
+			}
+		})
+	}
+}
+
+func TestCookieCodecChunking(t *testing.T) {
+	codec, err := NewCookieCodec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	large := strings.Repeat("abcdefghij", MaximumCookieSize/4)
+	value, cookies := roundTripCookie(t, codec, large)
+	if value != large {
+		t.Fatal("chunked cookie value does not match")
+	}
+	if len(cookies) < 3 {
+		t.Fatalf("expected chunked cookies, got %d", len(cookies))
+	}
+
+	// stale chunks left over from a larger value must be ignored
+	w := httptest.NewRecorder()
+	if err = codec.WriteCookie(w, "small", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "__Host-session", Value: w.Result().Cookies()[0].Value})
+	for _, cookie := range cookies[1:] {
+		r.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	if value = codec.ReadCookie(r); value != "small" {
+		t.Fatalf("stale chunks corrupted cookie value: %q", value)
+	}
+
+	// the session middleware expires chunks that are no longer used
+	w = httptest.NewRecorder()
+	if err = codec.(requestCookieWriter).writeRequestCookie(w, r, "small", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	written := w.Result().Cookies()
+	if len(written) != len(cookies) {
+		t.Fatalf("expected %d cookies, got %d", len(cookies), len(written))
+	}
+	for i, cookie := range written[1:] {
+		if cookie.Name != cookies[i+1].Name || cookie.MaxAge >= 0 {
+			t.Fatalf("stale chunk %q was not expired: %+v", cookies[i+1].Name, cookie)
+		}
+	}
+
+	err = codec.WriteCookie(
+		httptest.NewRecorder(),
+		strings.Repeat("a", MaximumCookieSize*MaximumCookieChunks),
+		time.Now(),
+	)
+	if !errors.Is(err, ErrLargeCookie) {
+		t.Fatal("expected large cookie error, got:", err)
+	}
+}
+
+func TestDefaultCookieCodec(t *testing.T) {
+	o := &options{}
+	if err := WithDefaultCookieCodec()(o); err != nil {
+		t.Fatal(err)
+	}
+	value, cookies := roundTripCookie(t, o.CookieCodec, "token")
+	if value != "token" {
+		t.Fatalf("cookie value %q does not match", value)
+	}
+	if cookies[0].Name != defaultCookieName || cookies[0].Secure {
+		t.Fatalf("default cookie changed: %+v", cookies[0])
+	}
 }
@@ -0,0 +1,171 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type cookieOptions struct {
+	Name         string
+	Path         string
+	Domain       string
+	SameSite     http.SameSite
+	Partitioned  bool
+	Insecure     bool
+	ScriptAccess bool
+}
+
+type CookieOption func(*cookieOptions) error
+
+// WithCookieName sets the cookie name. A name that begins
+// with [CookiePrefixHost] or [CookiePrefixSecure] is checked
+// against cookie attributes. Otherwise, the strongest prefix
+// allowed by the attributes is added automatically.
+func WithCookieName(name string) CookieOption {
+	return func(o *cookieOptions) error {
+		if name == "" {
+			return errors.New("cannot use an empty cookie name")
+		}
+		if strings.ContainsAny(name, "()<>@,;:\\\"/[]?={} \t") {
+			return errors.New("cookie name contains invalid characters")
+		}
+		if o.Name != "" {
+			return errors.New("cookie name is already set")
+		}
+		o.Name = name
+		return nil
+	}
+}
+
+func WithDefaultCookieName() CookieOption {
+	return func(o *cookieOptions) error {
+		if o.Name != "" {
+			return nil
+		}
+		o.Name = defaultCookieName
+		return nil
+	}
+}
+
+func WithCookiePath(p string) CookieOption {
+	return func(o *cookieOptions) error {
+		if !strings.HasPrefix(p, "/") {
+			return errors.New("cookie path must begin with a slash")
+		}
+		if o.Path != "" {
+			return errors.New("cookie path is already set")
+		}
+		o.Path = p
+		return nil
+	}
+}
+
+func WithDefaultCookiePath() CookieOption {
+	return func(o *cookieOptions) error {
+		if o.Path != "" {
+			return nil
+		}
+		o.Path = "/"
+		return nil
+	}
+}
+
+// WithCookieDomain shares the cookie with the subdomains of
+// the given domain. Domain cookies cannot use [CookiePrefixHost].
+func WithCookieDomain(domain string) CookieOption {
+	return func(o *cookieOptions) error {
+		if domain == "" {
+			return errors.New("cannot use an empty cookie domain")
+		}
+		if o.Domain != "" {
+			return errors.New("cookie domain is already set")
+		}
+		o.Domain = domain
+		return nil
+	}
+}
+
+func WithCookieSameSite(mode http.SameSite) CookieOption {
+	return func(o *cookieOptions) error {
+		switch mode {
+		case http.SameSiteLaxMode, http.SameSiteStrictMode, http.SameSiteNoneMode:
+		default:
+			return errors.New("cookie same site mode must be lax, strict, or none")
+		}
+		if o.SameSite != 0 {
+			return errors.New("cookie same site mode is already set")
+		}
+		o.SameSite = mode
+		return nil
+	}
+}
+
+func WithDefaultCookieSameSite() CookieOption {
+	return func(o *cookieOptions) error {
+		if o.SameSite != 0 {
+			return nil
+		}
+		o.SameSite = http.SameSiteStrictMode
+		return nil
+	}
+}
+
+// WithCookiePartitioned places the cookie into partitioned storage
+// keyed by the top-level site, also known as CHIPS. Useful for
+// embedded cross-site content, when combined with
+// [http.SameSiteNoneMode].
+func WithCookiePartitioned() CookieOption {
+	return func(o *cookieOptions) error {
+		if o.Partitioned {
+			return errors.New("cookie is already partitioned")
+		}
+		o.Partitioned = true
+		return nil
+	}
+}
+
+// WithCookieInsecure allows the cookie to travel over plain HTTP.
+// Insecure cookies receive no name prefix.
+func WithCookieInsecure() CookieOption {
+	return func(o *cookieOptions) error {
+		if o.Insecure {
+			return errors.New("cookie is already insecure")
+		}
+		o.Insecure = true
+		return nil
+	}
+}
+
+// WithCookieScriptAccess omits [http.Cookie.HttpOnly] flag,
+// which lets client scripts read the cookie.
+func WithCookieScriptAccess() CookieOption {
+	return func(o *cookieOptions) error {
+		if o.ScriptAccess {
+			return errors.New("cookie script access is already allowed")
+		}
+		o.ScriptAccess = true
+		return nil
+	}
+}
+
+func withCookiePrefix() CookieOption {
+	return func(o *cookieOptions) error {
+		isHostLocked := !o.Insecure && o.Path == "/" && o.Domain == ""
+		switch {
+		case strings.HasPrefix(o.Name, CookiePrefixHost):
+			if !isHostLocked {
+				return errors.New("cookie with " + CookiePrefixHost + " prefix must be secure, have path \"/\", and no domain")
+			}
+		case strings.HasPrefix(o.Name, CookiePrefixSecure):
+			if o.Insecure {
+				return errors.New("cookie with " + CookiePrefixSecure + " prefix must be secure")
+			}
+		case isHostLocked:
+			o.Name = CookiePrefixHost + o.Name
+		case !o.Insecure:
+			o.Name = CookiePrefixSecure + o.Name
+		}
+		return nil
+	}
+}
@@ -28,7 +28,7 @@ func (e Error) Error() string {
 	case ErrNoSessionInContext:
 		return "no session in context"
 	case ErrLargeCookie:
-		return fmt.Sprintf("cookie must be less than %d bytes long", MaximumCookieSize)
+		return fmt.Sprintf("cookie must fit into %d chunks of %d bytes", MaximumCookieChunks, MaximumCookieSize)
 	default:
 		return "unknown session error"
 	}
@@ -3,6 +3,8 @@ package session
 import (
 	"context"
 	"log/slog"
+
+	"github.com/dkotik/htadaptor/middleware/traceid"
 )
 
 type SlogHandler struct {
@@ -25,32 +27,7 @@ func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
 		return h.handler.Handle(ctx, r)
 	}
 	_ = Read(ctx, func(s Session) error {
-		role := s.Role()
-		if role == "" {
-			role = "guest"
-		}
-		r.AddAttrs(
-			slog.Attr{
-				Key:   "session_id",
-				Value: slog.StringValue(s.ID()),
-			},
-			slog.Attr{
-				Key:   "trace_id",
-				Value: slog.StringValue(s.TraceID()),
-			},
-			slog.Attr{
-				Key:   "user_id",
-				Value: slog.StringValue(s.UserID()),
-			},
-			slog.Attr{
-				Key:   "ip_address",
-				Value: slog.StringValue(s.Address()),
-			},
-			slog.Attr{
-				Key:   "role",
-				Value: slog.StringValue(role),
-			},
-		)
+		r.AddAttrs(attributes(ctx, s)...)
 		return nil
 	})
 	return h.handler.Handle(ctx, r)
@@ -63,3 +40,52 @@ func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 func (h *SlogHandler) WithGroup(name string) slog.Handler {
 	return NewSlogHandler(h.handler.WithGroup(name))
 }
+
+// Attributes returns the attributes that [SlogHandler] adds
+// to log records. Unlike the handler, it does not start a new
+// session, which makes it safe to call after the response
+// was written. Returns <nil> without a valid session.
+func Attributes(ctx context.Context) []slog.Attr {
+	c, ok := ctx.Value(contextKey).(*sessionContext)
+	if !ok {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		if err := c.readCookieToken(); err != nil || c.values == nil || c.IsExpired() {
+			c.values = nil // let the next [Read] or [Write] reset it
+			return nil
+		}
+	}
+	return attributes(ctx, c)
+}
+
+func attributes(ctx context.Context, s Session) []slog.Attr {
+	role := s.Role()
+	if role == "" {
+		role = "guest"
+	}
+	return []slog.Attr{
+		{
+			Key:   "session_id",
+			Value: slog.StringValue(s.ID()),
+		},
+		{
+			Key:   "trace_id",
+			Value: slog.StringValue(traceid.FromContext(ctx)),
+		},
+		{
+			Key:   "user_id",
+			Value: slog.StringValue(s.UserID()),
+		},
+		{
+			Key:   "ip_address",
+			Value: slog.StringValue(s.Address()),
+		},
+		{
+			Key:   "role",
+			Value: slog.StringValue(role),
+		},
+	}
+}
@@ -103,13 +103,30 @@ func WithTokenizer(t Tokenizer) Option {
 	}
 }
 
+// WithDefaultTokenizer uses [NewEncryptedTokenizer] bound to the name
+// of the cookie codec, if it reports one using a CookieName method,
+// like the codecs of this package do. Otherwise, the default cookie
+// name is used.
+//
+// Encrypted tokens replaced the signed tokens of [NewTokenizer]
+// as the default. Signed tokens issued before the upgrade no longer
+// decode, so their sessions start over. Use [WithTokenizer] with
+// [NewTokenizer] to keep them.
 func WithDefaultTokenizer() Option {
 	return func(o *options) error {
 		if o.Tokenizer != nil {
 			return nil
 		}
+		name := defaultCookieName
+		if named, ok := o.CookieCodec.(cookieNamer); ok && named.CookieName() != "" {
+			name = named.CookieName()
+		}
 		// o.Tokenizer = gorilla.New("session", secrets.WithExpiry(o.Expiry))
-		o.Tokenizer = NewTokenizer(secrets.WithExpiry(o.Expiry))
+		tokenizer, err := NewEncryptedTokenizer(name, secrets.WithExpiry(o.Expiry))
+		if err != nil {
+			return err
+		}
+		o.Tokenizer = tokenizer
 		return nil
 	}
 }
@@ -127,12 +144,17 @@ func WithCookieCodec(c CookieCodec) Option {
 	}
 }
 
+// WithDefaultCookieCodec uses [NewStrictCookieCodec] with the "session"
+// name. Opt into secure, prefixed, and chunked cookies with
+// [WithCookieCodec] and [NewCookieCodec]. Renaming the cookie ends
+// existing sessions, just like changing the tokenizer does, see
+// [WithDefaultTokenizer].
 func WithDefaultCookieCodec() Option {
 	return func(o *options) error {
 		if o.CookieCodec != nil {
 			return nil
 		}
-		o.CookieCodec = NewStrictCookieCodec("session", "/")
+		o.CookieCodec = NewStrictCookieCodec(defaultCookieName, "/")
 		return nil
 	}
 }
@@ -39,8 +39,8 @@ func New(withOptions ...Option) (func(http.Handler) http.Handler, error) {
 		WithDefaultName(),
 		WithDefaultExpiry(),
 		WithDefaultRotationContext(),
-		WithDefaultTokenizer(),
 		WithDefaultCookieCodec(),
+		WithDefaultTokenizer(), // binds to the cookie name
 		WithDefaultFactory(),
 	) {
 		if err = option(options); err != nil {
@@ -24,6 +24,9 @@ type Tokenizer interface {
 	Decode(any, string) error
 }
 
+// hmacTokenizer signs gob-encoded session values without encrypting
+// them. Prefer [NewEncryptedTokenizer], unless clients must be able
+// to read session values.
 type hmacTokenizer struct {
 	wmu   *sync.Mutex
 	write *secrets.Secret
@@ -62,9 +65,9 @@ func (h *hmacTokenizer) Encode(data any) (string, error) {
 // signature that matches HMAC sum of [Key.Label] followed
 // by the rest of the data.
 func Validate(secret *secrets.Secret, b []byte) bool {
-	// if len(b) <= tokenTagSize {
-	// 	return false
-	// }
+	if len(b) <= tokenSignatureSize {
+		return false
+	}
 	// if !bytes.Equal(k.Label, b[:12]) {
 	// 	// does not match key label
 	// 	return false
@@ -98,12 +101,15 @@ func Sign(secret *secrets.Secret, b []byte) []byte {
 
 func (h *hmacTokenizer) Decode(data any, token string) (err error) {
 	b := []byte(token)
-	h.rmu.Lock()
-	if !Validate(h.present, b) && !Validate(h.past, b) {
-		// TODO: // OPTIMIZE validation
+	if len(b) <= tokenTagSize {
 		return nil
 	}
+	h.rmu.Lock()
+	present, past := h.present, h.past
 	h.rmu.Unlock()
+	if !Validate(present, b) && !Validate(past, b) {
+		return nil
+	}
 	b = b[tokenTagSize:] // chomp off tag
 	dbuf := make([]byte, base64.RawURLEncoding.DecodedLen(len(b)))
 	if _, err = base64.RawURLEncoding.Decode(dbuf, b); err != nil {
@@ -0,0 +1,128 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+// JSONWebKey is a public key encoded according to RFC 7517.
+// Only the elliptic curve and Edwards curve key types are supported.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+}
+
+// KeySet is a JSON Web Key Set document.
+type KeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// NewJSONWebKey encodes an Ed25519 or ECDSA public key.
+func NewJSONWebKey(id, algorithm string, key any) (*JSONWebKey, error) {
+	if id == "" {
+		return nil, errors.New("cannot use an empty key id")
+	}
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		return &JSONWebKey{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			ID:        id,
+			Algorithm: algorithm,
+			Use:       "sig",
+			X:         base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	case *ecdsa.PublicKey:
+		point, err := key.Bytes() // 0x04 || X || Y
+		if err != nil {
+			return nil, err
+		}
+		size := (len(point) - 1) / 2
+		return &JSONWebKey{
+			KeyType:   "EC",
+			Curve:     key.Curve.Params().Name,
+			ID:        id,
+			Algorithm: algorithm,
+			Use:       "sig",
+			X:         base64.RawURLEncoding.EncodeToString(point[1 : 1+size]),
+			Y:         base64.RawURLEncoding.EncodeToString(point[1+size:]),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type: %T", key)
+	}
+}
+
+// PublicKey decodes the key into [ed25519.PublicKey]
+// or [*ecdsa.PublicKey].
+func (k *JSONWebKey) PublicKey() (any, error) {
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %q x coordinate: %w", k.ID, err)
+	}
+	switch k.KeyType {
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported key %q curve: %s", k.ID, k.Curve)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key %q size: %d", k.ID, len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported key %q curve: %s", k.ID, k.Curve)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q y coordinate: %w", k.ID, err)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid key %q coordinate size", k.ID)
+		}
+		return ecdsa.ParseUncompressedPublicKey(curve, slices.Concat([]byte{4}, x, y))
+	default:
+		return nil, fmt.Errorf("unsupported key %q type: %s", k.ID, k.KeyType)
+	}
+}
+
+// Render writes the key set as JSON. Public keys are meant to be
+// fetched often by other services, so they are cached briefly.
+func (s *KeySet) Render(w http.ResponseWriter) error {
+	w.Header().Set("content-type", "application/jwk-set+json")
+	w.Header().Set("cache-control", "public, max-age=300")
+	return json.NewEncoder(w).Encode(s)
+}
+
+// ParseKeySet reads a JSON Web Key Set document.
+func ParseKeySet(r io.Reader) (*KeySet, error) {
+	set := &KeySet{}
+	if err := json.NewDecoder(io.LimitReader(r, 1<<20)).Decode(set); err != nil {
+		return nil, fmt.Errorf("cannot decode JSON web key set: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.New("JSON web key set is empty")
+	}
+	return set, nil
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/dkotik/htadaptor/middleware/session/secrets"
 	"github.com/golang-jwt/jwt/v5"
@@ -36,12 +37,9 @@ func New(withOptions ...secrets.Option) *Tokenizer {
 }
 
 func (h *Tokenizer) Encode(data any) (string, error) {
-	claims := jwt.MapClaims(data.(map[string]any))
-	// panic(fmt.Sprintf("%+v", claims))
-	if exp, ok := claims["expires"]; ok { // do not type-cast
-		claims["exp"] = exp
-	} else if _, ok := claims["exp"]; !ok { // do not type-cast
-		return "", errors.New("token must include an `expires` or `exp` field")
+	claims, err := claimsFromData(data)
+	if err != nil {
+		return "", err
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
@@ -51,73 +49,83 @@ func (h *Tokenizer) Encode(data any) (string, error) {
 	return token.SignedString(h.write.Entropy)
 }
 
+// Decode ignores tokens that fail verification, leaving data
+// untouched, so that the session is reset instead of failing
+// the request.
 func (h *Tokenizer) Decode(data any, tokenString string) (err error) {
+	_ = h.DecodeWithLeeway(data, tokenString, 0)
+	return nil
+}
+
+// DecodeWithLeeway tolerates the given clock skew when validating
+// time-based claims. Unlike [Tokenizer.Decode], it returns
+// verification errors, such as [jwt.ErrTokenExpired].
+func (h *Tokenizer) DecodeWithLeeway(data any, tokenString string, leeway time.Duration) (err error) {
+	return decodeClaims(data, tokenString, hs256, leeway, func(kid, _ string) (any, error) {
+		id := []byte(kid)
+
+		h.rmu.Lock()
+		defer h.rmu.Unlock()
+		if bytes.Equal(h.present.ID, id) {
+			return h.present.Entropy, nil
+		}
+		if bytes.Equal(h.past.ID, id) {
+			return h.past.Entropy, nil
+		}
+		return nil, fmt.Errorf("none of the keys matched key id: %s", id)
+	})
+}
+
+func claimsFromData(data any) (jwt.MapClaims, error) {
+	values, ok := data.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T as token claims", data)
+	}
+	claims := jwt.MapClaims(values)
+	if exp, ok := claims["expires"]; ok { // do not type-cast
+		claims["exp"] = exp
+	} else if _, ok := claims["exp"]; !ok { // do not type-cast
+		return nil, errors.New("token must include an `expires` or `exp` field")
+	}
+	return claims, nil
+}
+
+var hs256 = []string{jwt.SigningMethodHS256.Alg()}
+
+// decodeClaims parses and verifies a token signed by the given method
+// using the key looked up by the token header key id. Tokens that fail
+// verification leave data untouched.
+//
+// Signing algorithms are restricted to the given list, which prevents
+// algorithm substitution attacks.
+func decodeClaims(
+	data any,
+	tokenString string,
+	algorithms []string,
+	leeway time.Duration,
+	lookup func(kid, alg string) (any, error),
+) error {
 	token, err := jwt.Parse(
 		tokenString,
 		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok { // important
-				return nil, fmt.Errorf("signing method other than HMAC-sha256 not supported: %v", token.Header["alg"])
-			}
 			kid, ok := token.Header["kid"].(string) // TODO: test without kid
 			if !ok {
 				return nil, errors.New("token header does not contain a key id")
 			}
-			id := []byte(kid)
-
-			h.rmu.Lock()
-			defer h.rmu.Unlock()
-			if bytes.Equal(h.present.ID, id) {
-				return h.present.Entropy, nil
-			}
-			if bytes.Equal(h.past.ID, id) {
-				return h.past.Entropy, nil
-			}
-			return nil, fmt.Errorf("none of the keys matched key id: %s", id)
+			return lookup(kid, token.Method.Alg())
 		},
 		// jwt.WithJSONNumber(), // bad
+		jwt.WithValidMethods(algorithms), // important
 		jwt.WithExpirationRequired(),
 		jwt.WithPaddingAllowed(),
+		jwt.WithLeeway(leeway),
 	)
 	if err != nil {
-		// TODO: ignoring decoding error
-		// panic(err)
-		return nil
+		return err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		// panic(fmt.Sprintf("%+v", token.Claims))
-		// value := reflect.Indirect(reflect.ValueOf(data))
-		// value.Set(reflect.MakeMap(value.Type()))
-		//
-		// target := *(data.(*map[string]any))
-		// target["id"] = claims["exp"]
-		// (*(data.(*map[string]any)))["id"] = "124"
-		// if value.Type().Kind() != reflect.Pointer {
-		//   dec.err = errors.New("gob: attempt to decode into a non-pointer")
-		//   return dec.err
-		// }
-
-		// gob.Register(json.Number("0"))
-		// b := &bytes.Buffer{}
-		// if err = gob.NewEncoder(b).Encode(claims); err != nil {
-		// 	return err
-		// }
-		// // panic(string(b.Bytes()))
-		// if err = gob.NewDecoder(b).Decode(data); err != nil {
-		// 	return err
-		// }
-
 		*(data.(*map[string]any)) = claims
-		// data = claims
-		// fmt.Printf("\n\n%+v\n\n", data)
-		// fmt.Printf("\n\n%+v || %T\n\n", claims, claims["id"])
-		// result := make(map[string]any)
-		// for k, v := range claims {
-		// 	result[k] = v
-		// }
-		// *(data.(*map[string]any)) = map[string]any{
-		// 	"id": "test",
-		// }
 	}
 	return nil
 }
@@ -42,3 +42,24 @@ func TestDecoing(t *testing.T) {
 		t.Fatal("decoded value does not match original")
 	}
 }
+
+func TestDecodingRejectsExpiredTokens(t *testing.T) {
+	tokens := New()
+	token, err := tokens.Encode(map[string]any{
+		"exp": float64(time.Now().Add(-time.Second * 5).Unix()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]any
+	if err = tokens.Decode(&decoded, token); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != nil {
+		t.Fatal("expired token was decoded without a leeway")
+	}
+	if err = tokens.DecodeWithLeeway(&decoded, token, time.Second*10); err != nil {
+		t.Fatal("token expired within leeway was rejected:", err)
+	}
+}
@@ -0,0 +1,215 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dkotik/htadaptor/middleware/session/secrets"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyPair is a signing key derived from a [secrets.Secret].
+// The public half is published using [KeyPairTokenizer.KeySet].
+type KeyPair struct {
+	ID      string
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeyPairDerivation deterministically turns [secrets.Secret] entropy
+// into an asymmetric [KeyPair].
+type KeyPairDerivation func(*secrets.Secret) (*KeyPair, error)
+
+// KeyPairTokenizer signs JSON Web Tokens with asymmetric keys that
+// rotate together with [secrets.Rotation]. Other services can verify
+// the tokens using only the public keys served by
+// [KeyPairTokenizer.KeySetHandler].
+type KeyPairTokenizer struct {
+	method jwt.SigningMethod
+	derive KeyPairDerivation
+
+	wmu   *sync.Mutex
+	write *KeyPair
+
+	rmu     *sync.Mutex
+	present *KeyPair
+	past    *KeyPair
+}
+
+// NewEdDSA creates a [KeyPairTokenizer] that signs tokens
+// with Ed25519 keys.
+func NewEdDSA(withOptions ...secrets.Option) (*KeyPairTokenizer, error) {
+	return NewKeyPairTokenizer(jwt.SigningMethodEdDSA, DeriveEd25519, withOptions...)
+}
+
+// NewES256 creates a [KeyPairTokenizer] that signs tokens
+// with ECDSA P-256 keys.
+func NewES256(withOptions ...secrets.Option) (*KeyPairTokenizer, error) {
+	return NewKeyPairTokenizer(jwt.SigningMethodES256, DeriveP256, withOptions...)
+}
+
+// NewKeyPairTokenizer creates a [KeyPairTokenizer] for any asymmetric
+// signing method given a matching key pair derivation.
+func NewKeyPairTokenizer(
+	method jwt.SigningMethod,
+	derive KeyPairDerivation,
+	withOptions ...secrets.Option,
+) (*KeyPairTokenizer, error) {
+	if method == nil {
+		return nil, errors.New("cannot use a <nil> signing method")
+	}
+	if derive == nil {
+		return nil, errors.New("cannot use a <nil> key pair derivation")
+	}
+	t := &KeyPairTokenizer{
+		method: method,
+		derive: derive,
+		wmu:    &sync.Mutex{},
+		rmu:    &sync.Mutex{},
+	}
+	if err := secrets.NewRotation(t.Rotate, withOptions...); err != nil {
+		return nil, fmt.Errorf("cannot create %s tokenizer: %w", method.Alg(), err)
+	}
+	return t, nil
+}
+
+// DeriveEd25519 expands secret entropy into an Ed25519 private key seed.
+func DeriveEd25519(s *secrets.Secret) (*KeyPair, error) {
+	seed, err := hkdf.Key(sha256.New, s.Entropy, s.ID, "Ed25519", ed25519.SeedSize)
+	if err != nil {
+		return nil, err
+	}
+	private := ed25519.NewKeyFromSeed(seed)
+	return &KeyPair{
+		ID:      string(s.ID),
+		Private: private,
+		Public:  private.Public(),
+	}, nil
+}
+
+// DeriveP256 expands secret entropy into an ECDSA P-256 private key.
+func DeriveP256(s *secrets.Secret) (*KeyPair, error) {
+	curve := elliptic.P256()
+	// one extra byte makes the modulo bias negligible
+	b, err := hkdf.Key(sha256.New, s.Entropy, s.ID, "P-256", 33)
+	if err != nil {
+		return nil, err
+	}
+	// scalar must fall into [1, N-1]
+	n := new(big.Int).Sub(curve.Params().N, big.NewInt(1))
+	k := new(big.Int).SetBytes(b)
+	k.Mod(k, n).Add(k, big.NewInt(1))
+
+	private, err := ecdsa.ParseRawPrivateKey(curve, k.FillBytes(make([]byte, 32)))
+	if err != nil {
+		return nil, err
+	}
+	return &KeyPair{
+		ID:      string(s.ID),
+		Private: private,
+		Public:  private.Public(),
+	}, nil
+}
+
+func (t *KeyPairTokenizer) Encode(data any) (string, error) {
+	claims, err := claimsFromData(data)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(t.method, claims)
+
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	token.Header["kid"] = t.write.ID
+	return token.SignedString(t.write.Private)
+}
+
+// Decode ignores tokens that fail verification, leaving data
+// untouched, so that the session is reset instead of failing
+// the request.
+func (t *KeyPairTokenizer) Decode(data any, tokenString string) error {
+	_ = t.DecodeWithLeeway(data, tokenString, 0)
+	return nil
+}
+
+// DecodeWithLeeway tolerates the given clock skew when validating
+// time-based claims. Unlike [KeyPairTokenizer.Decode], it returns
+// verification errors, such as [jwt.ErrTokenExpired].
+func (t *KeyPairTokenizer) DecodeWithLeeway(data any, tokenString string, leeway time.Duration) error {
+	return decodeClaims(data, tokenString, []string{t.method.Alg()}, leeway, func(kid, _ string) (any, error) {
+		t.rmu.Lock()
+		defer t.rmu.Unlock()
+		if t.present.ID == kid {
+			return t.present.Public, nil
+		}
+		if t.past.ID == kid {
+			return t.past.Public, nil
+		}
+		return nil, fmt.Errorf("none of the keys matched key id: %s", kid)
+	})
+}
+
+func (t *KeyPairTokenizer) Rotate(present, past *secrets.Secret) error {
+	presentPair, err := t.derive(present)
+	if err != nil {
+		return fmt.Errorf("cannot derive present key pair: %w", err)
+	}
+	pastPair, err := t.derive(past)
+	if err != nil {
+		return fmt.Errorf("cannot derive past key pair: %w", err)
+	}
+
+	t.rmu.Lock()
+	t.present = presentPair
+	t.past = pastPair
+	t.rmu.Unlock()
+
+	t.wmu.Lock()
+	t.write = presentPair
+	t.wmu.Unlock()
+	return nil
+}
+
+// KeySet returns the present and past public keys.
+func (t *KeyPairTokenizer) KeySet() (*KeySet, error) {
+	t.rmu.Lock()
+	pairs := []*KeyPair{t.present}
+	if t.past.ID != t.present.ID {
+		pairs = append(pairs, t.past)
+	}
+	t.rmu.Unlock()
+
+	set := &KeySet{Keys: make([]JSONWebKey, 0, len(pairs))}
+	for _, pair := range pairs {
+		key, err := NewJSONWebKey(pair.ID, t.method.Alg(), pair.Public)
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, *key)
+	}
+	return set, nil
+}
+
+// KeySetHandler publishes [KeyPairTokenizer.KeySet] as a JSON Web
+// Key Set document, conventionally mounted at
+// "/.well-known/jwks.json".
+func (t *KeyPairTokenizer) KeySetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		set, err := t.KeySet()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		_ = set.Render(w)
+	})
+}
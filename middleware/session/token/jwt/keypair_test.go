@@ -0,0 +1,114 @@
+package jwt
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyPairTokenizers(t *testing.T) {
+	for name, create := range map[string]func() (*KeyPairTokenizer, error){
+		"EdDSA": func() (*KeyPairTokenizer, error) { return NewEdDSA() },
+		"ES256": func() (*KeyPairTokenizer, error) { return NewES256() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			tokens, err := create()
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := tokens.Encode(map[string]any{
+				"user_id": "test",
+				"exp":     float64(time.Now().Add(time.Hour).Unix()),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded map[string]any
+			if err = tokens.Decode(&decoded, token); err != nil {
+				t.Fatal(err)
+			}
+			if decoded["user_id"] != "test" {
+				t.Fatalf("decoded claims do not match: %+v", decoded)
+			}
+
+			verifier, err := NewVerifier(WithKeySetHandler(tokens.KeySetHandler()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded = nil
+			if err = verifier.Decode(&decoded, token); err != nil {
+				t.Fatal(err)
+			}
+			if decoded["user_id"] != "test" {
+				t.Fatalf("verified claims do not match: %+v", decoded)
+			}
+
+			decoded = nil
+			if err = verifier.Decode(&decoded, token[:len(token)-4]+"AAAA"); err != nil {
+				t.Fatal(err)
+			}
+			if decoded != nil {
+				t.Fatal("tampered token was accepted")
+			}
+			if _, err = verifier.Encode(decoded); err != ErrVerifierCannotEncode {
+				t.Fatal("verifier must not issue tokens")
+			}
+		})
+	}
+}
+
+func TestKeySetFileVerifier(t *testing.T) {
+	tokens, err := NewEdDSA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	tokens.KeySetHandler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if ct := w.Header().Get("content-type"); ct != "application/jwk-set+json" {
+		t.Fatal("unexpected content type:", ct)
+	}
+	p := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(p, w.Body.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := NewVerifier(WithKeySetFile(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := tokens.Encode(map[string]any{
+		"role":    "service",
+		"expires": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err = verifier.Decode(&decoded, token); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["role"] != "service" {
+		t.Fatalf("verified claims do not match: %+v", decoded)
+	}
+
+	other, err := NewES256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err = other.Encode(map[string]any{
+		"expires": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded = nil
+	if err = verifier.Decode(&decoded, token); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != nil {
+		t.Fatal("token signed by an unknown key was accepted")
+	}
+}
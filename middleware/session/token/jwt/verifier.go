@@ -0,0 +1,251 @@
+package jwt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrVerifierCannotEncode is returned by [Verifier.Encode],
+// because the verifier does not possess any private keys.
+var ErrVerifierCannotEncode = errors.New("token verifier cannot issue tokens")
+
+// KeySetSource loads a JSON Web Key Set.
+type KeySetSource func() (*KeySet, error)
+
+// NewKeySetHandlerSource fetches the key set by calling
+// the handler with a synthetic GET request, which is useful when
+// the issuer runs in the same process or when the handler is a proxy
+// to the issuer.
+func NewKeySetHandlerSource(h http.Handler) KeySetSource {
+	return func() (*KeySet, error) {
+		r, err := http.NewRequest(http.MethodGet, "/", nil)
+		if err != nil {
+			return nil, err
+		}
+		w := &keySetResponse{header: make(http.Header)}
+		h.ServeHTTP(w, r)
+		if w.code != 0 && w.code != http.StatusOK {
+			return nil, fmt.Errorf("key set handler responded with status code %d", w.code)
+		}
+		return ParseKeySet(&w.body)
+	}
+}
+
+// keySetResponse collects the response of a key set handler.
+type keySetResponse struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *keySetResponse) Header() http.Header {
+	return w.header
+}
+
+func (w *keySetResponse) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *keySetResponse) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// NewKeySetFileSource reads the key set from a file.
+func NewKeySetFileSource(p string) KeySetSource {
+	return func() (*KeySet, error) {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ParseKeySet(f)
+	}
+}
+
+type verificationKey struct {
+	Algorithm string
+	Key       any
+}
+
+// Verifier is a [session.Tokenizer] that only decodes tokens
+// issued by a [KeyPairTokenizer] using the published public keys.
+// Use it for service-to-service authentication.
+//
+// When a token carries an unknown key identifier, the key set is
+// reloaded at most once per refresh interval to pick up key rotations.
+type Verifier struct {
+	source   KeySetSource
+	interval time.Duration
+
+	mu       *sync.Mutex
+	keys     map[string]verificationKey
+	loadedAt time.Time
+}
+
+type verifierOptions struct {
+	Source   KeySetSource
+	Interval time.Duration
+}
+
+type VerifierOption func(*verifierOptions) error
+
+func WithKeySetSource(s KeySetSource) VerifierOption {
+	return func(o *verifierOptions) error {
+		if s == nil {
+			return errors.New("cannot use a <nil> key set source")
+		}
+		if o.Source != nil {
+			return errors.New("key set source is already set")
+		}
+		o.Source = s
+		return nil
+	}
+}
+
+func WithKeySetHandler(h http.Handler) VerifierOption {
+	return func(o *verifierOptions) error {
+		if h == nil {
+			return errors.New("cannot use a <nil> key set handler")
+		}
+		return WithKeySetSource(NewKeySetHandlerSource(h))(o)
+	}
+}
+
+func WithKeySetFile(p string) VerifierOption {
+	return func(o *verifierOptions) error {
+		if p == "" {
+			return errors.New("cannot use an empty key set file path")
+		}
+		return WithKeySetSource(NewKeySetFileSource(p))(o)
+	}
+}
+
+func WithRefreshInterval(d time.Duration) VerifierOption {
+	return func(o *verifierOptions) error {
+		if d < time.Second {
+			return errors.New("cannot use key set refresh interval of less than one second")
+		}
+		if o.Interval != 0 {
+			return errors.New("key set refresh interval is already set")
+		}
+		o.Interval = d
+		return nil
+	}
+}
+
+func WithDefaultRefreshInterval() VerifierOption {
+	return func(o *verifierOptions) error {
+		if o.Interval != 0 {
+			return nil
+		}
+		return WithRefreshInterval(time.Minute)(o)
+	}
+}
+
+// NewVerifier creates a [Verifier] and loads the initial key set.
+func NewVerifier(withOptions ...VerifierOption) (_ *Verifier, err error) {
+	o := &verifierOptions{}
+	for _, option := range append(
+		withOptions,
+		WithDefaultRefreshInterval(),
+		func(o *verifierOptions) error {
+			if o.Source == nil {
+				return errors.New("key set source is required")
+			}
+			return nil
+		},
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create token verifier: %w", err)
+		}
+	}
+
+	v := &Verifier{
+		source:   o.Source,
+		interval: o.Interval,
+		mu:       &sync.Mutex{},
+	}
+	if err = v.reload(time.Now()); err != nil {
+		return nil, fmt.Errorf("cannot create token verifier: %w", err)
+	}
+	return v, nil
+}
+
+func (v *Verifier) reload(at time.Time) error {
+	set, err := v.source()
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			return err
+		}
+		keys[jwk.ID] = verificationKey{
+			Algorithm: jwk.Algorithm,
+			Key:       key,
+		}
+	}
+	v.keys = keys
+	v.loadedAt = at
+	return nil
+}
+
+func (v *Verifier) lookup(kid, alg string) (any, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok := v.keys[kid]
+	if !ok {
+		if at := time.Now(); at.Sub(v.loadedAt) >= v.interval {
+			if err := v.reload(at); err != nil {
+				v.loadedAt = at // prevent hammering a failing source
+				return nil, fmt.Errorf("cannot reload key set: %w", err)
+			}
+			key, ok = v.keys[kid]
+		}
+		if !ok {
+			return nil, fmt.Errorf("none of the keys matched key id: %s", kid)
+		}
+	}
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return nil, fmt.Errorf("key %q cannot be used with %s algorithm", kid, alg)
+	}
+	return key.Key, nil
+}
+
+// Encode always returns [ErrVerifierCannotEncode].
+func (v *Verifier) Encode(data any) (string, error) {
+	return "", ErrVerifierCannotEncode
+}
+
+// Decode ignores tokens that fail verification, leaving data
+// untouched, so that the session is reset instead of failing
+// the request.
+func (v *Verifier) Decode(data any, tokenString string) error {
+	_ = v.DecodeWithLeeway(data, tokenString, 0)
+	return nil
+}
+
+// DecodeWithLeeway tolerates the given clock skew when validating
+// time-based claims. Unlike [Verifier.Decode], it returns
+// verification errors, such as [jwt.ErrTokenExpired].
+func (v *Verifier) DecodeWithLeeway(data any, tokenString string, leeway time.Duration) error {
+	return decodeClaims(data, tokenString, []string{
+		"EdDSA", "ES256", "ES384", "ES512",
+	}, leeway, v.lookup)
+}
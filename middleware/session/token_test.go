@@ -0,0 +1,136 @@
+package session
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkotik/htadaptor/middleware/session/secrets"
+)
+
+func testTokenizerRoundTrip(t *testing.T, tokenizer Tokenizer) string {
+	t.Helper()
+	token, err := tokenizer.Encode(map[string]any{
+		"user_id": "someone",
+		"expires": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values map[string]any
+	if err = tokenizer.Decode(&values, token); err != nil {
+		t.Fatal(err)
+	}
+	if values["user_id"] != "someone" {
+		t.Fatalf("decoded values do not match: %+v", values)
+	}
+	return token
+}
+
+func testTokenizerRejects(t *testing.T, tokenizer Tokenizer, tokens ...string) {
+	t.Helper()
+	for _, token := range tokens {
+		var values map[string]any
+		if err := tokenizer.Decode(&values, token); err != nil {
+			t.Fatal(err)
+		}
+		if len(values) != 0 {
+			t.Fatalf("token %q was not rejected: %+v", token, values)
+		}
+	}
+}
+
+func tamper(token string) string {
+	b := []byte(token)
+	i := len(b) / 2
+	if b[i] == 'A' {
+		b[i] = 'B'
+	} else {
+		b[i] = 'A'
+	}
+	return string(b)
+}
+
+func TestHMACTokenizer(t *testing.T) {
+	tokenizer := NewTokenizer()
+	token := testTokenizerRoundTrip(t, tokenizer)
+	// decoding invalid tokens more than once used to deadlock
+	testTokenizerRejects(t, tokenizer, "", "short", tamper(token), tamper(token))
+	testTokenizerRoundTrip(t, tokenizer)
+}
+
+func TestEncryptedTokenizer(t *testing.T) {
+	tokenizer, err := NewEncryptedTokenizer("session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := testTokenizerRoundTrip(t, tokenizer)
+	if strings.Contains(token, "someone") {
+		t.Fatal("token is not encrypted")
+	}
+	testTokenizerRejects(t, tokenizer, "", "short", "!invalid base64!", tamper(token))
+
+	other, err := NewEncryptedTokenizer("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTokenizerRejects(t, other, token)
+
+	t.Run("rotation", func(t *testing.T) {
+		secret := func(id string) *secrets.Secret {
+			return &secrets.Secret{
+				ID:      []byte(id),
+				Entropy: []byte(strings.Repeat(id, 32/len(id))),
+			}
+		}
+		tokenizer := &aeadTokenizer{
+			associatedData: []byte("session"),
+			wmu:            &sync.Mutex{},
+			rmu:            &sync.Mutex{},
+		}
+		if err := tokenizer.Rotate(secret("first..."), secret("first...")); err != nil {
+			t.Fatal(err)
+		}
+		token := testTokenizerRoundTrip(t, tokenizer)
+		if err := tokenizer.Rotate(secret("second.."), secret("first...")); err != nil {
+			t.Fatal(err)
+		}
+		testTokenizerRoundTrip(t, tokenizer)
+		var values map[string]any
+		if err := tokenizer.Decode(&values, token); err != nil {
+			t.Fatal(err)
+		}
+		if values["user_id"] != "someone" {
+			t.Fatal("token encrypted with the past key was rejected")
+		}
+		if err := tokenizer.Rotate(secret("third..."), secret("second..")); err != nil {
+			t.Fatal(err)
+		}
+		testTokenizerRejects(t, tokenizer, token)
+	})
+}
+
+func TestDefaultTokenizerBindsCookieName(t *testing.T) {
+	codec, err := NewCookieCodec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &options{}
+	for _, option := range []Option{
+		WithDefaultExpiry(),
+		WithCookieCodec(codec),
+		WithDefaultTokenizer(),
+	} {
+		if err = option(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tokenizer, ok := o.Tokenizer.(*aeadTokenizer)
+	if !ok {
+		t.Fatalf("unexpected default tokenizer %T", o.Tokenizer)
+	}
+	if name := string(tokenizer.associatedData); name != CookiePrefixHost+defaultCookieName {
+		t.Fatalf("token is bound to %q instead of the cookie name", name)
+	}
+}
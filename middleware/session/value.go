@@ -3,6 +3,8 @@ package session
 import (
 	"context"
 	"time"
+
+	"github.com/dkotik/htadaptor/middleware/traceid"
 )
 
 func Value(ctx context.Context, key string) any {
@@ -36,14 +38,10 @@ func ID(ctx context.Context) string {
 	return c.ID()
 }
 
+// TraceID returns the identifier of the current [traceid.Span],
+// regardless of the middleware order.
 func TraceID(ctx context.Context) string {
-	c, ok := ctx.Value(contextKey).(*sessionContext)
-	if !ok {
-		return ""
-	}
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.TraceID()
+	return traceid.FromContext(ctx)
 }
 
 func Role(ctx context.Context) string {
@@ -0,0 +1,35 @@
+package tenant
+
+import (
+	"net/http"
+)
+
+// Error signals a failure to resolve a tenant.
+type Error uint8
+
+const (
+	ErrUnidentifiedTenant Error = iota
+	ErrUnknownTenant
+)
+
+// HyperTextStatusCode satisfies [htadaptor.Error] interface.
+func (e Error) HyperTextStatusCode() int {
+	switch e {
+	case ErrUnidentifiedTenant:
+		return http.StatusBadRequest
+	default:
+		return http.StatusNotFound
+	}
+}
+
+// Error satisfies [error] interface.
+func (e Error) Error() string {
+	switch e {
+	case ErrUnidentifiedTenant:
+		return "request does not identify a tenant"
+	case ErrUnknownTenant:
+		return "tenant does not exist"
+	default:
+		return "unknown tenant error"
+	}
+}
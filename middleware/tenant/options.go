@@ -0,0 +1,224 @@
+package tenant
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// ErrorHandler reports tenant resolution failures. It matches
+// [htadaptor.ErrorHandler], so any adaptor error handler can be used.
+type ErrorHandler interface {
+	HandleError(http.ResponseWriter, *http.Request, error) error
+}
+
+// ErrorHandlerFunc is a functional implementation of [ErrorHandler].
+type ErrorHandlerFunc func(http.ResponseWriter, *http.Request, error) error
+
+// HandleError satisfies [ErrorHandler] interface.
+func (f ErrorHandlerFunc) HandleError(w http.ResponseWriter, r *http.Request, err error) error {
+	return f(w, r, err)
+}
+
+// Identifier recovers a tenant key from a request. It returns
+// an empty key, if the request does not identify a tenant, and
+// the request that should be passed to the next handler.
+type Identifier func(*http.Request) (key string, next *http.Request)
+
+type options struct {
+	Resolver        Resolver
+	Identifiers     []Identifier
+	CacheExpiration time.Duration
+	CacheCapacity   int
+	ErrorHandler    ErrorHandler
+}
+
+type Option func(*options) error
+
+// WithResolver sets the [Resolver] that looks up tenants by key.
+func WithResolver(r Resolver) Option {
+	return func(o *options) error {
+		if r == nil {
+			return errors.New("cannot use <nil> resolver")
+		}
+		if o.Resolver != nil {
+			return errors.New("resolver is already set")
+		}
+		o.Resolver = r
+		return nil
+	}
+}
+
+// WithIdentifier adds a custom way of recovering the tenant key.
+// Identifiers are tried in the order they were added until one
+// of them returns a key.
+func WithIdentifier(i Identifier) Option {
+	return func(o *options) error {
+		if i == nil {
+			return errors.New("cannot use <nil> identifier")
+		}
+		o.Identifiers = append(o.Identifiers, i)
+		return nil
+	}
+}
+
+// WithSubdomain identifies tenants by the subdomain of the given
+// domain, such as "acme" for "acme.example.com" requests.
+func WithSubdomain(domain string) Option {
+	return func(o *options) error {
+		domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+		if domain == "" {
+			return errors.New("cannot use an empty domain")
+		}
+		suffix := "." + domain
+		return WithIdentifier(func(r *http.Request) (string, *http.Request) {
+			host := r.Host
+			if name, _, err := net.SplitHostPort(host); err == nil {
+				host = name
+			}
+			host = strings.TrimSuffix(strings.ToLower(host), ".")
+			subdomain, _ := strings.CutSuffix(host, suffix)
+			if subdomain == host {
+				return "", r
+			}
+			return subdomain, r
+		})(o)
+	}
+}
+
+// WithPathValue identifies tenants by a request path value,
+// such as the subdomain captured by a wildcard host of
+// [htadaptor.NewHostMux] under [htadaptor.HostSubdomainPathValue]
+// or a "{tenant}" pattern wildcard of [http.ServeMux].
+func WithPathValue(name string) Option {
+	return func(o *options) error {
+		if name == "" {
+			return errors.New("cannot use an empty path value name")
+		}
+		return WithIdentifier(func(r *http.Request) (string, *http.Request) {
+			return r.PathValue(name), r
+		})(o)
+	}
+}
+
+// WithPathPrefix identifies tenants by the first segment of the
+// request path, such as "acme" for "/acme/orders". The segment
+// is removed from the path passed to the next handler, so that
+// it serves "/orders" for every tenant.
+func WithPathPrefix() Option {
+	return WithIdentifier(func(r *http.Request) (string, *http.Request) {
+		key, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if key == "" {
+			return "", r
+		}
+		next := r.Clone(r.Context())
+		next.URL.Path = "/" + rest
+		next.URL.RawPath = ""
+		return key, next
+	})
+}
+
+// WithHeader identifies tenants by a request header, which
+// suits API gateways that authenticate the tenant upstream.
+// Never trust this header from clients directly.
+func WithHeader(name string) Option {
+	return func(o *options) error {
+		if name == "" {
+			return errors.New("cannot use an empty header name")
+		}
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		return WithIdentifier(func(r *http.Request) (string, *http.Request) {
+			return strings.TrimSpace(r.Header.Get(name)), r
+		})(o)
+	}
+}
+
+// WithCacheExpiration sets how long resolved and unknown
+// tenants are remembered.
+func WithCacheExpiration(d time.Duration) Option {
+	return func(o *options) error {
+		if d < time.Second {
+			return errors.New("cache expiration must be at least one second")
+		}
+		if o.CacheExpiration != 0 {
+			return errors.New("cache expiration is already set")
+		}
+		o.CacheExpiration = d
+		return nil
+	}
+}
+
+func WithDefaultCacheExpiration() Option {
+	return func(o *options) error {
+		if o.CacheExpiration != 0 {
+			return nil
+		}
+		o.CacheExpiration = time.Minute
+		return nil
+	}
+}
+
+// WithCacheCapacity limits the number of remembered tenants.
+// Unknown tenant names are limited separately by the same number.
+func WithCacheCapacity(entries int) Option {
+	return func(o *options) error {
+		if entries < 1 {
+			return errors.New("cache capacity must be positive")
+		}
+		if o.CacheCapacity != 0 {
+			return errors.New("cache capacity is already set")
+		}
+		o.CacheCapacity = entries
+		return nil
+	}
+}
+
+// DefaultCacheCapacity is the number of tenants remembered
+// unless [WithCacheCapacity] is used.
+const DefaultCacheCapacity = 1024
+
+func WithDefaultCacheCapacity() Option {
+	return func(o *options) error {
+		if o.CacheCapacity != 0 {
+			return nil
+		}
+		o.CacheCapacity = DefaultCacheCapacity
+		return nil
+	}
+}
+
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(o *options) error {
+		if h == nil {
+			return errors.New("cannot use a <nil> error handler")
+		}
+		if o.ErrorHandler != nil {
+			return errors.New("error handler is already set")
+		}
+		o.ErrorHandler = h
+		return nil
+	}
+}
+
+func WithDefaultErrorHandler() Option {
+	return func(o *options) error {
+		if o.ErrorHandler != nil {
+			return nil
+		}
+		o.ErrorHandler = ErrorHandlerFunc(
+			func(w http.ResponseWriter, r *http.Request, err error) error {
+				var tenantError Error
+				if errors.As(err, &tenantError) {
+					http.Error(w, tenantError.Error(), tenantError.HyperTextStatusCode())
+					return err
+				}
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return err
+			},
+		)
+		return nil
+	}
+}
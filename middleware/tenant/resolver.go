@@ -0,0 +1,109 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Resolver finds a tenant by the key identified in a request,
+// such as a subdomain. It returns [ErrUnknownTenant], if there
+// is no such tenant.
+type Resolver interface {
+	ResolveTenant(ctx context.Context, key string) (*Tenant, error)
+}
+
+// ResolverFunc is a functional implementation of [Resolver].
+type ResolverFunc func(context.Context, string) (*Tenant, error)
+
+// ResolveTenant satisfies [Resolver] interface.
+func (f ResolverFunc) ResolveTenant(ctx context.Context, key string) (*Tenant, error) {
+	return f(ctx, key)
+}
+
+// Map is a [Resolver] of a fixed set of tenants by key.
+type Map map[string]*Tenant
+
+// ResolveTenant satisfies [Resolver] interface.
+func (m Map) ResolveTenant(_ context.Context, key string) (*Tenant, error) {
+	if t, ok := m[key]; ok && t != nil {
+		return t, nil
+	}
+	return nil, ErrUnknownTenant
+}
+
+type cacheEntry struct {
+	tenant  *Tenant
+	err     error
+	expires time.Time
+}
+
+// cache remembers resolved and unknown tenants, so that
+// neither busy tenants nor probing for tenant names
+// reach the [Resolver] on every request. Unknown names are
+// kept apart, so that probing cannot evict resolved tenants.
+type cache struct {
+	resolver   Resolver
+	expiration time.Duration
+	capacity   int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	unknown map[string]cacheEntry
+}
+
+func (c *cache) ResolveTenant(ctx context.Context, key string) (*Tenant, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry, ok = c.unknown[key]
+	}
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.tenant, entry.err
+	}
+
+	t, err := c.resolver.ResolveTenant(ctx, key)
+	if err == nil && t == nil {
+		err = ErrUnknownTenant
+	}
+	if err != nil && !errors.Is(err, ErrUnknownTenant) {
+		return nil, err // do not cache failures
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := c.entries
+	if err != nil {
+		entries = c.unknown
+	}
+	delete(c.entries, key)
+	delete(c.unknown, key)
+	if len(entries) >= c.capacity {
+		c.evict(entries, now)
+	}
+	entries[key] = cacheEntry{
+		tenant:  t,
+		err:     err,
+		expires: now.Add(c.expiration),
+	}
+	return t, err
+}
+
+// evict removes expired entries or, if there are none,
+// an arbitrary entry to make room for another.
+func (c *cache) evict(entries map[string]cacheEntry, now time.Time) {
+	for key, entry := range entries {
+		if !now.Before(entry.expires) {
+			delete(entries, key)
+		}
+	}
+	for key := range entries {
+		if len(entries) < c.capacity {
+			return
+		}
+		delete(entries, key)
+	}
+}
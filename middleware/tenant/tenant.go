@@ -0,0 +1,120 @@
+/*
+Package tenant provides a middleware that resolves the tenant
+of a request from a subdomain, a path prefix, or a header using
+a pluggable [Resolver]. Resolved tenants are cached.
+
+The [Tenant] is placed into request [context.Context] and can be
+recovered using [TenantFromContext] or its identifier injected into
+decoded request structs using [extract.NewTenantValueExtractor].
+Use [htadaptor.Adaptor.AdaptPerTenant] to give each tenant its
+own adaptor options, such as templates.
+*/
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type contextKeyType struct{}
+
+var contextKey = contextKeyType{}
+
+// Tenant is the party that owns the data that the request
+// operates on.
+type Tenant struct {
+	ID     string
+	Values map[string]any
+}
+
+// TenantFromContext recovers the [Tenant] placed into
+// the context by the middleware.
+func TenantFromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(contextKey).(*Tenant)
+	return t, ok && t != nil
+}
+
+// ContextWithTenant places the [Tenant] into context.
+// Use [TenantFromContext] to recover it later.
+func ContextWithTenant(parent context.Context, t *Tenant) context.Context {
+	return context.WithValue(parent, contextKey, t)
+}
+
+type middleware struct {
+	next         http.Handler
+	resolver     Resolver
+	identifiers  []Identifier
+	errorHandler ErrorHandler
+}
+
+// New creates a middleware that rejects requests that do not
+// identify a tenant with [ErrUnidentifiedTenant] and requests
+// for tenants that do not exist with [ErrUnknownTenant].
+func New(withOptions ...Option) (func(http.Handler) http.Handler, error) {
+	o := &options{}
+	var err error
+	for _, option := range append(
+		withOptions,
+		WithDefaultCacheExpiration(),
+		WithDefaultCacheCapacity(),
+		WithDefaultErrorHandler(),
+		func(o *options) error {
+			if o.Resolver == nil {
+				return errors.New("resolver is required")
+			}
+			if len(o.Identifiers) == 0 {
+				return errors.New("provide at least one way to identify tenants")
+			}
+			return nil
+		},
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create tenant middleware: %w", err)
+		}
+	}
+
+	resolver := &cache{
+		resolver:   o.Resolver,
+		expiration: o.CacheExpiration,
+		capacity:   o.CacheCapacity,
+		entries:    make(map[string]cacheEntry),
+		unknown:    make(map[string]cacheEntry),
+	}
+	return func(next http.Handler) http.Handler {
+		if next == nil {
+			panic("cannot use a <nil> next handler")
+		}
+		return &middleware{
+			next:         next,
+			resolver:     resolver,
+			identifiers:  o.Identifiers,
+			errorHandler: o.ErrorHandler,
+		}
+	}, nil
+}
+
+func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t, next, err := m.resolve(r)
+	if err != nil {
+		_ = m.errorHandler.HandleError(w, r, err)
+		return
+	}
+	m.next.ServeHTTP(w, next.WithContext(ContextWithTenant(next.Context(), t)))
+}
+
+func (m *middleware) resolve(r *http.Request) (*Tenant, *http.Request, error) {
+	for _, identify := range m.identifiers {
+		key, next := identify(r)
+		if key == "" {
+			continue
+		}
+		t, err := m.resolver.ResolveTenant(r.Context(), key)
+		if err != nil {
+			return nil, nil, err
+		}
+		return t, next, nil
+	}
+	return nil, nil, ErrUnidentifiedTenant
+}
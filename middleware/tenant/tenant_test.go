@@ -0,0 +1,255 @@
+package tenant_test
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dkotik/htadaptor"
+	"github.com/dkotik/htadaptor/middleware/tenant"
+)
+
+type ordersRequest struct {
+	TenantID string `schema:"tenant"`
+}
+
+func (r *ordersRequest) Validate(ctx context.Context) error {
+	if r.TenantID == "" {
+		return errors.New("tenant ID is required")
+	}
+	return nil
+}
+
+func TestTenantResolution(t *testing.T) {
+	var lookups atomic.Int32
+	mw, err := tenant.New(
+		tenant.WithResolver(tenant.ResolverFunc(
+			func(ctx context.Context, key string) (*tenant.Tenant, error) {
+				lookups.Add(1)
+				return tenant.Map{
+					"acme":   {ID: "acme"},
+					"globex": {ID: "globex"},
+				}.ResolveTenant(ctx, key)
+			},
+		)),
+		tenant.WithHeader("X-Tenant"),
+		tenant.WithSubdomain("example.com"),
+		tenant.WithPathPrefix(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(htadaptor.Must(htadaptor.New().AdaptFunc(
+		func(ctx context.Context, r *ordersRequest) (string, error) {
+			current, ok := tenant.TenantFromContext(ctx)
+			if !ok || current.ID != r.TenantID {
+				return "", errors.New("tenant was not placed into context")
+			}
+			return r.TenantID, nil
+		},
+		htadaptor.WithQueryValues("tenant"),
+		htadaptor.WithTenantValue("tenant"),
+	)))
+
+	cases := []struct {
+		Name       string
+		URL        string
+		Header     string
+		StatusCode int
+		Body       string
+	}{
+		{
+			Name:       "subdomain",
+			URL:        "http://acme.example.com/orders",
+			StatusCode: http.StatusOK,
+			Body:       "acme",
+		},
+		{
+			Name:       "subdomain with port",
+			URL:        "http://Globex.example.com:8080/orders",
+			StatusCode: http.StatusOK,
+			Body:       "globex",
+		},
+		{
+			Name:       "header takes precedence",
+			URL:        "http://acme.example.com/orders",
+			Header:     "globex",
+			StatusCode: http.StatusOK,
+			Body:       "globex",
+		},
+		{
+			Name:       "path prefix",
+			URL:        "http://other.org/acme/orders",
+			StatusCode: http.StatusOK,
+			Body:       "acme",
+		},
+		{
+			Name:       "query cannot override tenant",
+			URL:        "http://acme.example.com/orders?tenant=globex",
+			StatusCode: http.StatusOK,
+			Body:       "acme",
+		},
+		{
+			Name:       "unknown tenant",
+			URL:        "http://initech.example.com/orders",
+			StatusCode: http.StatusNotFound,
+			Body:       tenant.ErrUnknownTenant.Error(),
+		},
+		{
+			Name:       "unidentified tenant",
+			URL:        "http://other.org/",
+			StatusCode: http.StatusBadRequest,
+			Body:       tenant.ErrUnidentifiedTenant.Error(),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, c.URL, nil)
+			if c.Header != "" {
+				r.Header.Set("X-Tenant", c.Header)
+			}
+			h.ServeHTTP(w, r)
+			if w.Code != c.StatusCode {
+				t.Fatalf("expected status %d, got %d: %s", c.StatusCode, w.Code, w.Body.String())
+			}
+			if body := w.Body.String(); !strings.Contains(body, c.Body) {
+				t.Fatalf("expected body to contain %q, got %q", c.Body, body)
+			}
+		})
+	}
+
+	before := lookups.Load()
+	for range 3 {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(
+			http.MethodGet, "http://acme.example.com/orders", nil))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(
+			http.MethodGet, "http://initech.example.com/orders", nil))
+	}
+	if after := lookups.Load(); after != before {
+		t.Fatalf("resolver was called %d times despite cache", after-before)
+	}
+}
+
+func TestProbingDoesNotEvictTenants(t *testing.T) {
+	var lookups atomic.Int32
+	mw, err := tenant.New(
+		tenant.WithResolver(tenant.ResolverFunc(
+			func(ctx context.Context, key string) (*tenant.Tenant, error) {
+				lookups.Add(1)
+				return tenant.Map{"acme": {ID: "acme"}}.ResolveTenant(ctx, key)
+			},
+		)),
+		tenant.WithHeader("X-Tenant"),
+		tenant.WithCacheCapacity(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(key string) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Tenant", key)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve("acme")
+	for _, key := range []string{"probe1", "probe2", "probe3"} {
+		serve(key)
+	}
+	before := lookups.Load()
+	serve("acme")
+	if after := lookups.Load(); after != before {
+		t.Fatal("probing unknown tenants evicted a resolved tenant")
+	}
+}
+
+func TestTenantFromHostMux(t *testing.T) {
+	mw, err := tenant.New(
+		tenant.WithResolver(tenant.Map{"acme": {ID: "acme"}}),
+		tenant.WithPathValue(htadaptor.HostSubdomainPathValue),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux, err := htadaptor.NewHostMux(htadaptor.HostMuxAssociation{
+		Name: "*.example.com",
+		Handler: mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current, _ := tenant.TenantFromContext(r.Context())
+			io.WriteString(w, current.ID)
+		})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://acme.example.com/", nil))
+	if body := w.Body.String(); body != "acme" {
+		t.Fatalf("unexpected response %q", body)
+	}
+}
+
+func TestAdaptPerTenant(t *testing.T) {
+	templates := map[string]*template.Template{
+		"acme":   template.Must(template.New("").Parse(`Acme: {{ . }}`)),
+		"globex": template.Must(template.New("").Parse(`Globex: {{ . }}`)),
+	}
+	h, err := htadaptor.New().AdaptPerTenant(
+		func(t *tenant.Tenant) ([]htadaptor.Option, error) {
+			return []htadaptor.Option{
+				htadaptor.WithTemplate(templates[t.ID]),
+			}, nil
+		},
+		func(a htadaptor.Adaptor) (http.Handler, error) {
+			return a.AdaptFunc(
+				func(ctx context.Context, r *ordersRequest) (string, error) {
+					return "orders of " + r.TenantID, nil
+				},
+				htadaptor.WithTenantValue("tenant"),
+			)
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw, err := tenant.New(
+		tenant.WithResolver(tenant.Map{
+			"acme":   {ID: "acme"},
+			"globex": {ID: "globex"},
+		}),
+		tenant.WithPathPrefix(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adapted := h
+	h = mw(h)
+
+	for prefix, expected := range map[string]string{
+		"acme":   "Acme: orders of acme",
+		"globex": "Globex: orders of globex",
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+prefix+"/orders", nil))
+		if body := w.Body.String(); body != expected {
+			t.Errorf("expected %q, got %q", expected, body)
+		}
+	}
+
+	// a tenant resolved anew picks up changed options
+	templates["acme"] = template.Must(template.New("").Parse(`Acme Corp: {{ . }}`))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	adapted.ServeHTTP(w, r.WithContext(
+		tenant.ContextWithTenant(r.Context(), &tenant.Tenant{ID: "acme"})))
+	if body := w.Body.String(); body != "Acme Corp: orders of acme" {
+		t.Errorf("tenant options were not refreshed: %q", body)
+	}
+}
@@ -21,15 +21,17 @@ func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
 }
 
 func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
-	if ctx == nil {
-		return h.handler.Handle(ctx, r)
-	}
-	if tid, ok := ctx.Value(contextKey{}).(string); ok {
-		traceAttr := slog.Attr{
-			Key:   "trace_id",
-			Value: slog.StringValue(tid),
-		}
-		r.AddAttrs(traceAttr)
+	if c := SpanContextFromContext(ctx); c.IsValid() {
+		r.AddAttrs(
+			slog.Attr{
+				Key:   "trace_id",
+				Value: slog.StringValue(c.TraceID.String()),
+			},
+			slog.Attr{
+				Key:   "span_id",
+				Value: slog.StringValue(c.SpanID.String()),
+			},
+		)
 	}
 	return h.handler.Handle(ctx, r)
 }
@@ -0,0 +1,120 @@
+package traceid
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/dkotik/htadaptor/internal/recorder"
+)
+
+// Extract returns a context with the remote parent described
+// by the request headers. Malformed headers are ignored, which
+// starts a new trace, as the recommendation requires.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	parent, err := ParseTraceParent(h.Get(TraceParentHeader))
+	if err != nil {
+		return ctx
+	}
+	if state, err := ParseTraceState(h.Get(TraceStateHeader)); err == nil {
+		parent.TraceState = state
+	}
+	return ContextWithSpanContext(ctx, parent)
+}
+
+// Inject writes the current span of the context into
+// outgoing request headers.
+func Inject(ctx context.Context, h http.Header) {
+	c := SpanContextFromContext(ctx)
+	if !c.IsValid() {
+		return
+	}
+	h.Set(TraceParentHeader, c.TraceParent())
+	if c.TraceState != "" {
+		h.Set(TraceStateHeader, c.TraceState)
+	} else {
+		h.Del(TraceStateHeader)
+	}
+}
+
+// Middleware starts a server span for each request that continues
+// the trace from the traceparent header. The span is named after
+// the route pattern that [http.ServeMux] matched, so wrap
+// the mux directly: middleware in between that replaces
+// the request hides the pattern.
+func (t *Tracer) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if next == nil {
+			panic("cannot use a <nil> next handler")
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := t.Start(
+				Extract(r.Context(), r.Header),
+				r.Method,
+				SpanKindServer,
+				slog.String("http.request.method", r.Method),
+				slog.String("url.path", r.URL.Path),
+			)
+			rec := recorder.New(w)
+			r = r.WithContext(ctx)
+			next.ServeHTTP(rec, r)
+
+			code := rec.StatusCode()
+			span.SetAttributes(slog.Int("http.response.status_code", code))
+			if r.Pattern != "" {
+				span.SetName(r.Pattern)
+				span.SetAttributes(slog.String("http.route", r.Pattern))
+			}
+			if code >= http.StatusInternalServerError {
+				span.End(statusError(code))
+				return
+			}
+			span.End(nil)
+		})
+	}
+}
+
+type statusError int
+
+func (e statusError) Error() string {
+	return http.StatusText(int(e))
+}
+
+type transport struct {
+	tracer *Tracer
+	next   http.RoundTripper
+}
+
+// NewTransport wraps an [http.RoundTripper] to start client spans
+// and to propagate them with traceparent and tracestate headers.
+// Uses [http.DefaultTransport] if next is <nil>.
+func (t *Tracer) NewTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{tracer: t, next: next}
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(
+		r.Context(),
+		r.Method,
+		SpanKindClient,
+		slog.String("http.request.method", r.Method),
+		slog.String("server.address", r.URL.Host),
+	)
+	r = r.Clone(ctx) // round trippers must not modify the request
+	Inject(ctx, r.Header)
+	response, err := t.next.RoundTrip(r)
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+	span.SetAttributes(slog.Int("http.response.status_code", response.StatusCode))
+	if response.StatusCode >= http.StatusBadRequest {
+		span.End(statusError(response.StatusCode))
+	} else {
+		span.End(nil)
+	}
+	return response, nil
+}
@@ -0,0 +1,307 @@
+/*
+Package otlp exports [traceid.SpanData] to an OpenTelemetry
+collector using the OTLP/HTTP protocol with JSON encoding.
+
+	exporter, err := otlp.New(
+	  otlp.WithEndpoint("http://collector:4318/v1/traces"),
+	  otlp.WithServiceName("billing"),
+	)
+	tracer, err := traceid.New(traceid.WithExporter(exporter))
+*/
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/dkotik/htadaptor/middleware/traceid"
+)
+
+// DefaultEndpoint is where a local collector accepts traces.
+const DefaultEndpoint = "http://localhost:4318/v1/traces"
+
+const (
+	scopeName               = "github.com/dkotik/htadaptor/middleware/traceid"
+	statusCodeError         = 2
+	maxErrorResponseSize    = 1 << 12
+	defaultServiceName      = "unknown_service"
+	serviceNameAttributeKey = "service.name"
+)
+
+type options struct {
+	Endpoint    string
+	ServiceName string
+	Headers     http.Header
+	HTTPClient  *http.Client
+}
+
+type Option func(*options) error
+
+// WithEndpoint sets the full URL of the collector traces path.
+func WithEndpoint(endpoint string) Option {
+	return func(o *options) error {
+		if o.Endpoint != "" {
+			return errors.New("endpoint is already set")
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid endpoint: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("endpoint scheme %q is not supported", u.Scheme)
+		}
+		o.Endpoint = endpoint
+		return nil
+	}
+}
+
+func WithDefaultEndpoint() Option {
+	return func(o *options) error {
+		if o.Endpoint != "" {
+			return nil
+		}
+		return WithEndpoint(DefaultEndpoint)(o)
+	}
+}
+
+// WithServiceName sets the service.name resource attribute.
+func WithServiceName(name string) Option {
+	return func(o *options) error {
+		if o.ServiceName != "" {
+			return errors.New("service name is already set")
+		}
+		if name == "" {
+			return errors.New("cannot use an empty service name")
+		}
+		o.ServiceName = name
+		return nil
+	}
+}
+
+func WithDefaultServiceName() Option {
+	return func(o *options) error {
+		if o.ServiceName != "" {
+			return nil
+		}
+		return WithServiceName(defaultServiceName)(o)
+	}
+}
+
+// WithHeader adds a header to every export request,
+// for example, for collector authentication.
+func WithHeader(name, value string) Option {
+	return func(o *options) error {
+		if name == "" {
+			return errors.New("cannot use an empty header name")
+		}
+		if o.Headers == nil {
+			o.Headers = make(http.Header)
+		}
+		o.Headers.Add(name, value)
+		return nil
+	}
+}
+
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) error {
+		if c == nil {
+			return errors.New("cannot use a <nil> HTTP client")
+		}
+		if o.HTTPClient != nil {
+			return errors.New("HTTP client is already set")
+		}
+		o.HTTPClient = c
+		return nil
+	}
+}
+
+func WithDefaultHTTPClient() Option {
+	return func(o *options) error {
+		if o.HTTPClient != nil {
+			return nil
+		}
+		return WithHTTPClient(http.DefaultClient)(o)
+	}
+}
+
+// Exporter satisfies [traceid.Exporter].
+type Exporter struct {
+	endpoint string
+	headers  http.Header
+	client   *http.Client
+	resource resource
+}
+
+func New(withOptions ...Option) (*Exporter, error) {
+	o := &options{}
+	var err error
+	for _, option := range append(
+		withOptions,
+		WithDefaultEndpoint(),
+		WithDefaultServiceName(),
+		WithDefaultHTTPClient(),
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create OTLP exporter: %w", err)
+		}
+	}
+	return &Exporter{
+		endpoint: o.Endpoint,
+		headers:  o.Headers,
+		client:   o.HTTPClient,
+		resource: resource{
+			Attributes: []keyValue{newKeyValue(slog.String(serviceNameAttributeKey, o.ServiceName))},
+		},
+	}, nil
+}
+
+// ExportSpans posts spans to the collector.
+func (e *Exporter) ExportSpans(ctx context.Context, spans []traceid.SpanData) error {
+	payload := exportRequest{ResourceSpans: []resourceSpans{{
+		Resource: e.resource,
+		ScopeSpans: []scopeSpans{{
+			Scope: scope{Name: scopeName},
+			Spans: make([]span, 0, len(spans)),
+		}},
+	}}}
+	for _, s := range spans {
+		payload.ResourceSpans[0].ScopeSpans[0].Spans = append(
+			payload.ResourceSpans[0].ScopeSpans[0].Spans,
+			newSpan(s),
+		)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot encode spans: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range e.headers {
+		request.Header[name] = values
+	}
+	request.Header.Set("content-type", "application/json")
+	response, err := e.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("cannot reach collector: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorResponseSize))
+		return fmt.Errorf("collector rejected spans with status %d: %s", response.StatusCode, bytes.TrimSpace(detail))
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	return nil
+}
+
+// The types below follow the OTLP JSON mapping: identifiers are
+// hexadecimal strings and 64-bit integers are decimal strings.
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	TraceState        string     `json:"traceState,omitempty"`
+	Flags             uint32     `json:"flags"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func newKeyValue(attr slog.Attr) keyValue {
+	v := attr.Value.Resolve()
+	kv := keyValue{Key: attr.Key}
+	switch v.Kind() {
+	case slog.KindBool:
+		b := v.Bool()
+		kv.Value.BoolValue = &b
+	case slog.KindInt64:
+		i := strconv.FormatInt(v.Int64(), 10)
+		kv.Value.IntValue = &i
+	case slog.KindUint64:
+		i := strconv.FormatUint(v.Uint64(), 10)
+		kv.Value.IntValue = &i
+	case slog.KindFloat64:
+		f := v.Float64()
+		kv.Value.DoubleValue = &f
+	default:
+		s := v.String()
+		kv.Value.StringValue = &s
+	}
+	return kv
+}
+
+func newSpan(s traceid.SpanData) span {
+	result := span{
+		TraceID:           s.Context.TraceID.String(),
+		SpanID:            s.Context.SpanID.String(),
+		TraceState:        s.Context.TraceState,
+		Flags:             uint32(s.Context.Flags),
+		Name:              s.Name,
+		Kind:              int(s.Kind),
+		StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+	}
+	if s.Parent.IsValid() {
+		result.ParentSpanID = s.Parent.String()
+	}
+	for _, attr := range s.Attributes {
+		result.Attributes = append(result.Attributes, newKeyValue(attr))
+	}
+	if s.Error != nil {
+		result.Status = status{Code: statusCodeError, Message: s.Error.Error()}
+	}
+	return result
+}
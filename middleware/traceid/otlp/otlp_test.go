@@ -0,0 +1,111 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkotik/htadaptor/middleware/traceid"
+)
+
+func TestExporter(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("content-type") != "application/json" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		payload := make(map[string]any)
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received <- payload
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer collector.Close()
+
+	exporter, err := New(
+		WithEndpoint(collector.URL+"/v1/traces"),
+		WithServiceName("test"),
+		WithHeader("authorization", "Bearer token"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer, err := traceid.New(traceid.WithExporter(exporter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := tracer.Start(context.Background(), "parent", traceid.SpanKindServer,
+		slog.String("http.route", "/"), slog.Int("http.response.status_code", 500))
+	_, child := tracer.Start(ctx, "child", traceid.SpanKindInternal)
+	child.End(errors.New("failure"))
+	parent.End(nil)
+	if err = tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := <-received
+	resourceSpans := payload["resourceSpans"].([]any)[0].(map[string]any)
+	resource := resourceSpans["resource"].(map[string]any)
+	serviceName := resource["attributes"].([]any)[0].(map[string]any)
+	if serviceName["key"] != "service.name" || serviceName["value"].(map[string]any)["stringValue"] != "test" {
+		t.Fatalf("unexpected resource: %+v", resource)
+	}
+	spans := resourceSpans["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	if len(spans) != 2 {
+		t.Fatalf("expected two spans, got %d", len(spans))
+	}
+	exportedChild, exportedParent := spans[0].(map[string]any), spans[1].(map[string]any)
+	if exportedChild["traceId"] != parent.Context().TraceID.String() {
+		t.Fatal("trace identifier does not match:", exportedChild["traceId"])
+	}
+	if exportedChild["parentSpanId"] != parent.Context().SpanID.String() {
+		t.Fatal("parent span identifier does not match:", exportedChild["parentSpanId"])
+	}
+	if exportedChild["status"].(map[string]any)["code"] != float64(2) {
+		t.Fatalf("child status is not an error: %+v", exportedChild["status"])
+	}
+	if exportedParent["kind"] != float64(traceid.SpanKindServer) {
+		t.Fatal("unexpected span kind:", exportedParent["kind"])
+	}
+	statusCode := exportedParent["attributes"].([]any)[1].(map[string]any)["value"].(map[string]any)
+	if statusCode["intValue"] != "500" {
+		t.Fatalf("integer attribute was not encoded as a string: %+v", statusCode)
+	}
+}
+
+func TestExporterRejection(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer collector.Close()
+
+	exporter, err := New(WithEndpoint(collector.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = exporter.ExportSpans(context.Background(), []traceid.SpanData{{
+		Name: "test",
+		Context: traceid.SpanContext{
+			TraceID: traceid.NewID(),
+			SpanID:  traceid.NewSpanID(),
+		},
+	}}); err == nil {
+		t.Fatal("collector rejection was not reported")
+	}
+
+	if _, err = New(WithEndpoint("ftp://collector")); err == nil {
+		t.Fatal("unsupported endpoint scheme was accepted")
+	}
+}
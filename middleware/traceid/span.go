@@ -0,0 +1,134 @@
+package traceid
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type contextKey struct{}
+
+// SpanKind describes the relationship of a span to remote callers.
+// Values match OpenTelemetry span kinds.
+type SpanKind int
+
+const (
+	SpanKindUnspecified SpanKind = iota
+	SpanKindInternal
+	SpanKindServer
+	SpanKindClient
+)
+
+// Span records a timed operation. Spans are safe for concurrent use.
+type Span struct {
+	tracer *Tracer
+
+	mu         sync.Mutex
+	name       string
+	context    SpanContext
+	parent     SpanID
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	attributes []slog.Attr
+}
+
+// SpanData is a snapshot of an ended [Span] passed to an [Exporter].
+type SpanData struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanID
+	Kind       SpanKind
+	Start      time.Time
+	End        time.Time
+	Attributes []slog.Attr
+	Error      error
+}
+
+// Context returns the identifiers of the span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context // immutable
+}
+
+// SetName replaces the name given at the start, for example,
+// when the route pattern becomes known after routing.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttributes adds key-value pairs to the span.
+func (s *Span) SetAttributes(attrs ...slog.Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attributes = append(s.attributes, attrs...)
+	s.mu.Unlock()
+}
+
+// End records the span completion with an optional error.
+// Only the first call takes effect.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	data := SpanData{
+		Name:       s.name,
+		Context:    s.context,
+		Parent:     s.parent,
+		Kind:       s.kind,
+		Start:      s.start,
+		End:        s.end,
+		Attributes: s.attributes,
+		Error:      err,
+	}
+	s.mu.Unlock()
+	if s.tracer != nil && s.context.IsSampled() {
+		s.tracer.record(data)
+	}
+}
+
+// ContextWithSpanContext sets a remote parent for spans started
+// from the returned context.
+func ContextWithSpanContext(parent context.Context, c SpanContext) context.Context {
+	return context.WithValue(parent, contextKey{}, &Span{context: c})
+}
+
+// SpanFromContext returns the current span or <nil>.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(contextKey{}).(*Span)
+	return s
+}
+
+// SpanContextFromContext returns the identifiers of the current span.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	return SpanFromContext(ctx).Context()
+}
+
+// FromContext returns the trace identifier of the current span
+// or an empty string.
+func FromContext(ctx context.Context) string {
+	c := SpanContextFromContext(ctx)
+	if !c.TraceID.IsValid() {
+		return ""
+	}
+	return c.TraceID.String()
+}
@@ -1,10 +1,204 @@
 /*
-Package traceid provides [htadaptor.Middleware] that injects
-trace identifiers into request [context.Context] and a matching
-[slog.Handler] for populating the log records with the identifier.
+Package traceid provides [htadaptor.Middleware] that propagates
+W3C Trace Context through request [context.Context], records spans
+around request handling and adaptor stages, and a matching
+[slog.Handler] for populating the log records with the identifiers.
+
+	exporter, err := otlp.New()
+	if err != nil {
+		return err
+	}
+	tracer, err := traceid.New(traceid.WithExporter(exporter))
+	if err != nil {
+		return err
+	}
+	adaptor := htadaptor.New(htadaptor.WithInstrument(tracer))
+	handler := tracer.Middleware()(mux)
+	defer tracer.Shutdown(context.Background())
+
+The trace identifier returned by [FromContext] is the only one
+read by the service and session packages, so that logs, sessions,
+and exported spans refer to the same trace. Without the middleware,
+requests carry no trace identifier.
+
+[htadaptor.Middleware]: https://pkg.go.dev/github.com/dkotik/htadaptor#Middleware
 */
 package traceid
 
-// https://lukas.zapletalovi.com/posts/2023/about-structured-logging-in-go121/?utm_source=pocket_saves
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// Header names defined by the W3C Trace Context recommendation.
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// FlagSampled marks traces that are recorded by the caller.
+const FlagSampled byte = 0x01
+
+const (
+	traceParentVersion = "00"
+	traceParentLength  = 55
+	maxTraceStateSize  = 512
+	maxTraceStateItems = 32
+)
+
+// ID identifies a trace.
+type ID [16]byte
+
+// NewID returns a random trace identifier.
+func NewID() (id ID) {
+	for !id.IsValid() {
+		rest := rand.Uint64()
+		for i := range 8 {
+			id[i] = byte(rest >> (8 * i))
+		}
+		rest = rand.Uint64()
+		for i := range 8 {
+			id[8+i] = byte(rest >> (8 * i))
+		}
+	}
+	return id
+}
+
+// IsValid returns false for the all-zero identifier.
+func (id ID) IsValid() bool {
+	return id != ID{}
+}
+
+// String returns 32 lowercase hexadecimal characters.
+func (id ID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// NewSpanID returns a random span identifier.
+func NewSpanID() (id SpanID) {
+	for !id.IsValid() {
+		rest := rand.Uint64()
+		for i := range 8 {
+			id[i] = byte(rest >> (8 * i))
+		}
+	}
+	return id
+}
+
+// IsValid returns false for the all-zero identifier.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// String returns 16 lowercase hexadecimal characters.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext is the part of a span that crosses process boundaries.
+type SpanContext struct {
+	TraceID    ID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	Remote     bool
+}
+
+// IsValid returns true when both identifiers are set.
+func (c SpanContext) IsValid() bool {
+	return c.TraceID.IsValid() && c.SpanID.IsValid()
+}
+
+// IsSampled returns true when the span should be exported.
+func (c SpanContext) IsSampled() bool {
+	return c.Flags&FlagSampled != 0
+}
+
+// TraceParent formats the context as a traceparent header value.
+func (c SpanContext) TraceParent() string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceParentVersion, c.TraceID, c.SpanID, c.Flags)
+}
+
+func decodeHex(dst []byte, s string) error {
+	if strings.ToLower(s) != s {
+		return errors.New("identifiers must be lowercase")
+	}
+	if _, err := hex.Decode(dst, []byte(s)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseTraceParent reads a traceparent header value. Values
+// of future versions are parsed by their known prefix,
+// as the recommendation requires.
+func ParseTraceParent(value string) (c SpanContext, err error) {
+	if len(value) < traceParentLength {
+		return c, errors.New("traceparent is too short")
+	}
+	version := value[:2]
+	if version == "ff" {
+		return c, errors.New("traceparent version ff is forbidden")
+	}
+	if len(value) > traceParentLength && (version == traceParentVersion || value[traceParentLength] != '-') {
+		return c, errors.New("traceparent is too long")
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return c, errors.New("traceparent is malformed")
+	}
+	var v [1]byte
+	if err = decodeHex(v[:], version); err != nil {
+		return c, fmt.Errorf("invalid traceparent version: %w", err)
+	}
+	if err = decodeHex(c.TraceID[:], value[3:35]); err != nil {
+		return c, fmt.Errorf("invalid trace identifier: %w", err)
+	}
+	if err = decodeHex(c.SpanID[:], value[36:52]); err != nil {
+		return c, fmt.Errorf("invalid span identifier: %w", err)
+	}
+	if err = decodeHex(v[:], value[53:55]); err != nil {
+		return c, fmt.Errorf("invalid trace flags: %w", err)
+	}
+	c.Flags = v[0]
+	if !c.IsValid() {
+		return c, errors.New("traceparent identifiers cannot be all zeroes")
+	}
+	c.Remote = true
+	return c, nil
+}
 
-type contextKey struct{}
+// ParseTraceState checks the shape of a tracestate header value
+// and returns it without optional white space. Vendor entries
+// are passed through without interpretation.
+func ParseTraceState(value string) (string, error) {
+	if len(value) > maxTraceStateSize {
+		return "", fmt.Errorf("tracestate is longer than %d bytes", maxTraceStateSize)
+	}
+	members := make([]string, 0, 4)
+	seen := make(map[string]struct{})
+	for member := range strings.SplitSeq(value, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(member, "=")
+		if !ok || key == "" || val == "" {
+			return "", fmt.Errorf("tracestate member %q is malformed", member)
+		}
+		if _, ok = seen[key]; ok {
+			return "", fmt.Errorf("tracestate key %q is repeated", key)
+		}
+		seen[key] = struct{}{}
+		members = append(members, member)
+	}
+	if len(members) > maxTraceStateItems {
+		return "", fmt.Errorf("tracestate has more than %d members", maxTraceStateItems)
+	}
+	return strings.Join(members, ","), nil
+}
@@ -0,0 +1,92 @@
+package traceid
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	c, err := ParseTraceParent(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatal("trace identifier does not match:", c.TraceID)
+	}
+	if c.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatal("span identifier does not match:", c.SpanID)
+	}
+	if !c.IsSampled() || !c.Remote {
+		t.Fatalf("unexpected span context: %+v", c)
+	}
+	if c.TraceParent() != valid {
+		t.Fatal("formatted traceparent does not match:", c.TraceParent())
+	}
+	if _, err = ParseTraceParent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future"); err != nil {
+		t.Fatal("future version was not parsed:", err)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		if _, err = ParseTraceParent(invalid); err == nil {
+			t.Fatalf("traceparent %q was accepted", invalid)
+		}
+	}
+}
+
+func TestParseTraceState(t *testing.T) {
+	state, err := ParseTraceState(" rojo=00f067aa0ba902b7 ,, congo=t61rcWkgMzE ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE" {
+		t.Fatal("unexpected trace state:", state)
+	}
+	for _, invalid := range []string{"rojo", "rojo=1,rojo=2", "=1"} {
+		if _, err = ParseTraceState(invalid); err == nil {
+			t.Fatalf("tracestate %q was accepted", invalid)
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	incoming := http.Header{}
+	incoming.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	incoming.Set(TraceStateHeader, "rojo=00f067aa0ba902b7")
+
+	tracer, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, span := tracer.Start(Extract(context.Background(), incoming), "test", SpanKindServer)
+	defer span.End(nil)
+	if FromContext(ctx) != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatal("trace was not continued:", FromContext(ctx))
+	}
+	if span.Context().IsSampled() {
+		t.Fatal("sampling decision of the caller was not followed")
+	}
+
+	outgoing := http.Header{}
+	Inject(ctx, outgoing)
+	propagated, err := ParseTraceParent(outgoing.Get(TraceParentHeader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if propagated.TraceID != span.Context().TraceID || propagated.SpanID != span.Context().SpanID {
+		t.Fatal("propagated traceparent does not match the span:", outgoing.Get(TraceParentHeader))
+	}
+	if outgoing.Get(TraceStateHeader) != "rojo=00f067aa0ba902b7" {
+		t.Fatal("tracestate was not propagated")
+	}
+}
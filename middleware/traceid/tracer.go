@@ -0,0 +1,370 @@
+package traceid
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Exporter sends ended spans to a tracing backend.
+type Exporter interface {
+	ExportSpans(context.Context, []SpanData) error
+}
+
+type options struct {
+	Exporter      Exporter
+	SampleRatio   float64
+	BatchSize     int
+	QueueSize     int
+	FlushInterval time.Duration
+	Logger        *slog.Logger
+}
+
+type Option func(*options) error
+
+// WithExporter sends sampled spans to an [Exporter]. Without
+// an exporter, the tracer only propagates trace context.
+func WithExporter(e Exporter) Option {
+	return func(o *options) error {
+		if e == nil {
+			return errors.New("cannot use a <nil> span exporter")
+		}
+		if o.Exporter != nil {
+			return errors.New("span exporter is already set")
+		}
+		o.Exporter = e
+		return nil
+	}
+}
+
+// WithSampleRatio records the given fraction of traces that
+// start at this service. Traces that arrive with a traceparent
+// header follow the sampling decision of the caller.
+func WithSampleRatio(ratio float64) Option {
+	return func(o *options) error {
+		if o.SampleRatio != 0 {
+			return errors.New("sample ratio is already set")
+		}
+		if ratio <= 0 || ratio > 1 || math.IsNaN(ratio) {
+			return errors.New("sample ratio must be above 0 and not greater than 1")
+		}
+		o.SampleRatio = ratio
+		return nil
+	}
+}
+
+func WithDefaultSampleRatio() Option {
+	return func(o *options) error {
+		if o.SampleRatio != 0 {
+			return nil
+		}
+		return WithSampleRatio(1)(o)
+	}
+}
+
+// WithBatchSize exports spans as soon as the given number
+// of spans has ended.
+func WithBatchSize(size int) Option {
+	return func(o *options) error {
+		if o.BatchSize != 0 {
+			return errors.New("batch size is already set")
+		}
+		if size < 1 {
+			return errors.New("batch size must be positive")
+		}
+		o.BatchSize = size
+		return nil
+	}
+}
+
+func WithDefaultBatchSize() Option {
+	return func(o *options) error {
+		if o.BatchSize != 0 {
+			return nil
+		}
+		return WithBatchSize(512)(o)
+	}
+}
+
+// WithQueueSize limits how many batches wait for the exporter.
+// Spans of batches that do not fit are dropped and reported
+// to the logger, so that a slow collector cannot exhaust memory.
+func WithQueueSize(batches int) Option {
+	return func(o *options) error {
+		if o.QueueSize != 0 {
+			return errors.New("queue size is already set")
+		}
+		if batches < 1 {
+			return errors.New("queue size must be positive")
+		}
+		o.QueueSize = batches
+		return nil
+	}
+}
+
+func WithDefaultQueueSize() Option {
+	return func(o *options) error {
+		if o.QueueSize != 0 {
+			return nil
+		}
+		return WithQueueSize(8)(o)
+	}
+}
+
+// WithFlushInterval limits how long ended spans wait
+// for a full batch.
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *options) error {
+		if o.FlushInterval != 0 {
+			return errors.New("flush interval is already set")
+		}
+		if d < time.Millisecond*10 {
+			return errors.New("cannot set flush interval lower than 10ms")
+		}
+		o.FlushInterval = d
+		return nil
+	}
+}
+
+func WithDefaultFlushInterval() Option {
+	return func(o *options) error {
+		if o.FlushInterval != 0 {
+			return nil
+		}
+		return WithFlushInterval(time.Second * 5)(o)
+	}
+}
+
+// WithLogger reports export failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) error {
+		if logger == nil {
+			return errors.New("cannot use a <nil> structured logger")
+		}
+		if o.Logger != nil {
+			return errors.New("logger is already set")
+		}
+		o.Logger = logger
+		return nil
+	}
+}
+
+func WithDefaultLogger() Option {
+	return func(o *options) error {
+		if o.Logger != nil {
+			return nil
+		}
+		return WithLogger(slog.Default())(o)
+	}
+}
+
+// Tracer starts spans and exports them in batches, one batch
+// at a time. It satisfies
+// [htadaptor.Instrument] to record a span for each adaptor stage.
+//
+// [htadaptor.Instrument]: https://pkg.go.dev/github.com/dkotik/htadaptor#Instrument
+type Tracer struct {
+	exporter      Exporter
+	threshold     uint64
+	batchSize     int
+	flushInterval time.Duration
+	logger        *slog.Logger
+
+	mu      sync.Mutex
+	pending []SpanData
+	timer   *time.Timer
+	closed  bool
+	queue   chan []SpanData
+	dropped atomic.Int64
+	done    chan struct{} // closed when the export worker stops
+}
+
+func New(withOptions ...Option) (*Tracer, error) {
+	o := &options{}
+	var err error
+	for _, option := range append(
+		withOptions,
+		WithDefaultSampleRatio(),
+		WithDefaultBatchSize(),
+		WithDefaultQueueSize(),
+		WithDefaultFlushInterval(),
+		WithDefaultLogger(),
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create tracer: %w", err)
+		}
+	}
+	threshold := uint64(math.MaxUint64)
+	if o.SampleRatio < 1 {
+		threshold = uint64(o.SampleRatio * math.MaxUint64)
+	}
+	t := &Tracer{
+		exporter:      o.Exporter,
+		threshold:     threshold,
+		batchSize:     o.BatchSize,
+		flushInterval: o.FlushInterval,
+		logger:        o.Logger,
+	}
+	if t.exporter != nil {
+		t.queue = make(chan []SpanData, o.QueueSize)
+		t.done = make(chan struct{})
+		go t.export()
+	}
+	return t, nil
+}
+
+// isSampled makes the same decision for a trace identifier
+// in every service that uses the same ratio.
+func (t *Tracer) isSampled(id ID) bool {
+	if t.threshold == math.MaxUint64 {
+		return true
+	}
+	return binary.BigEndian.Uint64(id[8:]) < t.threshold
+}
+
+// Start begins a child of the span in the context or
+// a new trace, if there is none.
+func (t *Tracer) Start(
+	ctx context.Context,
+	name string,
+	kind SpanKind,
+	attrs ...slog.Attr,
+) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	s := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: attrs,
+		context: SpanContext{
+			TraceID:    parent.TraceID,
+			SpanID:     NewSpanID(),
+			Flags:      parent.Flags,
+			TraceState: parent.TraceState,
+		},
+	}
+	if parent.IsValid() {
+		s.parent = parent.SpanID
+	} else {
+		s.context.TraceID = NewID()
+		if t.isSampled(s.context.TraceID) {
+			s.context.Flags |= FlagSampled
+		}
+	}
+	return context.WithValue(ctx, contextKey{}, s), s
+}
+
+// BeginStage satisfies [htadaptor.Instrument].
+//
+// [htadaptor.Instrument]: https://pkg.go.dev/github.com/dkotik/htadaptor#Instrument
+func (t *Tracer) BeginStage(ctx context.Context, stage string) (context.Context, func(error)) {
+	ctx, s := t.Start(ctx, stage, SpanKindInternal)
+	return ctx, s.End
+}
+
+func (t *Tracer) record(data SpanData) {
+	if t.exporter == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		t.dropped.Add(1)
+		return
+	}
+	t.pending = append(t.pending, data)
+	if len(t.pending) >= t.batchSize {
+		t.exportPending()
+		return
+	}
+	if t.timer == nil {
+		t.timer = time.AfterFunc(t.flushInterval, func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.exportPending()
+		})
+	}
+}
+
+// exportPending must be called while holding the lock.
+func (t *Tracer) exportPending() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if len(t.pending) == 0 {
+		return
+	}
+	select {
+	case t.queue <- t.pending:
+	default: // exporter is falling behind
+		t.dropped.Add(int64(len(t.pending)))
+	}
+	t.pending = nil
+}
+
+// export sends queued batches one at a time until the queue
+// is closed by [Tracer.Shutdown].
+func (t *Tracer) export() {
+	defer close(t.done)
+	for batch := range t.queue {
+		if dropped := t.dropped.Swap(0); dropped > 0 {
+			t.logger.Warn(
+				"dropped spans because the export queue is full",
+				slog.Int64("count", dropped),
+			)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), t.flushInterval*2)
+		if err := t.exporter.ExportSpans(ctx, batch); err != nil {
+			t.logger.Error(
+				"cannot export spans",
+				slog.Int("count", len(batch)),
+				slog.Any("error", err),
+			)
+		}
+		cancel()
+	}
+}
+
+// Shutdown exports pending spans and waits for exports to complete
+// or the context to be done. Spans that end afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t.exporter == nil {
+		return nil
+	}
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+	} else {
+		if t.timer != nil {
+			t.timer.Stop()
+			t.timer = nil
+		}
+		batch := t.pending
+		t.pending = nil
+		t.closed = true // nothing else sends to the queue
+		t.mu.Unlock()
+		if len(batch) > 0 {
+			select {
+			case t.queue <- batch:
+			case <-ctx.Done():
+				t.dropped.Add(int64(len(batch)))
+			}
+		}
+		close(t.queue)
+	}
+
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("cannot complete span export: %w", context.Cause(ctx))
+	}
+}
@@ -0,0 +1,194 @@
+package traceid_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dkotik/htadaptor"
+	"github.com/dkotik/htadaptor/middleware/session"
+	"github.com/dkotik/htadaptor/middleware/traceid"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []traceid.SpanData
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []traceid.SpanData) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mu.Unlock()
+	return nil
+}
+
+type testRequest struct {
+	Name string
+}
+
+func (t *testRequest) Validate(ctx context.Context) error {
+	return nil
+}
+
+func TestAdaptorSpans(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer, err := traceid.New(traceid.WithExporter(exporter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	adaptor := htadaptor.New(htadaptor.WithInstrument(tracer))
+
+	var sessionTraceID string
+	mux := http.NewServeMux()
+	mux.Handle("POST /greet", htadaptor.Must(adaptor.AdaptFunc(
+		func(ctx context.Context, r *testRequest) (string, error) {
+			if traceid.SpanFromContext(ctx).Context().SpanID == (traceid.SpanID{}) {
+				t.Error("domain call has no span")
+			}
+			sessionTraceID = session.TraceID(ctx)
+			return "hello " + r.Name, nil
+		},
+	)))
+	sessions, err := session.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sessions(tracer.Middleware()(mux))
+
+	r := httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(`{"Name":"gopher"}`))
+	r.Header.Set("content-type", "application/json")
+	r.Header.Set(traceid.TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatal("unexpected status code:", w.Code, w.Body.String())
+	}
+	if err = tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	names := make([]string, 0, len(exporter.spans))
+	var server traceid.SpanData
+	for _, span := range exporter.spans {
+		names = append(names, span.Name)
+		if span.Context.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Fatalf("span %q belongs to another trace", span.Name)
+		}
+		if span.Kind == traceid.SpanKindServer {
+			server = span
+		}
+	}
+	slices.Sort(names)
+	expected := []string{"POST /greet", "decode", "domain", "encode", "validate"}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("span names %v do not match %v", names, expected)
+	}
+	if server.Parent.String() != "00f067aa0ba902b7" {
+		t.Fatal("server span is not a child of the remote parent")
+	}
+	for _, span := range exporter.spans {
+		if span.Kind == traceid.SpanKindInternal && span.Parent != server.Context.SpanID {
+			t.Fatalf("stage span %q is not a child of the server span", span.Name)
+		}
+	}
+	if sessionTraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatal("session trace identifier does not match the trace:", sessionTraceID)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(traceid.TraceParentHeader)
+	}))
+	defer upstream.Close()
+
+	tracer, err := traceid.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, span := tracer.Start(context.Background(), "parent", traceid.SpanKindInternal)
+	defer span.End(nil)
+	client := &http.Client{Transport: tracer.NewTransport(nil)}
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+
+	propagated, err := traceid.ParseTraceParent(received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if propagated.TraceID != span.Context().TraceID {
+		t.Fatal("trace was not propagated:", received)
+	}
+	if propagated.SpanID == span.Context().SpanID {
+		t.Fatal("client span was not started")
+	}
+	if r.Header.Get(traceid.TraceParentHeader) != "" {
+		t.Fatal("transport modified the original request")
+	}
+}
+
+// slowExporter blocks until released, like an unreachable collector.
+type slowExporter struct {
+	release chan struct{}
+	active  atomic.Int32
+	peak    atomic.Int32
+	spans   atomic.Int32
+}
+
+func (e *slowExporter) ExportSpans(ctx context.Context, spans []traceid.SpanData) error {
+	active := e.active.Add(1)
+	defer e.active.Add(-1)
+	if active > e.peak.Load() {
+		e.peak.Store(active)
+	}
+	<-e.release
+	e.spans.Add(int32(len(spans)))
+	return nil
+}
+
+func TestSlowExporter(t *testing.T) {
+	exporter := &slowExporter{release: make(chan struct{})}
+	logs := &strings.Builder{}
+	tracer, err := traceid.New(
+		traceid.WithExporter(exporter),
+		traceid.WithBatchSize(1),
+		traceid.WithQueueSize(2),
+		traceid.WithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 100 {
+		_, span := tracer.Start(context.Background(), "request", traceid.SpanKindServer)
+		span.End(nil)
+	}
+	close(exporter.release)
+	if err = tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if peak := exporter.peak.Load(); peak != 1 {
+		t.Fatalf("%d exports ran concurrently", peak)
+	}
+	if exported := exporter.spans.Load(); exported < 1 || exported > 3 {
+		t.Fatalf("exported %d spans beyond the queue size", exported)
+	}
+	if !strings.Contains(logs.String(), "dropped spans") {
+		t.Fatal("dropped spans were not reported:", logs.String())
+	}
+}
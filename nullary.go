@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+
+	"github.com/dkotik/htadaptor/middleware/authorize"
 )
 
 // AdaptNullaryFunc creates a new adaptor for a
@@ -20,34 +22,45 @@ func (a Adaptor) AdaptNullaryFunc[O any](
 		return nil, err
 	}
 	return &NullaryFuncAdaptor[O]{
-		domainCall:   domainCall,
-		statusCode:   o.StatusCode,
-		encoder:      o.Encoder,
-		errorHandler: o.ErrorHandler,
+		domainCall:    domainCall,
+		statusCode:    o.StatusCode,
+		encoder:       o.Encoder,
+		errorHandler:  o.ErrorHandler,
+		authorization: o.Authorization,
+		instrument:    o.Instrument,
 	}, nil
 }
 
 // NullaryFuncAdaptor calls a domain function with no input
 // and returns a response struct.
 type NullaryFuncAdaptor[O any] struct {
-	domainCall   func(context.Context) (O, error)
-	statusCode   int
-	encoder      Encoder
-	errorHandler ErrorHandler
+	domainCall    func(context.Context) (O, error)
+	statusCode    int
+	encoder       Encoder
+	errorHandler  ErrorHandler
+	authorization authorize.Policy
+	instrument    Instrument
 }
 
 func (a *NullaryFuncAdaptor[O]) executeDomainCall(
 	w http.ResponseWriter,
 	r *http.Request,
 ) (err error) {
-	response, err := a.domainCall(r.Context())
-	if err != nil {
+	ctx := r.Context()
+	if err = authorizeRequest(ctx, a.instrument, a.authorization, nil); err != nil {
+		return err
+	}
+	domainCtx, end := beginStage(ctx, a.instrument, StageDomainCall)
+	response, err := a.domainCall(domainCtx)
+	if end(err); err != nil {
 		return err
 	}
+	_, end = beginStage(ctx, a.instrument, StageEncode)
 	if err = a.encoder.Encode(w, r, a.statusCode, response); err != nil {
-		return NewEncodingError(err)
+		err = NewEncodingError(err)
 	}
-	return nil
+	end(err)
+	return err
 }
 
 // ServeHTTP satisfies [http.Handler] interface.
@@ -1,6 +1,7 @@
 package htadaptor
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
@@ -10,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/dkotik/htadaptor/extract"
+	"github.com/dkotik/htadaptor/middleware/authorize"
 	"github.com/dkotik/htadaptor/reflectd"
 )
 
@@ -19,6 +21,8 @@ type options struct {
 	Encoder        Encoder
 	StatusCode     int
 	ErrorHandler   ErrorHandler
+	Authorization  authorize.Policy
+	Instrument     Instrument
 }
 
 type Option func(*options) error
@@ -146,6 +150,39 @@ func WithDefaultErrorHandler() Option {
 	}
 }
 
+// WithAuthorization guards the domain call with an [authorize.Policy].
+// The policy is evaluated after the request is decoded and validated,
+// so that its rules can inspect request struct fields. Denials are
+// reported with [http.StatusUnauthorized] or [http.StatusForbidden].
+//
+// Several policies are combined using [authorize.AllOf], which lets
+// [New] set a baseline policy that individual handlers tighten.
+func WithAuthorization(p authorize.Policy) Option {
+	return func(o *options) error {
+		if p == nil {
+			return errors.New("cannot use a <nil> authorization policy")
+		}
+		if o.Authorization != nil {
+			o.Authorization = authorize.AllOf(o.Authorization, p)
+			return nil
+		}
+		o.Authorization = p
+		return nil
+	}
+}
+
+// authorizeRequest evaluates the policy, if one is set,
+// against the subject recovered from request context.
+func authorizeRequest(ctx context.Context, i Instrument, p authorize.Policy, request any) (err error) {
+	if p == nil {
+		return nil
+	}
+	ctx, end := beginStage(ctx, i, StageAuthorize)
+	err = p.Authorize(ctx, authorize.SubjectFromContext(ctx), request)
+	end(err)
+	return err
+}
+
 func WithDecoder(d Decoder) Option {
 	return func(o *options) error {
 		if d == nil {
@@ -236,3 +273,19 @@ func WithSessionValues(names ...string) Option {
 		return nil
 	}
 }
+
+// WithClaimsValues is a convenience option that adds [reflectd.WithClaimsValues] to the decoder options.
+func WithClaimsValues(names ...string) Option {
+	return func(o *options) error {
+		o.DecoderOptions = append(o.DecoderOptions, reflectd.WithClaimsValues(names...))
+		return nil
+	}
+}
+
+// WithTenantValue is a convenience option that adds [reflectd.WithTenantValue] to the decoder options.
+func WithTenantValue(name string) Option {
+	return func(o *options) error {
+		o.DecoderOptions = append(o.DecoderOptions, reflectd.WithTenantValue(name))
+		return nil
+	}
+}
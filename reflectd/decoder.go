@@ -42,7 +42,7 @@ func NewDecoder(withOptions ...Option) (_ *Decoder, err error) {
 				}
 			}
 			if !extract.AreSessionExtractorsLast(o.Extractors...) {
-				return errors.New("security failure: all session value extractors must be at the end of the list to prevent other kinds of extractors from overriding their trusted values even when nested")
+				return errors.New("security failure: all session, claims, and tenant value extractors must be at the end of the list to prevent other kinds of extractors from overriding their trusted values even when nested")
 			}
 			return nil
 		},
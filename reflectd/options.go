@@ -151,3 +151,25 @@ func WithSessionValues(keys ...string) Option {
 		return WithExtractors(ex)(o)
 	}
 }
+
+// WithClaimsValues adds a [extract.NewClaimsValueExtractor] to a [Decoder].
+func WithClaimsValues(keys ...string) Option {
+	return func(o *options) error {
+		ex, err := extract.NewClaimsValueExtractor(keys...)
+		if err != nil {
+			return fmt.Errorf("failed to initialize claims value extractor: %w", err)
+		}
+		return WithExtractors(ex)(o)
+	}
+}
+
+// WithTenantValue adds a [extract.NewTenantValueExtractor] to a [Decoder].
+func WithTenantValue(name string) Option {
+	return func(o *options) error {
+		ex, err := extract.NewTenantValueExtractor(name)
+		if err != nil {
+			return fmt.Errorf("failed to initialize tenant value extractor: %w", err)
+		}
+		return WithExtractors(ex)(o)
+	}
+}
@@ -0,0 +1,414 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// acmeStandIn is a minimal RFC 8555 certificate authority, like Pebble,
+// that issues a certificate for a single domain after validating the
+// HTTP-01 challenge against the given address. It trusts request
+// signatures, which are not the subject of these tests.
+type acmeStandIn struct {
+	*httptest.Server
+	t         *testing.T
+	validator string // address that answers HTTP-01 challenges
+
+	authority    *x509.Certificate
+	authorityKey *ecdsa.PrivateKey
+
+	mu         sync.Mutex
+	nonce      int
+	thumbprint string
+	domain     string
+	token      string
+	validated  bool
+	status     string // of the order
+	chain      []byte
+}
+
+func newACMEStandIn(t *testing.T, validator string) *acmeStandIn {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ACME Stand-In Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ACME Stand-In Root"},
+	}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authority, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &acmeStandIn{
+		t:            t,
+		validator:    validator,
+		authority:    authority,
+		authorityKey: key,
+		token:        "challenge-token",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /directory", a.directory)
+	mux.HandleFunc("HEAD /new-nonce", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /new-account", a.newAccount)
+	mux.HandleFunc("POST /new-order", a.newOrder)
+	mux.HandleFunc("POST /order", a.order)
+	mux.HandleFunc("POST /authz", a.authorization)
+	mux.HandleFunc("POST /challenge", a.challenge)
+	mux.HandleFunc("POST /finalize", a.finalize)
+	mux.HandleFunc("POST /certificate", a.certificate)
+	a.Server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			a.mu.Lock()
+			a.nonce++
+			w.Header().Set("Replay-Nonce", "nonce-"+strconv.Itoa(a.nonce))
+			a.mu.Unlock()
+			mux.ServeHTTP(w, r)
+		},
+	))
+	t.Cleanup(a.Close)
+	return a
+}
+
+// payload decodes a JSON Web Signature request body
+// and returns the protected header.
+func (a *acmeStandIn) payload(r *http.Request, v any) (protected struct {
+	JWK json.RawMessage `json:"jwk"`
+}) {
+	var jws struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		a.t.Error("cannot decode JWS:", err)
+		return
+	}
+	header, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if err != nil || json.Unmarshal(header, &protected) != nil {
+		a.t.Error("cannot decode protected JWS header")
+	}
+	if v == nil || jws.Payload == "" {
+		return // POST-as-GET
+	}
+	b, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err != nil || json.Unmarshal(b, v) != nil {
+		a.t.Error("cannot decode JWS payload")
+	}
+	return
+}
+
+func (a *acmeStandIn) respond(w http.ResponseWriter, code int, location string, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if location != "" {
+		w.Header().Set("Location", a.URL+location)
+	}
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (a *acmeStandIn) directory(w http.ResponseWriter, r *http.Request) {
+	a.respond(w, http.StatusOK, "", map[string]string{
+		"newNonce":   a.URL + "/new-nonce",
+		"newAccount": a.URL + "/new-account",
+		"newOrder":   a.URL + "/new-order",
+		"revokeCert": a.URL + "/revoke-cert",
+		"keyChange":  a.URL + "/key-change",
+	})
+}
+
+func (a *acmeStandIn) newAccount(w http.ResponseWriter, r *http.Request) {
+	protected := a.payload(r, nil)
+	var key struct {
+		Curve string `json:"crv"`
+		Type  string `json:"kty"`
+		X     string `json:"x"`
+		Y     string `json:"y"`
+	}
+	if err := json.Unmarshal(protected.JWK, &key); err != nil {
+		a.t.Error("cannot decode account key:", err)
+	}
+	// RFC 7638 thumbprint of the account key
+	hash := sha256.Sum256(fmt.Appendf(nil,
+		`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, key.Curve, key.Type, key.X, key.Y))
+	a.mu.Lock()
+	a.thumbprint = base64.RawURLEncoding.EncodeToString(hash[:])
+	a.mu.Unlock()
+	a.respond(w, http.StatusCreated, "/account", map[string]string{"status": "valid"})
+}
+
+func (a *acmeStandIn) orderResponse() map[string]any {
+	order := map[string]any{
+		"status":         a.status,
+		"identifiers":    []map[string]string{{"type": "dns", "value": a.domain}},
+		"authorizations": []string{a.URL + "/authz"},
+		"finalize":       a.URL + "/finalize",
+	}
+	if a.status == "valid" {
+		order["certificate"] = a.URL + "/certificate"
+	}
+	return order
+}
+
+func (a *acmeStandIn) newOrder(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Identifiers []struct {
+			Value string `json:"value"`
+		} `json:"identifiers"`
+	}
+	a.payload(r, &request)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(request.Identifiers) != 1 {
+		a.t.Error("expected a single identifier")
+		return
+	}
+	a.domain = request.Identifiers[0].Value
+	a.status = "pending"
+	a.respond(w, http.StatusCreated, "/order", a.orderResponse())
+}
+
+func (a *acmeStandIn) order(w http.ResponseWriter, r *http.Request) {
+	a.payload(r, nil)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.respond(w, http.StatusOK, "/order", a.orderResponse())
+}
+
+func (a *acmeStandIn) challengeResponse() map[string]string {
+	status := "pending"
+	if a.validated {
+		status = "valid"
+	}
+	return map[string]string{
+		"type":   "http-01",
+		"url":    a.URL + "/challenge",
+		"token":  a.token,
+		"status": status,
+	}
+}
+
+func (a *acmeStandIn) authorization(w http.ResponseWriter, r *http.Request) {
+	a.payload(r, nil)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	challenge := a.challengeResponse()
+	a.respond(w, http.StatusOK, "", map[string]any{
+		"status":     challenge["status"],
+		"identifier": map[string]string{"type": "dns", "value": a.domain},
+		"challenges": []map[string]string{challenge},
+	})
+}
+
+// challenge validates the key authorization served over plain HTTP,
+// just like a certificate authority would on port 80.
+func (a *acmeStandIn) challenge(w http.ResponseWriter, r *http.Request) {
+	a.payload(r, nil)
+	a.mu.Lock()
+	domain, token, thumbprint := a.domain, a.token, a.thumbprint
+	a.mu.Unlock()
+
+	request, err := http.NewRequestWithContext(r.Context(), http.MethodGet,
+		"http://"+a.validator+"/.well-known/acme-challenge/"+token, nil)
+	if err != nil {
+		a.t.Error(err)
+		return
+	}
+	request.Host = domain
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		a.t.Error("cannot fetch HTTP-01 challenge:", err)
+		return
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		a.t.Error(err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if response.StatusCode == http.StatusOK && string(body) == token+"."+thumbprint {
+		a.validated = true
+		a.status = "ready"
+	} else {
+		a.t.Errorf("invalid HTTP-01 response %d: %q", response.StatusCode, body)
+	}
+	a.respond(w, http.StatusOK, "", a.challengeResponse())
+}
+
+func (a *acmeStandIn) finalize(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		CSR string `json:"csr"`
+	}
+	a.payload(r, &request)
+	der, err := base64.RawURLEncoding.DecodeString(request.CSR)
+	if err != nil {
+		a.t.Error(err)
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err == nil {
+		err = csr.CheckSignature()
+	}
+	if err != nil {
+		a.t.Error("invalid certificate signing request:", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.status != "ready" || !slices.Equal(csr.DNSNames, []string{a.domain}) {
+		a.t.Errorf("cannot finalize %q order for %v", a.status, csr.DNSNames)
+		return
+	}
+	leaf, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: a.domain},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, a.authority, csr.PublicKey, a.authorityKey)
+	if err != nil {
+		a.t.Error(err)
+		return
+	}
+	a.chain = append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: a.authority.Raw})...,
+	)
+	a.status = "valid"
+	a.respond(w, http.StatusOK, "/order", a.orderResponse())
+}
+
+func (a *acmeStandIn) certificate(w http.ResponseWriter, r *http.Request) {
+	a.payload(r, nil)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	_, _ = w.Write(a.chain)
+}
+
+func TestACMEHTTPChallenge(t *testing.T) {
+	const domain = "service.test"
+	main, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirect, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authority := newACMEStandIn(t, redirect.Addr().String())
+	manager, err := NewACMEManager(
+		WithACMEHosts(domain),
+		WithACMEDirectoryURL(authority.URL+"/directory"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readiness := &Readiness{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Run(
+			ctx,
+			WithListener(main),
+			func(o *options) error { // same as WithHTTPSRedirect
+				o.Listeners = append(o.Listeners, &listener{Listener: redirect, Redirect: true})
+				return nil
+			},
+			WithACME(manager),
+			WithReadiness(readiness),
+			WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "secure")
+			})),
+		)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	for deadline := time.Now().Add(time.Second); !readiness.IsReady(); {
+		if time.Now().After(deadline) {
+			t.Fatal("service did not become ready")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(authority.authority)
+	client := &http.Client{
+		Timeout: time.Second * 10,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{ServerName: domain, RootCAs: roots},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	response, err := client.Get("https://" + main.Addr().String() + "/")
+	if err != nil {
+		t.Fatal("certificate was not issued:", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if string(body) != "secure" {
+		t.Fatalf("unexpected response %q", body)
+	}
+	if !authority.validated {
+		t.Fatal("HTTP-01 challenge was not validated")
+	}
+
+	// other requests to the redirect listener are still redirected
+	request, err := http.NewRequest(http.MethodGet, "http://"+redirect.Addr().String()+"/path", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Host = domain
+	response, err = client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+	_, port, _ := net.SplitHostPort(main.Addr().String())
+	if location := response.Header.Get("Location"); location != "https://"+domain+":"+port+"/path" {
+		t.Fatalf("unexpected redirect location %q", location)
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCertificateReloadInterval is how often [Run] checks
+// certificate files for changes.
+const DefaultCertificateReloadInterval = time.Second * 30
+
+type certificateFiles struct {
+	Certificate string
+	Key         string
+	Modified    time.Time
+}
+
+// CertificateManager serves certificates loaded from files and
+// selects among them using Server Name Indication. Changed files
+// are reloaded atomically, so that renewed certificates take
+// effect without a restart.
+type CertificateManager struct {
+	mu           sync.Mutex
+	files        []certificateFiles
+	certificates atomic.Pointer[[]*tls.Certificate]
+}
+
+// NewCertificateManager loads a certificate for each pair
+// of certificate and key file paths.
+func NewCertificateManager(certificateAndKeyFiles ...string) (*CertificateManager, error) {
+	if len(certificateAndKeyFiles) == 0 || len(certificateAndKeyFiles)%2 != 0 {
+		return nil, errors.New("certificate manager requires pairs of certificate and key files")
+	}
+	m := &CertificateManager{}
+	for i := 0; i < len(certificateAndKeyFiles); i += 2 {
+		if err := m.Add(certificateAndKeyFiles[i], certificateAndKeyFiles[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Add loads a certificate and starts tracking its files.
+func (m *CertificateManager) Add(certificateFile, keyFile string) error {
+	if certificateFile == "" {
+		return errors.New("certificate file path is required")
+	}
+	if keyFile == "" {
+		return errors.New("key file path is required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := append(slices.Clone(m.files), certificateFiles{
+		Certificate: certificateFile,
+		Key:         keyFile,
+	})
+	if err := m.load(files); err != nil {
+		return err
+	}
+	m.files = files
+	return nil
+}
+
+func (m *CertificateManager) load(files []certificateFiles) error {
+	certificates := make([]*tls.Certificate, len(files))
+	for i, pair := range files {
+		modified, err := lastModified(pair.Certificate, pair.Key)
+		if err != nil {
+			return err
+		}
+		certificate, err := tls.LoadX509KeyPair(pair.Certificate, pair.Key)
+		if err != nil {
+			return fmt.Errorf("cannot load certificate %q: %w", pair.Certificate, err)
+		}
+		certificates[i] = &certificate
+		files[i].Modified = modified
+	}
+	m.certificates.Store(&certificates)
+	return nil
+}
+
+func lastModified(paths ...string) (latest time.Time, err error) {
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return latest, err
+		}
+		if modified := info.ModTime(); modified.After(latest) {
+			latest = modified
+		}
+	}
+	return latest, nil
+}
+
+// Reload loads all certificates again if any of their files changed.
+// Previous certificates remain in use if any of the files
+// cannot be loaded, which protects against partially written
+// renewals. Returns true if the certificates were replaced.
+func (m *CertificateManager) Reload() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changed := false
+	for _, pair := range m.files {
+		modified, err := lastModified(pair.Certificate, pair.Key)
+		if err != nil {
+			return false, err
+		}
+		if !modified.Equal(pair.Modified) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	files := make([]certificateFiles, len(m.files))
+	copy(files, m.files)
+	if err := m.load(files); err != nil {
+		return false, err
+	}
+	m.files = files
+	return true, nil
+}
+
+// Watch calls [CertificateManager.Reload] at every interval until
+// the context is cancelled.
+func (m *CertificateManager) Watch(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := m.Reload()
+			if err != nil {
+				logger.ErrorContext(ctx, "cannot reload TLS certificates", slog.Any("error", err))
+			} else if reloaded {
+				logger.InfoContext(ctx, "reloaded TLS certificates")
+			}
+		}
+	}
+}
+
+// GetCertificate returns the first certificate that is compatible
+// with the client hello, or the first certificate when none are.
+// Satisfies [tls.Config.GetCertificate] signature.
+func (m *CertificateManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certificates := *m.certificates.Load()
+	for _, certificate := range certificates {
+		if hello.SupportsCertificate(certificate) == nil {
+			return certificate, nil
+		}
+	}
+	return certificates[0], nil
+}
+
+// TLSConfig returns a [tls.Config] that uses the manager
+// to pick certificates.
+func (m *CertificateManager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: m.GetCertificate,
+	}
+}
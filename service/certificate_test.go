@@ -0,0 +1,100 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCertificate(t *testing.T, dir, host string, modified time.Time) (string, string) {
+	t.Helper()
+	certificate, err := NewSelfSignedCertificate(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificateFile := filepath.Join(dir, host+".crt")
+	keyFile := filepath.Join(dir, host+".key")
+	for p, block := range map[string]*pem.Block{
+		certificateFile: {Type: "CERTIFICATE", Bytes: certificate.Certificate[0]},
+		keyFile:         {Type: "PRIVATE KEY", Bytes: key},
+	} {
+		if err = os.WriteFile(p, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chtimes(p, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certificateFile, keyFile
+}
+
+func TestCertificateManager(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	firstCertificate, firstKey := writeTestCertificate(t, dir, "first.test", start)
+	secondCertificate, secondKey := writeTestCertificate(t, dir, "second.test", start)
+
+	m, err := NewCertificateManager(firstCertificate, firstKey, secondCertificate, secondKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(host string) *x509.Certificate {
+		t.Helper()
+		certificate, err := m.GetCertificate(&tls.ClientHelloInfo{
+			ServerName:        host,
+			SupportedVersions: []uint16{tls.VersionTLS13},
+			SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return certificate.Leaf
+	}
+	if name := serve("second.test").Subject.CommonName; name != "second.test" {
+		t.Fatalf("server name indication selected %q", name)
+	}
+	if name := serve("unknown.test").Subject.CommonName; name != "first.test" {
+		t.Fatalf("unknown host name did not fall back to the first certificate: %q", name)
+	}
+
+	reloaded, err := m.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded {
+		t.Fatal("reloaded unchanged certificates")
+	}
+
+	previous := serve("first.test").SerialNumber
+	writeTestCertificate(t, dir, "first.test", start.Add(time.Minute))
+	if reloaded, err = m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded {
+		t.Fatal("changed certificate was not reloaded")
+	}
+	renewed := serve("first.test").SerialNumber
+	if renewed.Cmp(previous) == 0 {
+		t.Fatal("renewed certificate is not served")
+	}
+
+	// a partially written renewal must not replace working certificates
+	if err = os.WriteFile(firstCertificate, []byte("partial"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Reload(); err == nil {
+		t.Fatal("loaded a broken certificate")
+	}
+	if serve("first.test").SerialNumber.Cmp(renewed) != 0 {
+		t.Fatal("broken certificate replaced a working one")
+	}
+}
@@ -0,0 +1,531 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Redacted replaces values of fields tagged as `secret:"true"`
+// when the effective configuration is logged.
+const Redacted = "REDACTED"
+
+type configOptions struct {
+	File              string
+	EnvironmentPrefix string
+	FlagSet           *flag.FlagSet
+	Arguments         []string
+	Logger            *slog.Logger
+}
+
+type ConfigOption func(*configOptions) error
+
+// WithConfigFile reads configuration from a TOML, JSON, or YAML
+// file chosen by the file extension. Unknown keys are rejected
+// to catch misspelled settings.
+func WithConfigFile(p string) ConfigOption {
+	return func(o *configOptions) error {
+		if o.File != "" {
+			return errors.New("configuration file is already set")
+		}
+		if p == "" {
+			return errors.New("cannot use an empty configuration file path")
+		}
+		o.File = p
+		return nil
+	}
+}
+
+// WithEnvironment reads configuration from environment variables
+// named by the prefix and the upper-cased field path joined with
+// underscores, such as SERVICE_READ_TIMEOUT. Environment variables
+// take precedence over the configuration file.
+func WithEnvironment(prefix string) ConfigOption {
+	return func(o *configOptions) error {
+		if o.EnvironmentPrefix != "" {
+			return errors.New("environment prefix is already set")
+		}
+		if prefix == "" {
+			return errors.New("cannot use an empty environment prefix")
+		}
+		o.EnvironmentPrefix = strings.ToUpper(prefix)
+		return nil
+	}
+}
+
+// WithFlags registers a flag for each field, named by the field path
+// joined with dashes, such as -read-timeout, and parses the arguments.
+// Flags take precedence over environment variables.
+func WithFlags(fs *flag.FlagSet, arguments []string) ConfigOption {
+	return func(o *configOptions) error {
+		if fs == nil {
+			return errors.New("cannot use a <nil> flag set")
+		}
+		if o.FlagSet != nil {
+			return errors.New("flag set is already set")
+		}
+		if fs.Parsed() {
+			return errors.New("flag set is already parsed")
+		}
+		o.FlagSet = fs
+		o.Arguments = arguments
+		return nil
+	}
+}
+
+// WithCommandLine registers flags with [flag.CommandLine] and parses
+// process arguments.
+func WithCommandLine() ConfigOption {
+	return WithFlags(flag.CommandLine, os.Args[1:])
+}
+
+// WithConfigLogger prints the effective configuration
+// at debug level with secrets redacted.
+func WithConfigLogger(logger *slog.Logger) ConfigOption {
+	return func(o *configOptions) error {
+		if logger == nil {
+			return errors.New("cannot use a <nil> structured logger")
+		}
+		if o.Logger != nil {
+			return errors.New("configuration logger is already set")
+		}
+		o.Logger = logger
+		return nil
+	}
+}
+
+// configField is a settable leaf of the configuration structure.
+type configField struct {
+	path   []string
+	value  reflect.Value
+	usage  string
+	secret bool
+}
+
+func (f configField) name() string {
+	return strings.Join(f.path, ".")
+}
+
+func (f configField) flagName() string {
+	return strings.ReplaceAll(strings.Join(f.path, "-"), "_", "-")
+}
+
+func (f configField) environmentName(prefix string) string {
+	return prefix + strings.ToUpper(strings.Join(f.path, "_"))
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// snakeCase converts field names, keeping acronyms together:
+// HTTPSRedirectPort becomes https_redirect_port.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	b := &strings.Builder{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) ||
+				(unicode.IsUpper(previous) && nextIsLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func collectConfigFields(v reflect.Value, prefix []string) (fields []configField, err error) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, ok := field.Tag.Lookup("config")
+		if name == "-" {
+			continue
+		}
+		if !ok || name == "" {
+			name = snakeCase(field.Name)
+		}
+		value := v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			path := prefix
+			if !field.Anonymous {
+				path = append(prefix[:len(prefix):len(prefix)], name)
+			}
+			nested, err := collectConfigFields(value, path)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		case reflect.Slice:
+			if field.Type.Elem().Kind() != reflect.String {
+				return nil, fmt.Errorf("configuration field %q must be a slice of strings", field.Name)
+			}
+		default:
+			return nil, fmt.Errorf("configuration field %q has unsupported type %s", field.Name, field.Type)
+		}
+		fields = append(fields, configField{
+			path:   append(prefix[:len(prefix):len(prefix)], name),
+			value:  value,
+			usage:  field.Tag.Get("usage"),
+			secret: field.Tag.Get("secret") == "true",
+		})
+	}
+	return fields, nil
+}
+
+// set parses a textual value from any configuration source.
+func (f configField) set(s string) (err error) {
+	v := f.value
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Kind() == reflect.Slice:
+		items := make([]string, 0, 4)
+		for item := range strings.SplitSeq(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	}
+	return nil
+}
+
+func (f configField) String() string {
+	if f.value.Kind() == reflect.Slice {
+		return strings.Join(f.value.Convert(reflect.TypeFor[[]string]()).Interface().([]string), ",")
+	}
+	if f.value.Type() == durationType {
+		return time.Duration(f.value.Int()).String()
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+// configFlag defers parsing until all sources are known,
+// so that flags are applied last.
+type configFlag struct {
+	value  string
+	isBool bool
+}
+
+func (f *configFlag) String() string   { return f.value }
+func (f *configFlag) IsBoolFlag() bool { return f.isBool }
+func (f *configFlag) Set(s string) error {
+	f.value = s
+	return nil
+}
+
+func decodeConfigFile(p string) (values map[string]any, err error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".toml":
+		err = toml.Unmarshal(b, &values)
+	case ".json":
+		err = json.Unmarshal(b, &values)
+	case ".yaml", ".yml":
+		var raw map[string]any
+		if err = yaml.Unmarshal(b, &raw); err == nil {
+			values, err = normalizeYAML(raw)
+		}
+	default:
+		return nil, fmt.Errorf("configuration file extension %q is not one of .toml, .json, .yaml, or .yml", filepath.Ext(p))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode %q: %w", p, err)
+	}
+	return values, nil
+}
+
+// normalizeYAML converts nested maps with arbitrary keys
+// into maps with string keys.
+func normalizeYAML(m map[string]any) (map[string]any, error) {
+	for key, value := range m {
+		nested, ok := value.(map[any]any)
+		if !ok {
+			continue
+		}
+		converted := make(map[string]any, len(nested))
+		for k, v := range nested {
+			name, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("key %v of %q is not a string", k, key)
+			}
+			converted[name] = v
+		}
+		normalized, err := normalizeYAML(converted)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = normalized
+	}
+	return m, nil
+}
+
+func formatConfigFileValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := formatConfigFileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// flattenConfigFile turns nested tables into dotted field names.
+func flattenConfigFile(prefix string, values map[string]any, flat map[string]any) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			flattenConfigFile(key, nested, flat)
+			continue
+		}
+		flat[key] = value
+	}
+}
+
+// LoadConfig fills a configuration structure from the configuration
+// file, environment variables, and flags in the order of increasing
+// precedence. Field names are taken from `config` struct tags or
+// converted to snake case. Embedded structures, such as [Config],
+// share the names of the outer structure, while nested ones add
+// a prefix. Tag fields with `secret:"true"` to redact their values
+// from the log and with `usage:"..."` to describe their flags.
+func LoadConfig[T any](withOptions ...ConfigOption) (*T, error) {
+	o := &configOptions{}
+	var err error
+	for _, option := range withOptions {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot load configuration: %w", err)
+		}
+	}
+
+	config := new(T)
+	target := reflect.ValueOf(config).Elem()
+	if target.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot load configuration into %T: not a structure", config)
+	}
+	fields, err := collectConfigFields(target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load configuration: %w", err)
+	}
+
+	flags := make(map[string]*configFlag, len(fields))
+	if o.FlagSet != nil {
+		for _, field := range fields {
+			f := &configFlag{isBool: field.value.Kind() == reflect.Bool}
+			flags[field.name()] = f
+			o.FlagSet.Var(f, field.flagName(), field.usage)
+		}
+		if err = o.FlagSet.Parse(o.Arguments); err != nil {
+			return nil, fmt.Errorf("cannot parse flags: %w", err)
+		}
+	}
+
+	if o.File != "" {
+		values, err := decodeConfigFile(o.File)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load configuration: %w", err)
+		}
+		flat := make(map[string]any, len(values))
+		flattenConfigFile("", values, flat)
+		for _, field := range fields {
+			value, ok := flat[field.name()]
+			if !ok {
+				continue
+			}
+			delete(flat, field.name())
+			s, err := formatConfigFileValue(value)
+			if err == nil {
+				err = field.set(s)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid %q value in %q: %w", field.name(), o.File, err)
+			}
+		}
+		for unknown := range flat {
+			return nil, fmt.Errorf("unknown setting %q in %q", unknown, o.File)
+		}
+	}
+
+	if o.EnvironmentPrefix != "" {
+		for _, field := range fields {
+			name := field.environmentName(o.EnvironmentPrefix)
+			if value, ok := os.LookupEnv(name); ok {
+				if err = field.set(value); err != nil {
+					return nil, fmt.Errorf("invalid %s environment variable: %w", name, err)
+				}
+			}
+		}
+	}
+
+	if o.FlagSet != nil {
+		isSet := make(map[string]bool)
+		o.FlagSet.Visit(func(f *flag.Flag) {
+			isSet[f.Name] = true
+		})
+		for _, field := range fields {
+			if !isSet[field.flagName()] {
+				continue
+			}
+			if err = field.set(flags[field.name()].value); err != nil {
+				return nil, fmt.Errorf("invalid -%s flag: %w", field.flagName(), err)
+			}
+		}
+	}
+
+	if o.Logger != nil {
+		attrs := make([]slog.Attr, 0, len(fields))
+		for _, field := range fields {
+			value := field.String()
+			if field.secret && !field.value.IsZero() {
+				value = Redacted
+			}
+			attrs = append(attrs, slog.String(field.name(), value))
+		}
+		o.Logger.LogAttrs(context.Background(), slog.LevelDebug, "effective configuration", attrs...)
+	}
+	return config, nil
+}
+
+// Config holds service settings that operators can tune without
+// rebuilding the binary. Zero values leave the defaults of [Run]
+// in place. Embed it into the application configuration
+// and load both with [LoadConfig].
+type Config struct {
+	Host               string        `config:"host" usage:"network interface to listen on"`
+	Port               uint32        `config:"port" usage:"TCP port to listen on"`
+	UnixSocket         string        `config:"unix_socket" usage:"path of a Unix domain socket to listen on"`
+	TLSCertificateFile string        `config:"tls_certificate_file" usage:"path of the PEM certificate chain"`
+	TLSKeyFile         string        `config:"tls_key_file" usage:"path of the PEM private key"`
+	HTTPSRedirectPort  uint32        `config:"https_redirect_port" usage:"TCP port that redirects plain HTTP requests to HTTPS"`
+	ReadTimeout        time.Duration `config:"read_timeout" usage:"maximum duration for reading the entire request"`
+	ReadHeaderTimeout  time.Duration `config:"read_header_timeout" usage:"maximum duration for reading request headers"`
+	WriteTimeout       time.Duration `config:"write_timeout" usage:"maximum duration before timing out writes of the response"`
+	IdleTimeout        time.Duration `config:"idle_timeout" usage:"maximum duration to wait for the next request on a keep-alive connection"`
+	ShutdownTimeout    time.Duration `config:"shutdown_timeout" usage:"maximum duration to wait for active requests on shutdown"`
+	DrainDuration      time.Duration `config:"drain_duration" usage:"duration to keep serving after readiness fails on shutdown"`
+	MaxHeaderBytes     int           `config:"max_header_bytes" usage:"maximum size of request headers in bytes"`
+}
+
+// WithConfig applies the non-zero settings through the same
+// options that are used in code, so the same range checks apply.
+// Do not combine it with code options for the same settings.
+func WithConfig(c *Config) Option {
+	return func(o *options) (err error) {
+		if c == nil {
+			return errors.New("cannot use a <nil> configuration")
+		}
+		if (c.TLSCertificateFile == "") != (c.TLSKeyFile == "") {
+			return errors.New("configuration must set both TLS certificate and key files")
+		}
+		if c.Host != "" && c.Port == 0 && c.HTTPSRedirectPort == 0 {
+			return errors.New("configuration sets host without port")
+		}
+		for _, setting := range []struct {
+			name    string
+			isSet   bool
+			applied Option
+		}{
+			{"tls_certificate_file", c.TLSCertificateFile != "", WithTLS(c.TLSCertificateFile, c.TLSKeyFile)},
+			{"read_timeout", c.ReadTimeout != 0, WithReadTimeout(c.ReadTimeout)},
+			{"read_header_timeout", c.ReadHeaderTimeout != 0, WithReadHeaderTimeout(c.ReadHeaderTimeout)},
+			{"write_timeout", c.WriteTimeout != 0, WithWriteTimeout(c.WriteTimeout)},
+			{"idle_timeout", c.IdleTimeout != 0, WithIdleTimeout(c.IdleTimeout)},
+			{"shutdown_timeout", c.ShutdownTimeout != 0, WithShutdownTimeout(c.ShutdownTimeout)},
+			{"drain_duration", c.DrainDuration != 0, WithDrainDuration(c.DrainDuration)},
+			{"max_header_bytes", c.MaxHeaderBytes != 0, WithMaxHeaderBytes(c.MaxHeaderBytes)},
+			{"port", c.Port != 0, WithAddress(c.Host, c.Port)},
+			{"unix_socket", c.UnixSocket != "", WithUnixSocket(c.UnixSocket)},
+			{"https_redirect_port", c.HTTPSRedirectPort != 0, WithHTTPSRedirect(c.Host, c.HTTPSRedirectPort)},
+		} {
+			if !setting.isSet {
+				continue
+			}
+			if err = setting.applied(o); err != nil {
+				return fmt.Errorf("invalid %q setting: %w", setting.name, err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testDatabaseConfig struct {
+	URL      string `config:"url"`
+	Password string `secret:"true"`
+}
+
+type testConfig struct {
+	Config
+	Database testDatabaseConfig
+	Features []string
+	Debug    bool
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	for name, content := range map[string]string{
+		"service.toml": `
+read_timeout = "3s"
+max_header_bytes = 4096
+features = ["search", "export"]
+
+[database]
+url = "postgres://localhost/app"
+password = "hunter2"
+`,
+		"service.json": `{
+  "read_timeout": "3s",
+  "max_header_bytes": 4096,
+  "features": ["search", "export"],
+  "database": {"url": "postgres://localhost/app", "password": "hunter2"}
+}`,
+		"service.yaml": `
+read_timeout: 3s
+max_header_bytes: 4096
+features: [search, export]
+database:
+  url: postgres://localhost/app
+  password: hunter2
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := LoadConfig[testConfig](WithConfigFile(writeConfigFile(t, name, content)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.ReadTimeout != time.Second*3 || c.MaxHeaderBytes != 4096 {
+				t.Fatalf("service settings were not loaded: %+v", c.Config)
+			}
+			if c.Database.URL != "postgres://localhost/app" || c.Database.Password != "hunter2" {
+				t.Fatalf("nested settings were not loaded: %+v", c.Database)
+			}
+			if strings.Join(c.Features, ",") != "search,export" {
+				t.Fatalf("list setting was not loaded: %+v", c.Features)
+			}
+		})
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	p := writeConfigFile(t, "service.toml", `
+port = 8000
+write_timeout = "1s"
+idle_timeout = "10s"
+`)
+	t.Setenv("TEST_WRITE_TIMEOUT", "2s")
+	t.Setenv("TEST_IDLE_TIMEOUT", "20s")
+	t.Setenv("TEST_DATABASE_PASSWORD", "secret")
+
+	b := &bytes.Buffer{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c, err := LoadConfig[testConfig](
+		WithConfigFile(p),
+		WithEnvironment("TEST_"),
+		WithFlags(fs, []string{"-idle-timeout", "30s", "-debug"}),
+		WithConfigLogger(slog.New(slog.NewTextHandler(b, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+		}))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 8000 {
+		t.Fatal("file setting was lost:", c.Port)
+	}
+	if c.WriteTimeout != time.Second*2 {
+		t.Fatal("environment did not override the file:", c.WriteTimeout)
+	}
+	if c.IdleTimeout != time.Second*30 {
+		t.Fatal("flag did not override the environment:", c.IdleTimeout)
+	}
+	if !c.Debug || c.Database.Password != "secret" {
+		t.Fatalf("unexpected configuration: %+v", c)
+	}
+	if fs.Lookup("database-password") == nil || fs.Lookup("https-redirect-port") == nil {
+		t.Fatal("flags were not registered")
+	}
+
+	logged := b.String()
+	if strings.Contains(logged, "secret") || !strings.Contains(logged, "database.password="+Redacted) {
+		t.Fatal("secret was not redacted:", logged)
+	}
+	if !strings.Contains(logged, "idle_timeout=30s") {
+		t.Fatal("effective configuration was not logged:", logged)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	if _, err := LoadConfig[testConfig](WithConfigFile(
+		writeConfigFile(t, "service.toml", `read_timout = "3s"`),
+	)); err == nil {
+		t.Fatal("misspelled setting was accepted")
+	}
+	if _, err := LoadConfig[testConfig](WithConfigFile(
+		writeConfigFile(t, "service.ini", `read_timeout=3s`),
+	)); err == nil {
+		t.Fatal("unsupported file format was accepted")
+	}
+	t.Setenv("TEST_PORT", "http")
+	if _, err := LoadConfig[testConfig](WithEnvironment("TEST_")); err == nil {
+		t.Fatal("invalid port was accepted")
+	}
+	if _, err := LoadConfig[int](); err == nil {
+		t.Fatal("configuration must be a structure")
+	}
+}
+
+func TestWithConfig(t *testing.T) {
+	o := &options{}
+	if err := WithConfig(&Config{
+		ReadTimeout:    time.Second,
+		MaxHeaderBytes: 1 << 12,
+	})(o); err != nil {
+		t.Fatal(err)
+	}
+	if o.ReadTimeout != time.Second || o.MaxHeaderBytes != 1<<12 {
+		t.Fatalf("settings were not applied: %+v", o)
+	}
+
+	for _, invalid := range []*Config{
+		{ReadTimeout: time.Millisecond},
+		{MaxHeaderBytes: -1},
+		{TLSCertificateFile: "service.crt"},
+		{Host: "localhost"},
+	} {
+		if err := WithConfig(invalid)(&options{}); err == nil {
+			t.Fatalf("invalid configuration was accepted: %+v", invalid)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"ReadTimeout":       "read_timeout",
+		"HTTPSRedirectPort": "https_redirect_port",
+		"TLSKeyFile":        "tls_key_file",
+		"URL":               "url",
+	} {
+		if actual := snakeCase(name); actual != expected {
+			t.Fatalf("%s became %s instead of %s", name, actual, expected)
+		}
+	}
+}
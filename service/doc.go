@@ -5,6 +5,53 @@ Package service provides a standard library [http.Server] with conventional prod
 
 	err := service.Run(context.Background())
 
+# TLS Usage
+
+Certificate files are reloaded when they change. Repeat [WithTLS] to serve several certificates selected by Server Name Indication.
+
+	err := service.Run(
+	  context.Background(),
+	  service.WithTLS("example.com.crt", "example.com.key"),
+	  service.WithTLS("example.org.crt", "example.org.key"),
+	  service.WithAddress("", 443),
+	  service.WithHTTPSRedirect("", 80),
+	)
+
+Certificates can also be obtained automatically using [WithACME] or generated for local development using [WithSelfSignedCertificate].
+
+# Graceful Shutdown
+
+On interrupt or termination signal, [Run] flips [Readiness] to failing, keeps serving for [WithDrainDuration], closes [ShutdownSignal] channels for streaming handlers, waits for active requests up to [WithShutdownTimeout], and then runs [WithOnShutdown] hooks in reverse order. Share [Readiness] with the health package using [WithReadiness] to report draining to load balancers.
+
+	err := service.Run(
+	  context.Background(),
+	  service.WithDrainDuration(5*time.Second),
+	  service.WithOnShutdown(func(ctx context.Context) error {
+	    return db.Close()
+	  }),
+	)
+
+# Zero-Downtime Restarts
+
+With [WithGracefulRestart], SIGHUP or SIGUSR2 starts the executable again and hands it the open listeners. The old process drains and exits once the new one serves all of them, so upgrading the binary on disk and sending the signal replaces it without refusing connections. Under systemd, [Run] also reports READY, RELOADING, and STOPPING states and pings the watchdog when WatchdogSec is set.
+
+# Configuration
+
+Operators can tune a deployed binary using a TOML, JSON, or YAML file, environment variables, and flags, in the order of increasing precedence. Settings pass through the same options as code, so they are validated by the same range checks.
+
+	type AppConfig struct {
+	  service.Config
+	  DatabasePassword string `secret:"true"`
+	}
+
+	config, err := service.LoadConfig[AppConfig](
+	  service.WithConfigFile("/etc/app/config.toml"),
+	  service.WithEnvironment("APP_"), // APP_READ_TIMEOUT=5s
+	  service.WithCommandLine(),       // -read-timeout=5s
+	  service.WithConfigLogger(logger),
+	)
+	err = service.Run(ctx, service.WithConfig(&config.Config))
+
 # NGrok Usage
 
 Service is easy to use with <https://ngrok.com> tunnel, which exposes your local server to the world. Use with caution. You should be fairly confident that your code is secure and will not leak data from your system or damage it.
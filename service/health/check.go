@@ -0,0 +1,161 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Check returns an error when a dependency is unhealthy.
+type Check func(context.Context) error
+
+type checkOptions struct {
+	Timeout       time.Duration
+	CacheDuration time.Duration
+	NonCritical   bool
+}
+
+type CheckOption func(*checkOptions) error
+
+// WithTimeout limits how long the check may run.
+func WithTimeout(d time.Duration) CheckOption {
+	return func(o *checkOptions) error {
+		if o.Timeout != 0 {
+			return errors.New("timeout is already set")
+		}
+		if d < time.Millisecond*10 {
+			return errors.New("cannot set timeout lower than 10ms")
+		}
+		if d > time.Minute {
+			return errors.New("cannot set timeout above one minute")
+		}
+		o.Timeout = d
+		return nil
+	}
+}
+
+func WithDefaultTimeout() CheckOption {
+	return func(o *checkOptions) error {
+		if o.Timeout != 0 {
+			return nil
+		}
+		return WithTimeout(time.Second * 2)(o)
+	}
+}
+
+// WithCacheDuration reuses the last result for the given duration,
+// which protects expensive dependencies from frequent probes.
+func WithCacheDuration(d time.Duration) CheckOption {
+	return func(o *checkOptions) error {
+		if o.CacheDuration != 0 {
+			return errors.New("cache duration is already set")
+		}
+		if d < time.Millisecond*100 {
+			return errors.New("cannot set cache duration lower than 100ms")
+		}
+		o.CacheDuration = d
+		return nil
+	}
+}
+
+// WithNonCritical marks a check that only degrades the service
+// without making it unready. Failing non-critical checks
+// are reported with [StatusWarn] by the health endpoint
+// and are skipped by the readiness endpoint.
+func WithNonCritical() CheckOption {
+	return func(o *checkOptions) error {
+		if o.NonCritical {
+			return errors.New("check is already non-critical")
+		}
+		o.NonCritical = true
+		return nil
+	}
+}
+
+type check struct {
+	name          string
+	check         Check
+	timeout       time.Duration
+	cacheDuration time.Duration
+	critical      bool
+
+	mu   sync.Mutex
+	last *Result
+}
+
+func newCheck(name string, c Check, withOptions ...CheckOption) (*check, error) {
+	if name == "" {
+		return nil, errors.New("cannot use an empty check name")
+	}
+	if c == nil {
+		return nil, errors.New("cannot use a <nil> check")
+	}
+	o := &checkOptions{}
+	var err error
+	for _, option := range append(
+		withOptions,
+		WithDefaultTimeout(),
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create check %q: %w", name, err)
+		}
+	}
+	return &check{
+		name:          name,
+		check:         c,
+		timeout:       o.Timeout,
+		cacheDuration: o.CacheDuration,
+		critical:      !o.NonCritical,
+	}, nil
+}
+
+// run serializes concurrent probes, so that a slow dependency
+// is checked once and the cached result is shared.
+func (c *check) run(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.last != nil && now.Sub(c.last.CheckedAt) < c.cacheDuration {
+		return *c.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				errs <- fmt.Errorf("check panicked: %v", recovered)
+			}
+		}()
+		errs <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = fmt.Errorf("check did not complete in %s: %w", c.timeout, ctx.Err())
+	}
+	result := Result{
+		Name:      c.name,
+		Status:    StatusPass,
+		Critical:  c.critical,
+		CheckedAt: now,
+		Duration:  time.Since(now),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Status = StatusFail
+		if !c.critical {
+			result.Status = StatusWarn
+		}
+	}
+	if ctx.Err() == nil || !errors.Is(context.Cause(ctx), context.Canceled) {
+		c.last = &result // do not cache results of abandoned probes
+	}
+	return result
+}
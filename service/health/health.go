@@ -0,0 +1,242 @@
+/*
+Package health provides liveness, readiness, and health endpoints
+backed by a registry of named [Check]s.
+
+	readiness := &service.Readiness{}
+	checks, err := health.New(
+	  health.WithReadiness(readiness),
+	  health.WithCheck("database", db.PingContext, health.WithTimeout(time.Second)),
+	  health.WithCheck("mailer", mailer.Ping, health.WithNonCritical()),
+	)
+	mux.Handle("/", checks.Handler())
+	err = service.Run(ctx, service.WithReadiness(readiness), service.WithHandler(mux))
+
+Readiness fails as soon as the service starts draining connections,
+so that load balancers stop routing new traffic to it.
+*/
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dkotik/htadaptor"
+)
+
+// Paths of the endpoints served by [Registry.Handler].
+const (
+	LivenessPath  = "/livez"
+	ReadinessPath = "/readyz"
+	HealthPath    = "/healthz"
+)
+
+// Status follows the health check response format draft
+// by Nadareishvili et al.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Readiness reports whether the service accepts new traffic.
+// Satisfied by [github.com/dkotik/htadaptor/service.Readiness].
+type Readiness interface {
+	IsReady() bool
+}
+
+// Result is the outcome of a single [Check].
+type Result struct {
+	Name      string        `json:"name"`
+	Status    Status        `json:"status"`
+	Critical  bool          `json:"critical"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"durationNanoseconds"`
+	CheckedAt time.Time     `json:"checkedAt"`
+}
+
+// Report combines [Result]s into an overall [Status].
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks,omitempty"`
+}
+
+func newReport(results []Result) *Report {
+	report := &Report{Status: StatusPass, Checks: results}
+	for _, result := range results {
+		switch result.Status {
+		case StatusFail:
+			report.Status = StatusFail
+		case StatusWarn:
+			if report.Status == StatusPass {
+				report.Status = StatusWarn
+			}
+		}
+	}
+	return report
+}
+
+type options struct {
+	Readiness Readiness
+	Checks    []*check
+}
+
+type Option func(*options) error
+
+// WithReadiness fails readiness when the service is not ready,
+// for example, while it is draining connections before shutdown.
+func WithReadiness(r Readiness) Option {
+	return func(o *options) error {
+		if r == nil {
+			return errors.New("cannot use a <nil> readiness")
+		}
+		if o.Readiness != nil {
+			return errors.New("readiness is already set")
+		}
+		o.Readiness = r
+		return nil
+	}
+}
+
+// WithCheck registers a named [Check]. Checks are critical by default.
+func WithCheck(name string, c Check, withOptions ...CheckOption) Option {
+	return func(o *options) error {
+		for _, existing := range o.Checks {
+			if existing.name == name {
+				return fmt.Errorf("check %q is already registered", name)
+			}
+		}
+		check, err := newCheck(name, c, withOptions...)
+		if err != nil {
+			return err
+		}
+		o.Checks = append(o.Checks, check)
+		return nil
+	}
+}
+
+// Registry runs [Check]s and reports their results.
+type Registry struct {
+	readiness Readiness
+	checks    []*check
+}
+
+func New(withOptions ...Option) (*Registry, error) {
+	o := &options{}
+	var err error
+	for _, option := range withOptions {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create health check registry: %w", err)
+		}
+	}
+	return &Registry{
+		readiness: o.Readiness,
+		checks:    o.Checks,
+	}, nil
+}
+
+func (r *Registry) run(ctx context.Context, criticalOnly bool) []Result {
+	results := make([]Result, len(r.checks))
+	wg := sync.WaitGroup{}
+	for i, c := range r.checks {
+		if criticalOnly && !c.critical {
+			continue
+		}
+		wg.Go(func() {
+			results[i] = c.run(ctx)
+		})
+	}
+	wg.Wait()
+
+	completed := results[:0]
+	for _, result := range results {
+		if result.Name != "" {
+			completed = append(completed, result)
+		}
+	}
+	return completed
+}
+
+// Live reports that the process is able to serve requests.
+// It runs no checks, because restarting the process does not
+// fix failing dependencies.
+func (r *Registry) Live(ctx context.Context) (*Report, error) {
+	return newReport(nil), nil
+}
+
+// Ready runs critical checks, unless the service is not ready.
+func (r *Registry) Ready(ctx context.Context) (*Report, error) {
+	if r.readiness != nil && !r.readiness.IsReady() {
+		return newReport([]Result{{
+			Name:      "service",
+			Status:    StatusFail,
+			Critical:  true,
+			Error:     "service is not accepting new traffic",
+			CheckedAt: time.Now(),
+		}}), nil
+	}
+	return newReport(r.run(ctx, true)), nil
+}
+
+// Health runs all checks.
+func (r *Registry) Health(ctx context.Context) (*Report, error) {
+	report := newReport(r.run(ctx, false))
+	if r.readiness != nil && !r.readiness.IsReady() {
+		report.Status = StatusFail
+	}
+	return report, nil
+}
+
+// reportEncoder responds with [http.StatusServiceUnavailable]
+// to failed reports, which is what probes look at.
+var reportEncoder = htadaptor.EncoderFunc(
+	func(w http.ResponseWriter, r *http.Request, code int, v any) error {
+		if report, ok := v.(*Report); ok && report.Status == StatusFail {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("cache-control", "no-store")
+		return htadaptor.JSONEncoder.Encode(w, r, code, v)
+	},
+)
+
+// Handler serves [LivenessPath], [ReadinessPath], and [HealthPath].
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	adaptor := htadaptor.New(htadaptor.WithEncoder(reportEncoder))
+	for path, report := range map[string]func(context.Context) (*Report, error){
+		LivenessPath:  r.Live,
+		ReadinessPath: r.Ready,
+		HealthPath:    r.Health,
+	} {
+		mux.Handle("GET "+path, htadaptor.Must(adaptor.AdaptNullaryFunc(report)))
+	}
+	return mux
+}
+
+// IsProbe returns true for requests to health endpoints mounted
+// at the root. Use it to exclude probes from activity tracking,
+// such as the idledown middleware, since probes arrive regardless
+// of traffic. Use [IsProbeUnder] for endpoints mounted elsewhere.
+func IsProbe(r *http.Request) bool {
+	return isProbePath(r.URL.Path)
+}
+
+// IsProbeUnder matches requests to health endpoints mounted
+// under the path prefix, such as "/admin" for "/admin/livez".
+func IsProbeUnder(prefix string) func(*http.Request) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(r *http.Request) bool {
+		p, ok := strings.CutPrefix(r.URL.Path, prefix)
+		return ok && isProbePath(p)
+	}
+}
+
+func isProbePath(p string) bool {
+	return p == LivenessPath || p == ReadinessPath || p == HealthPath
+}
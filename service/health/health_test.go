@@ -0,0 +1,155 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testReadiness struct {
+	atomic.Bool
+}
+
+func (r *testReadiness) IsReady() bool {
+	return r.Load()
+}
+
+func TestRegistry(t *testing.T) {
+	readiness := &testReadiness{}
+	readiness.Store(true)
+	databaseCalls := &atomic.Int32{}
+	mailerFailure := &atomic.Bool{}
+
+	registry, err := New(
+		WithReadiness(readiness),
+		WithCheck("database", func(context.Context) error {
+			databaseCalls.Add(1)
+			return nil
+		}, WithCacheDuration(time.Minute)),
+		WithCheck("mailer", func(context.Context) error {
+			if mailerFailure.Load() {
+				return errors.New("mailer is down")
+			}
+			return nil
+		}, WithNonCritical()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := registry.Handler()
+
+	probe := func(path string, expectedCode int, expectedStatus Status) *Report {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != expectedCode {
+			t.Fatalf("%s status code %d does not match %d: %s", path, w.Code, expectedCode, w.Body.String())
+		}
+		if w.Header().Get("cache-control") != "no-store" {
+			t.Fatal("probe response must not be cached")
+		}
+		report := &Report{}
+		if err := json.NewDecoder(w.Body).Decode(report); err != nil {
+			t.Fatal(err)
+		}
+		if report.Status != expectedStatus {
+			t.Fatalf("%s status %q does not match %q", path, report.Status, expectedStatus)
+		}
+		return report
+	}
+
+	probe(LivenessPath, http.StatusOK, StatusPass)
+	if report := probe(HealthPath, http.StatusOK, StatusPass); len(report.Checks) != 2 {
+		t.Fatalf("expected two checks, got: %+v", report.Checks)
+	}
+	probe(ReadinessPath, http.StatusOK, StatusPass)
+	if calls := databaseCalls.Load(); calls != 1 {
+		t.Fatalf("cached check was called %d times", calls)
+	}
+
+	mailerFailure.Store(true)
+	report := probe(HealthPath, http.StatusOK, StatusWarn)
+	if report.Checks[1].Error != "mailer is down" {
+		t.Fatalf("failure detail is missing: %+v", report.Checks[1])
+	}
+	if report = probe(ReadinessPath, http.StatusOK, StatusPass); len(report.Checks) != 1 {
+		t.Fatal("readiness must skip non-critical checks")
+	}
+
+	readiness.Store(false)
+	probe(ReadinessPath, http.StatusServiceUnavailable, StatusFail)
+	probe(HealthPath, http.StatusServiceUnavailable, StatusFail)
+	probe(LivenessPath, http.StatusOK, StatusPass)
+}
+
+func TestCheckTimeout(t *testing.T) {
+	registry, err := New(
+		WithCheck("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(time.Second) // ignores cancellation
+			return nil
+		}, WithTimeout(time.Millisecond*20)),
+		WithCheck("panic", func(context.Context) error {
+			panic("boom")
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	report, err := registry.Ready(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > time.Millisecond*500 {
+		t.Fatal("check timeout was not enforced")
+	}
+	if report.Status != StatusFail {
+		t.Fatal("timed out and panicking checks must fail")
+	}
+	for _, result := range report.Checks {
+		if result.Error == "" {
+			t.Fatalf("check %q has no error detail", result.Name)
+		}
+	}
+
+	if _, err = New(
+		WithCheck("duplicate", func(context.Context) error { return nil }),
+		WithCheck("duplicate", func(context.Context) error { return nil }),
+	); err == nil {
+		t.Fatal("duplicate check names must be rejected")
+	}
+}
+
+func TestIsProbe(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"/livez":             true,
+		"/healthz":           true,
+		"/admin/readyz":      false,
+		"/api/users/healthz": false,
+		"/":                  false,
+		"/healthzombies":     false,
+	} {
+		if IsProbe(httptest.NewRequest(http.MethodGet, path, nil)) != expected {
+			t.Fatalf("path %q probe detection does not match %t", path, expected)
+		}
+	}
+
+	isAdminProbe := IsProbeUnder("/admin/")
+	for path, expected := range map[string]bool{
+		"/admin/readyz":      true,
+		"/admin/livez":       true,
+		"/readyz":            false,
+		"/api/admin/readyz":  false,
+		"/administer/readyz": false,
+	} {
+		if isAdminProbe(httptest.NewRequest(http.MethodGet, path, nil)) != expected {
+			t.Fatalf("path %q probe detection under /admin does not match %t", path, expected)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Hook runs when the service starts or shuts down. Use hooks
+// to warm caches, flush queues, and close database connections.
+type Hook func(context.Context) error
+
+// Readiness reports whether the service should receive new traffic.
+// [Run] marks the service ready once all listeners are serving
+// and not ready as soon as the shutdown begins, before connections
+// are drained. Share it with health checks using [WithReadiness].
+type Readiness struct {
+	ready atomic.Bool
+}
+
+// IsReady returns true while the service accepts new traffic.
+func (r *Readiness) IsReady() bool {
+	return r.ready.Load()
+}
+
+func (r *Readiness) set(ready bool) {
+	r.ready.Store(ready)
+}
+
+type shutdownSignalKey struct{}
+
+// ShutdownSignal returns a channel that is closed when the service
+// begins shutting down. Long-lived streaming handlers, such as
+// server-sent events, should select on it to finish early,
+// because [http.Server.Shutdown] does not cancel request contexts.
+// Returns a <nil> channel, which blocks forever, if the request
+// was not served by [Run].
+func ShutdownSignal(ctx context.Context) <-chan struct{} {
+	signal, _ := ctx.Value(shutdownSignalKey{}).(chan struct{})
+	return signal
+}
+
+// WithShutdownTimeout limits how long [Run] waits for active
+// requests to complete after draining. The same limit applies
+// to the shutdown hooks.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if o.ShutdownTimeout != 0 {
+			return errors.New("shutdown timeout is already set")
+		}
+		if d < time.Millisecond*100 {
+			return errors.New("cannot set shutdown timeout lower than 100ms")
+		}
+		if d > time.Minute*10 {
+			return errors.New("cannot set shutdown timeout above ten minutes")
+		}
+		o.ShutdownTimeout = d
+		return nil
+	}
+}
+
+// WithDrainDuration keeps serving requests for a while after
+// readiness flips to failing, so that load balancers have time
+// to route new traffic elsewhere before listeners close.
+func WithDrainDuration(d time.Duration) Option {
+	return func(o *options) error {
+		if o.DrainDuration != 0 {
+			return errors.New("drain duration is already set")
+		}
+		if d < time.Millisecond*100 {
+			return errors.New("cannot set drain duration lower than 100ms")
+		}
+		if d > time.Minute*5 {
+			return errors.New("cannot set drain duration above five minutes")
+		}
+		o.DrainDuration = d
+		return nil
+	}
+}
+
+// WithReadiness shares the service [Readiness] with health checks.
+func WithReadiness(r *Readiness) Option {
+	return func(o *options) error {
+		if r == nil {
+			return errors.New("cannot use a <nil> readiness")
+		}
+		if o.Readiness != nil {
+			return errors.New("readiness is already set")
+		}
+		o.Readiness = r
+		return nil
+	}
+}
+
+// WithOnStart adds a [Hook] that runs after the listeners are bound
+// and before any requests are served. A failing hook stops
+// the service.
+func WithOnStart(h Hook) Option {
+	return func(o *options) error {
+		if h == nil {
+			return errors.New("cannot use a <nil> start hook")
+		}
+		o.OnStart = append(o.OnStart, h)
+		return nil
+	}
+}
+
+// WithOnShutdown adds a [Hook] that runs after all requests are
+// completed. Hooks run in reverse order, like deferred calls,
+// so that resources are released in the reverse order
+// of their acquisition.
+func WithOnShutdown(h Hook) Option {
+	return func(o *options) error {
+		if h == nil {
+			return errors.New("cannot use a <nil> shutdown hook")
+		}
+		o.OnShutdown = append(o.OnShutdown, h)
+		return nil
+	}
+}
+
+// WithRegisterOnShutdown calls the function once when shutdown begins,
+// no matter how many listeners are served. Use it to notify hijacked
+// connections, such as WebSockets, which [http.Server.Shutdown] does
+// not track. See [http.Server.RegisterOnShutdown].
+func WithRegisterOnShutdown(f func()) Option {
+	return func(o *options) error {
+		if f == nil {
+			return errors.New("cannot use a <nil> shutdown function")
+		}
+		once := &sync.Once{}
+		o.RegisterOnShutdown = append(o.RegisterOnShutdown, func() {
+			once.Do(f) // every listener server calls it
+		})
+		return nil
+	}
+}
+
+func (o *options) baseContext(shutdownSignal chan struct{}) ContextFactory {
+	return func(l net.Listener) context.Context {
+		return context.WithValue(o.ContextFactory(l), shutdownSignalKey{}, shutdownSignal)
+	}
+}
+
+// errGracefulRestart stops the process that handed its listeners
+// to a replacement.
+var errGracefulRestart = fmt.Errorf("listeners were handed off: %w", context.Canceled)
+
+func isOrdinaryCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
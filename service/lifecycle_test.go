@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	URL := "http://" + ln.Addr().String()
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+	hook := func(event string) Hook {
+		return func(context.Context) error {
+			record(event)
+			return nil
+		}
+	}
+
+	readiness := &Readiness{}
+	streaming := make(chan struct{})
+	notified := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Run(
+			ctx,
+			WithListener(ln),
+			WithReadiness(readiness),
+			WithDrainDuration(time.Millisecond*200),
+			WithShutdownTimeout(time.Second),
+			WithOnStart(hook("start")),
+			WithOnShutdown(hook("close database")),
+			WithOnShutdown(hook("flush queue")),
+			WithRegisterOnShutdown(func() { close(notified) }),
+			WithHandler(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/stream" {
+						return
+					}
+					w.WriteHeader(http.StatusOK)
+					w.(http.Flusher).Flush()
+					close(streaming)
+					<-ShutdownSignal(r.Context())
+					record("stream closed")
+				},
+			)),
+		)
+	}()
+
+	for deadline := time.Now().Add(time.Second); !readiness.IsReady(); {
+		if time.Now().After(deadline) {
+			t.Fatal("service did not become ready")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	go func() {
+		response, err := http.Get(URL + "/stream")
+		if err == nil {
+			_, _ = io.Copy(io.Discard, response.Body)
+			_ = response.Body.Close()
+		}
+	}()
+	<-streaming
+
+	cancel()
+	time.Sleep(time.Millisecond * 50)
+	if readiness.IsReady() {
+		t.Fatal("readiness did not flip before shutdown")
+	}
+	response, err := http.Get(URL)
+	if err != nil {
+		t.Fatal("service stopped serving while draining:", err)
+	}
+	_ = response.Body.Close()
+
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal("ordinary shutdown returned an error:", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("service did not shut down")
+	}
+
+	select {
+	case <-notified: // runs in its own goroutine
+	case <-time.After(time.Second):
+		t.Fatal("hijacked connections were not notified")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"start", "stream closed", "flush queue", "close database"}
+	if !slices.Equal(events, expected) {
+		t.Fatalf("lifecycle events %v do not match %v", events, expected)
+	}
+}
+
+func TestRegisterOnShutdownWithTwoListeners(t *testing.T) {
+	first, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readiness := &Readiness{}
+	notified := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Run(
+			ctx,
+			WithListener(first),
+			WithListener(second),
+			WithReadiness(readiness),
+			WithDrainDuration(time.Millisecond*100),
+			WithRegisterOnShutdown(func() { close(notified) }),
+			WithHandler(http.NotFoundHandler()),
+		)
+	}()
+	for deadline := time.Now().Add(time.Second); !readiness.IsReady(); {
+		if time.Now().After(deadline) {
+			t.Fatal("service did not become ready")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	cancel()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal("ordinary shutdown returned an error:", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("service did not shut down")
+	}
+	select {
+	case <-notified:
+	case <-time.After(time.Second):
+		t.Fatal("hijacked connections were not notified")
+	}
+	time.Sleep(time.Millisecond * 50) // a second call would panic
+}
+
+func TestRunErrors(t *testing.T) {
+	failure := errors.New("failure")
+
+	t.Run("start hook", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = Run(
+			context.Background(),
+			WithListener(ln),
+			WithOnStart(func(context.Context) error { return failure }),
+		)
+		if !errors.Is(err, failure) {
+			t.Fatal("start hook error was not returned:", err)
+		}
+		if _, err = ln.Accept(); err == nil {
+			t.Fatal("listener was not closed")
+		}
+	})
+
+	t.Run("shutdown hook", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = Run(
+			ctx,
+			WithListener(ln),
+			WithOnShutdown(func(context.Context) error { return failure }),
+		)
+		if !errors.Is(err, failure) {
+			t.Fatal("shutdown hook error was not returned:", err)
+		}
+	})
+
+	t.Run("serve", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ln.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err = Run(context.Background(), WithListener(ln)); err == nil {
+			t.Fatal("serving a closed listener did not return an error")
+		}
+	})
+
+	t.Run("cause", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(failure)
+		if err = Run(ctx, WithListener(ln)); !errors.Is(err, failure) {
+			t.Fatal("cancellation cause was not returned:", err)
+		}
+	})
+}
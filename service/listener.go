@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/dkotik/htadaptor"
+)
+
+// listener pairs a network listener with the handler it serves.
+// Listeners with a <nil> handler serve the main [WithHandler].
+// Redirect listeners serve plain HTTP and send clients to HTTPS.
+type listener struct {
+	net.Listener
+	Handler  http.Handler
+	Redirect bool
+}
+
+// WithListener adds a network listener that serves the main handler.
+// Repeat the option to serve several listeners concurrently.
+func WithListener(l net.Listener) Option {
+	return func(o *options) error {
+		if l == nil {
+			return errors.New("cannot use a <nil> network listener")
+		}
+		o.Listeners = append(o.Listeners, &listener{Listener: l})
+		return nil
+	}
+}
+
+// WithListenerHandler adds a network listener that serves
+// its own handler instead of the main one.
+func WithListenerHandler(l net.Listener, h http.Handler) Option {
+	return func(o *options) error {
+		if l == nil {
+			return errors.New("cannot use a <nil> network listener")
+		}
+		if h == nil {
+			return errors.New("cannot use a <nil> HTTP handler")
+		}
+		o.Listeners = append(o.Listeners, &listener{Listener: l, Handler: h})
+		return nil
+	}
+}
+
+func WithAddress(host string, port uint32) Option {
+	return func(o *options) (err error) {
+		if port < 1 {
+			return errors.New("cannot use port lower than 1")
+		}
+		address := net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10))
+		if inherited := takeInheritedListener("tcp", address); inherited != nil {
+			return WithListener(inherited)(o)
+		}
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return fmt.Errorf("cannot bind listener to %q address: %w", address, err)
+		}
+		return WithListener(listener)(o)
+	}
+}
+
+// WithUnixSocket adds a Unix domain socket listener that serves
+// the main handler. A stale socket file left over from a previous
+// run is removed, unless the socket was handed over by
+// [WithGracefulRestart].
+func WithUnixSocket(p string) Option {
+	return func(o *options) error {
+		if p == "" {
+			return errors.New("cannot use an empty Unix socket path")
+		}
+		if inherited := takeInheritedListener("unix", p); inherited != nil {
+			return WithListener(inherited)(o)
+		}
+		if info, err := os.Stat(p); err == nil {
+			if info.Mode().Type() != fs.ModeSocket {
+				return fmt.Errorf("cannot replace %q with a Unix socket, because it is not a socket", p)
+			}
+			if err = os.Remove(p); err != nil {
+				return fmt.Errorf("cannot remove stale Unix socket: %w", err)
+			}
+		}
+		listener, err := net.Listen("unix", p)
+		if err != nil {
+			return fmt.Errorf("cannot bind listener to Unix socket %q: %w", p, err)
+		}
+		return WithListener(listener)(o)
+	}
+}
+
+// WithHTTPSRedirect adds a plain HTTP listener that permanently
+// redirects all requests to the same host and path over HTTPS.
+// The port of the first main listener is used, unless it is 443.
+// The listener also answers ACME HTTP-01 challenges when [WithACME]
+// is set. Conventionally, the port is 80.
+func WithHTTPSRedirect(host string, port uint32) Option {
+	return func(o *options) (err error) {
+		if port < 1 {
+			return errors.New("cannot use port lower than 1")
+		}
+		address := net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10))
+		l := takeInheritedListener("tcp", address)
+		if l == nil {
+			l, err = net.Listen("tcp", address)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot bind redirect listener to %q address: %w", address, err)
+		}
+		o.Listeners = append(o.Listeners, &listener{
+			Listener: l,
+			Redirect: true,
+		})
+		return nil
+	}
+}
+
+// secureRedirect sends clients to the same host and path
+// over HTTPS using the given port, which is omitted when empty.
+type secureRedirect string
+
+func (s secureRedirect) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	if host == "" {
+		http.Error(w, "Host Header Is Required", http.StatusBadRequest)
+		return
+	}
+	if s != "" {
+		host = net.JoinHostPort(host, string(s))
+	}
+	htadaptor.NewPermanentRedirect("https://"+host+r.URL.RequestURI()).ServeHTTP(w, r)
+}
+
+// newSecureRedirect targets the port of the first main listener.
+// Answers ACME HTTP-01 challenges, if [WithACME] is set.
+func (o *options) newSecureRedirect() (h http.Handler) {
+	port := ""
+	for _, l := range o.Listeners {
+		if l.Handler != nil || l.Redirect {
+			continue
+		}
+		if address, ok := l.Addr().(*net.TCPAddr); ok && address.Port != 443 {
+			port = strconv.Itoa(address.Port)
+		}
+		break
+	}
+	h = secureRedirect(port)
+	if o.ACME != nil {
+		h = o.ACME.HTTPHandler(h)
+	}
+	return h
+}
+
+func (o *options) closeListeners() (err error) {
+	for _, l := range o.Listeners {
+		err = errors.Join(err, l.Close())
+	}
+	if o.HTTP3 != nil {
+		err = errors.Join(err, o.HTTP3.Conn.Close())
+	}
+	return err
+}
+
+func (o *options) hasMainListener() bool {
+	for _, l := range o.Listeners {
+		if l.Handler == nil && !l.Redirect {
+			return true
+		}
+	}
+	return false
+}
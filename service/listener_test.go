@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMultipleListeners(t *testing.T) {
+	main, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(t.TempDir(), "service.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Run(
+			ctx,
+			WithListener(main),
+			WithListenerHandler(admin, http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					_, _ = io.WriteString(w, "admin")
+				},
+			)),
+			WithUnixSocket(socket),
+			WithHandler(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					_, _ = io.WriteString(w, "main")
+				},
+			)),
+		)
+	}()
+
+	for deadline := time.Now().Add(time.Second); ; {
+		if _, err = os.Stat(socket); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Unix socket was not bound:", err)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	for client, expectations := range map[*http.Client]map[string]string{
+		http.DefaultClient: {
+			"http://" + main.Addr().String():  "main",
+			"http://" + admin.Addr().String(): "admin",
+		},
+		unixClient: {
+			"http://unix": "main",
+		},
+	} {
+		for URL, expected := range expectations {
+			response, err := client.Get(URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(response.Body)
+			_ = response.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != expected {
+				t.Fatalf("listener %q served %q instead of %q", URL, body, expected)
+			}
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("listeners did not shut down together")
+	}
+	if _, err = http.Get("http://" + admin.Addr().String()); err == nil {
+		t.Fatal("listener is still serving after shutdown")
+	}
+}
+
+func TestSecureRedirect(t *testing.T) {
+	main, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer main.Close()
+	o := &options{}
+	if err = WithListener(main)(o); err != nil {
+		t.Fatal(err)
+	}
+	port := main.Addr().(*net.TCPAddr).Port
+
+	cases := map[string]string{
+		"http://example.com/path?q=1":      "https://example.com:" + strconv.Itoa(port) + "/path?q=1",
+		"http://example.com:80/a/b":        "https://example.com:" + strconv.Itoa(port) + "/a/b",
+		"http://[::1]:80/ipv6?encoded=%20": "https://[::1]:" + strconv.Itoa(port) + "/ipv6?encoded=%20",
+	}
+	redirect := o.newSecureRedirect()
+	for from, to := range cases {
+		w := httptest.NewRecorder()
+		redirect.ServeHTTP(w, httptest.NewRequest(http.MethodGet, from, nil))
+		if w.Code != http.StatusPermanentRedirect {
+			t.Fatalf("unexpected status code: %d", w.Code)
+		}
+		if location := w.Header().Get("Location"); location != to {
+			t.Fatalf("%q redirected to %q instead of %q", from, location, to)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	secureRedirect("").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if location := w.Header().Get("Location"); location != "https://example.com/" {
+		t.Fatalf("default port was not omitted: %q", location)
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/coreos/go-systemd/daemon"
+)
+
+// sdNotify reports service state to systemd. It does nothing
+// unless the service runs under systemd with Type=notify.
+func sdNotify(logger *slog.Logger, state string) {
+	if _, err := daemon.SdNotify(false, state); err != nil {
+		logger.Warn("cannot notify systemd", slog.String("state", state), slog.Any("error", err))
+	}
+}
+
+// startWatchdog pings the systemd watchdog at half of the interval
+// set by WatchdogSec until the context is done.
+func startWatchdog(ctx context.Context, logger *slog.Logger) {
+	interval, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		logger.Warn("cannot read systemd watchdog settings", slog.Any("error", err))
+		return
+	}
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sdNotify(logger, daemon.SdNotifyWatchdog)
+			}
+		}
+	}()
+}
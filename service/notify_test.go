@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSystemdNotifications(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", "100000")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+	defer cancel()
+	if err = Run(ctx, WithListener(ln)); err != nil {
+		t.Fatal(err)
+	}
+
+	var states []string
+	buffer := make([]byte, 256)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Millisecond * 50))
+		n, err := conn.Read(buffer)
+		if err != nil {
+			break
+		}
+		states = append(states, string(buffer[:n]))
+	}
+	notified := strings.Join(states, ",")
+	if !strings.HasPrefix(notified, "READY=1,WATCHDOG=1") || !strings.Contains(notified, "STOPPING=1") {
+		t.Fatal("unexpected notifications:", notified)
+	}
+}
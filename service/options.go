@@ -2,26 +2,44 @@ package service
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"time"
+
+	"golang.org/x/crypto/acme/autocert"
 )
 
+// ContextFactory creates the base context for requests arriving
+// through a listener. The listener is <nil> for HTTP/3 requests.
 type ContextFactory func(net.Listener) context.Context
 
 type options struct {
-	TLSCertificateFile string
-	TLSKeyFile         string
+	TLSConfig          *tls.Config
+	CertificateManager *CertificateManager
 	ReadTimeout        time.Duration
 	ReadHeaderTimeout  time.Duration
 	WriteTimeout       time.Duration
 	IdleTimeout        time.Duration
 	MaxHeaderBytes     int
 	Logger             *slog.Logger
-	Listener           net.Listener
+	Listeners          []*listener
+	UnencryptedHTTP2   bool
+	HTTP2              *http.HTTP2Config
+	HTTP3              *http3Listener
+	ShutdownTimeout    time.Duration
+	DrainDuration      time.Duration
+	GracefulRestart    bool
+	RestartTimeout     time.Duration
+	RestartArguments   []string
+	Readiness          *Readiness
+	OnStart            []Hook
+	OnShutdown         []Hook
+	RegisterOnShutdown []func()
+	ACME               *autocert.Manager
 	ContextFactory     ContextFactory
 	Handler            http.Handler
 }
@@ -35,7 +53,7 @@ func WithDebugOptions() Option {
 				return err
 			}
 		}
-		if o.Listener == nil {
+		if !o.hasMainListener() {
 			if err = WithAddress("localhost", 8080)(o); err != nil {
 				return err
 			}
@@ -72,13 +90,28 @@ func WithDefaultOptions() Option {
 				return err
 			}
 		}
+		if o.ShutdownTimeout == 0 {
+			if err = WithShutdownTimeout(time.Second * 5)(o); err != nil {
+				return err
+			}
+		}
+		if o.GracefulRestart && o.RestartTimeout == 0 {
+			if err = WithGracefulRestartTimeout(DefaultGracefulRestartTimeout)(o); err != nil {
+				return err
+			}
+		}
+		if o.Readiness == nil {
+			if err = WithReadiness(&Readiness{})(o); err != nil {
+				return err
+			}
+		}
 		if o.MaxHeaderBytes == 0 {
 			if err = WithMaxHeaderBytes(1 << 6)(o); err != nil {
 				return err
 			}
 		}
-		if o.Listener == nil {
-			if o.TLSCertificateFile != "" {
+		if !o.hasMainListener() {
+			if o.TLSConfig != nil {
 				if err = WithAddress("", 443)(o); err != nil {
 					return err
 				}
@@ -198,76 +231,101 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
-func WithListener(l net.Listener) Option {
+func WithContextFactory(f ContextFactory) Option {
 	return func(o *options) error {
-		if o.Listener != nil {
-			return errors.New("address is already set")
+		if o.ContextFactory != nil {
+			return errors.New("context factory is already set")
 		}
-		if l == nil {
-			return errors.New("cannot use a <nil> network listener")
+		if f == nil {
+			return errors.New("cannot use a <nil> context factory")
 		}
-		o.Listener = l
+		o.ContextFactory = f
 		return nil
 	}
 }
 
-func WithAddress(host string, port uint32) Option {
-	return func(o *options) (err error) {
-		if port < 1 {
-			return errors.New("cannot use port lower than 1")
-		}
-		address := fmt.Sprintf("%s:%d", host, port)
-		listener, err := net.Listen("tcp", address)
-		if err != nil {
-			return fmt.Errorf("cannot bind listener to %q address: %w", address, err)
+// WithDefaultContextFactory gives requests a background base context.
+// Trace identifiers come from the traceid middleware instead.
+func WithDefaultContextFactory() Option {
+	return func(o *options) error {
+		if o.ContextFactory == nil {
+			o.ContextFactory = func(_ net.Listener) context.Context {
+				return context.Background()
+			}
 		}
-		return WithListener(listener)(o)
+		return nil
 	}
 }
 
-func WithContextFactory(f ContextFactory) Option {
-	return func(o *options) error {
-		if o.ContextFactory != nil {
-			return errors.New("context factory is already set")
+// WithTLS serves a certificate from files, which are reloaded
+// when they change. Repeat the option to serve several
+// certificates selected by Server Name Indication.
+func WithTLS(certificateFile, keyFile string) Option {
+	return func(o *options) (err error) {
+		if o.CertificateManager != nil {
+			return o.CertificateManager.Add(certificateFile, keyFile)
 		}
-		if f == nil {
-			return errors.New("cannot use a <nil> context factory")
+		if o.TLSConfig != nil {
+			return errors.New("TLS option is already set")
 		}
-		o.ContextFactory = f
+		o.CertificateManager, err = NewCertificateManager(certificateFile, keyFile)
+		if err != nil {
+			return err
+		}
+		o.TLSConfig = o.CertificateManager.TLSConfig()
 		return nil
 	}
 }
 
-func WithTraceIDGenerator(generator func() string) Option {
+// WithTLSConfig serves TLS using the given configuration,
+// which must provide certificates.
+func WithTLSConfig(c *tls.Config) Option {
 	return func(o *options) error {
-		if generator == nil {
-			return errors.New("cannot use a <nil> trace id generator")
+		if c == nil {
+			return errors.New("cannot use a <nil> TLS configuration")
 		}
-		return WithContextFactory(func(_ net.Listener) context.Context {
-			return ContextWithTraceIDGenerator(context.Background(), generator)
-		})(o)
+		if o.TLSConfig != nil {
+			return errors.New("TLS option is already set")
+		}
+		if len(c.Certificates) == 0 && c.GetCertificate == nil && c.GetConfigForClient == nil {
+			return errors.New("TLS configuration does not provide certificates")
+		}
+		o.TLSConfig = c
+		return nil
 	}
 }
 
-func WithDefaultTraceIDGenerator() Option {
+// WithACME obtains and renews certificates automatically using
+// the ACME protocol. The manager answers TLS-ALPN-01 challenges
+// on its own. HTTP-01 challenges require mounting
+// [autocert.Manager.HTTPHandler] on port 80, which [WithHTTPSRedirect]
+// does automatically. See [NewACMEManager].
+func WithACME(m *autocert.Manager) Option {
 	return func(o *options) error {
-		if o.ContextFactory == nil {
-			return WithTraceIDGenerator(func() string {
-				return FastRandom(8)
-			})(o)
+		if m == nil {
+			return errors.New("cannot use a <nil> ACME certificate manager")
+		}
+		if err := WithTLSConfig(m.TLSConfig())(o); err != nil {
+			return err
 		}
+		o.ACME = m
 		return nil
 	}
 }
 
-func WithTLS(certificateFile, keyFile string) Option {
+// WithSelfSignedCertificate serves TLS using a certificate
+// generated on startup for local development.
+// See [NewSelfSignedCertificate].
+func WithSelfSignedCertificate(hosts ...string) Option {
 	return func(o *options) error {
-		if o.TLSCertificateFile != "" {
-			return errors.New("TLS option is already set")
+		certificate, err := NewSelfSignedCertificate(hosts...)
+		if err != nil {
+			return fmt.Errorf("cannot generate self-signed certificate: %w", err)
 		}
-		o.TLSCertificateFile = certificateFile
-		o.TLSKeyFile = keyFile
-		return nil
+		return WithTLSConfig(&tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{*certificate},
+		})(o)
 	}
 }
 
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// HTTP3Server serves HTTP/3 requests over QUIC. The service package
+// does not implement QUIC. Wrap an implementation, such as the quic-go
+// http3.Server, by assigning the handler and TLS configuration to it
+// and then serving the packet connection.
+type HTTP3Server interface {
+	ServeHTTP3(net.PacketConn, http.Handler, *tls.Config) error
+	Shutdown(context.Context) error
+}
+
+type http3Listener struct {
+	Server HTTP3Server
+	Conn   net.PacketConn
+}
+
+// WithH2C enables HTTP/2 over cleartext connections, which is
+// useful for internal traffic behind a load balancer that
+// terminates TLS. Do not expose h2c servers to the Internet.
+func WithH2C() Option {
+	return func(o *options) error {
+		if o.UnencryptedHTTP2 {
+			return errors.New("HTTP/2 cleartext is already enabled")
+		}
+		o.UnencryptedHTTP2 = true
+		return nil
+	}
+}
+
+// WithHTTP2Config tunes HTTP/2 settings, such as the maximum
+// number of concurrent streams and the frame size.
+func WithHTTP2Config(c *http.HTTP2Config) Option {
+	return func(o *options) error {
+		if c == nil {
+			return errors.New("cannot use a <nil> HTTP/2 configuration")
+		}
+		if o.HTTP2 != nil {
+			return errors.New("HTTP/2 configuration is already set")
+		}
+		if c.MaxConcurrentStreams < 0 {
+			return errors.New("maximum concurrent streams cannot be negative")
+		}
+		// RFC 9113 section 4.2
+		if size := c.MaxReadFrameSize; size != 0 && (size < 1<<14 || size > 1<<24-1) {
+			return errors.New("maximum read frame size must be between 16KiB and 16MiB")
+		}
+		o.HTTP2 = c
+		return nil
+	}
+}
+
+// WithHTTP3 serves the main handler over HTTP/3 on a UDP port
+// and advertises it to clients of TLS listeners using Alt-Svc
+// header. Requires TLS. The packet connection is handed off
+// by [WithGracefulRestart].
+func WithHTTP3(server HTTP3Server, host string, port uint32) Option {
+	return func(o *options) error {
+		if server == nil {
+			return errors.New("cannot use a <nil> HTTP/3 server")
+		}
+		if o.HTTP3 != nil {
+			return errors.New("HTTP/3 server is already set")
+		}
+		if port < 1 {
+			return errors.New("cannot use port lower than 1")
+		}
+		address := net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10))
+		conn := takeInheritedPacketConn("udp", address)
+		if conn == nil {
+			var err error
+			if conn, err = net.ListenPacket("udp", address); err != nil {
+				return fmt.Errorf("cannot bind HTTP/3 packet connection to %q address: %w", address, err)
+			}
+		}
+		o.HTTP3 = &http3Listener{Server: server, Conn: conn}
+		return nil
+	}
+}
+
+// redirectProtocols limits HTTPS redirect listeners to HTTP/1,
+// because clients are only expected to follow the redirect.
+func redirectProtocols() *http.Protocols {
+	p := &http.Protocols{}
+	p.SetHTTP1(true)
+	return p
+}
+
+func (o *options) protocols() *http.Protocols {
+	if !o.UnencryptedHTTP2 {
+		return nil // use defaults
+	}
+	p := &http.Protocols{}
+	p.SetHTTP1(true)
+	p.SetHTTP2(true)
+	p.SetUnencryptedHTTP2(true)
+	return p
+}
+
+// altSvc advertises HTTP/3 to clients that connected using TLS.
+type altSvc struct {
+	next  http.Handler
+	value string
+}
+
+func newAltSvc(next http.Handler, conn net.PacketConn) http.Handler {
+	port := 443
+	if address, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		port = address.Port
+	}
+	return &altSvc{
+		next:  next,
+		value: `h3=":` + strconv.Itoa(port) + `"; ma=86400`,
+	}
+}
+
+func (a *altSvc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.TLS != nil {
+		w.Header().Set("Alt-Svc", a.value)
+	}
+	a.next.ServeHTTP(w, r)
+}
+
+// baseContext carries the values of the [ContextFactory] context
+// into HTTP/3 requests, because packet connections are not
+// [net.Listener]s. The base is created once for the packet
+// connection, just like for every other listener.
+type baseContext struct {
+	context.Context
+	base context.Context
+}
+
+func (b *baseContext) Value(key any) any {
+	if value := b.Context.Value(key); value != nil {
+		return value
+	}
+	return b.base.Value(key)
+}
+
+func withBaseContext(next http.Handler, base context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(&baseContext{
+			Context: r.Context(),
+			base:    base,
+		}))
+	})
+}
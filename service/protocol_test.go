@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testHTTP3Server struct {
+	handler chan http.Handler
+	done    chan struct{}
+}
+
+func (s *testHTTP3Server) ServeHTTP3(_ net.PacketConn, h http.Handler, c *tls.Config) error {
+	if c == nil {
+		return io.ErrUnexpectedEOF
+	}
+	s.handler <- h
+	<-s.done
+	return http.ErrServerClosed
+}
+
+func (s *testHTTP3Server) Shutdown(context.Context) error {
+	close(s.done)
+	return nil
+}
+
+func TestH2C(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirect, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = Run(
+			ctx,
+			WithListener(ln),
+			func(o *options) error { // same as WithHTTPSRedirect
+				o.Listeners = append(o.Listeners, &listener{Listener: redirect, Redirect: true})
+				return nil
+			},
+			WithH2C(),
+			WithHTTP2Config(&http.HTTP2Config{
+				MaxConcurrentStreams: 10,
+				MaxReadFrameSize:     1 << 20,
+			}),
+			WithHandler(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					_, _ = io.WriteString(w, r.Proto)
+				},
+			)),
+		)
+	}()
+
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	response, err := client.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "HTTP/2.0" {
+		t.Fatal("request was not served using HTTP/2:", string(body))
+	}
+	if response, err = client.Get("http://" + redirect.Addr().String()); err == nil {
+		_ = response.Body.Close()
+		t.Fatal("redirect listener accepted HTTP/2 cleartext")
+	}
+
+	if err = WithHTTP2Config(&http.HTTP2Config{MaxReadFrameSize: 1})(&options{}); err == nil {
+		t.Fatal("accepted invalid frame size")
+	}
+}
+
+type testContextKey struct{}
+
+func TestHTTP3(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := uint32(udp.LocalAddr().(*net.UDPAddr).Port)
+	if err = udp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var calls atomic.Int32 // of the context factory
+	h3 := &testHTTP3Server{
+		handler: make(chan http.Handler, 1),
+		done:    make(chan struct{}),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Run(
+			ctx,
+			WithListener(ln),
+			WithSelfSignedCertificate(),
+			WithHTTP3(h3, "127.0.0.1", port),
+			WithContextFactory(func(net.Listener) context.Context {
+				calls.Add(1)
+				return context.WithValue(context.Background(), testContextKey{}, "trace")
+			}),
+			WithHandler(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					value, _ := r.Context().Value(testContextKey{}).(string)
+					_, _ = io.WriteString(w, value)
+				},
+			)),
+		)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	response, err := client.Get("https://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+	if altSvc := response.Header.Get("Alt-Svc"); !strings.HasPrefix(altSvc, fmt.Sprintf(`h3=":%d"`, port)) {
+		t.Fatalf("HTTP/3 is not advertised: %q", altSvc)
+	}
+
+	handler := <-h3.handler
+	before := calls.Load()
+	for range 3 {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if trace := w.Body.String(); trace != "trace" {
+			t.Fatalf("HTTP/3 handler does not share the context factory: %q", trace)
+		}
+	}
+	if after := calls.Load(); after != before {
+		t.Fatalf("context factory was called %d times for HTTP/3 requests", after-before)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("HTTP/3 server did not shut down")
+	}
+}
@@ -0,0 +1,48 @@
+//go:build !unix
+
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// DefaultGracefulRestartTimeout limits how long the running process
+// waits for its replacement to become ready.
+const DefaultGracefulRestartTimeout = time.Second * 30
+
+// WithGracefulRestart is only supported on Unix systems.
+func WithGracefulRestart() Option {
+	return func(o *options) error {
+		return errors.New("graceful restart is not supported on this platform")
+	}
+}
+
+// WithGracefulRestartTimeout is only supported on Unix systems.
+func WithGracefulRestartTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		return errors.New("graceful restart is not supported on this platform")
+	}
+}
+
+// WithGracefulRestartArguments is only supported on Unix systems.
+func WithGracefulRestartArguments(arguments ...string) Option {
+	return func(o *options) error {
+		return errors.New("graceful restart is not supported on this platform")
+	}
+}
+
+func takeInheritedListener(network, address string) net.Listener { return nil }
+
+func takeInheritedPacketConn(network, address string) net.PacketConn { return nil }
+
+func closeUnclaimedInheritedListeners(logger *slog.Logger) {}
+
+func signalInheritedReady() error { return nil }
+
+func (o *options) watchRestartSignals(ctx context.Context, cancel context.CancelCauseFunc, logger *slog.Logger) (handedOff func() bool) {
+	return func() bool { return false }
+}
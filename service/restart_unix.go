@@ -0,0 +1,401 @@
+//go:build unix
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/coreos/go-systemd/daemon"
+)
+
+const (
+	// ListenerFDsEnvironmentVariable tells the restarted process how many
+	// listener file descriptors it inherited, starting with descriptor 3.
+	ListenerFDsEnvironmentVariable = "HTADAPTOR_LISTENER_FDS"
+
+	// PacketConnFDsEnvironmentVariable tells the restarted process how
+	// many packet connections, used by [WithHTTP3], it inherited right
+	// after the listener file descriptors.
+	PacketConnFDsEnvironmentVariable = "HTADAPTOR_PACKET_CONN_FDS"
+
+	// ReadyFDEnvironmentVariable names the pipe descriptor that
+	// the restarted process closes once all of its listeners are serving.
+	ReadyFDEnvironmentVariable = "HTADAPTOR_READY_FD"
+
+	firstInheritedFD = 3
+)
+
+// DefaultGracefulRestartTimeout limits how long the running process
+// waits for its replacement to become ready.
+const DefaultGracefulRestartTimeout = time.Second * 30
+
+// WithGracefulRestart replaces the running binary without dropping
+// connections. On SIGHUP or SIGUSR2, [Run] starts the executable
+// again, which may have been upgraded on disk, and hands it every
+// listener created by [WithAddress], [WithUnixSocket], and
+// [WithHTTPSRedirect], as well as the packet connection of
+// [WithHTTP3]. Once the new process reports that it serves
+// all listeners, the old process drains and exits. If the new process
+// fails to become ready within [DefaultGracefulRestartTimeout]
+// or [WithGracefulRestartTimeout], it is killed and the old process
+// keeps serving.
+//
+// Other listeners, such as the ones passed to [WithListener], cannot
+// be claimed by the new process and must not bind fixed addresses.
+// Under systemd, set NotifyAccess=all, so that the new process
+// can take over as the main process:
+//
+//	[Service]
+//	Type         = notify
+//	NotifyAccess = all
+//	ExecStart    = /bin/myapp
+//	ExecReload   = /bin/kill -HUP $MAINPID
+//	WatchdogSec  = 30
+func WithGracefulRestart() Option {
+	return func(o *options) error {
+		if o.GracefulRestart {
+			return errors.New("graceful restart is already set")
+		}
+		o.GracefulRestart = true
+		return nil
+	}
+}
+
+// WithGracefulRestartTimeout limits how long the running process
+// waits for its replacement to become ready.
+// Requires [WithGracefulRestart].
+func WithGracefulRestartTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if o.RestartTimeout != 0 {
+			return errors.New("graceful restart timeout is already set")
+		}
+		if d < time.Millisecond*100 {
+			return errors.New("cannot set graceful restart timeout lower than 100ms")
+		}
+		if d > time.Minute*10 {
+			return errors.New("cannot set graceful restart timeout above ten minutes")
+		}
+		o.RestartTimeout = d
+		return nil
+	}
+}
+
+// WithGracefulRestartArguments starts the replacement process with
+// the given command line arguments instead of os.Args[1:].
+// Requires [WithGracefulRestart].
+func WithGracefulRestartArguments(arguments ...string) Option {
+	return func(o *options) error {
+		if o.RestartArguments != nil {
+			return errors.New("graceful restart arguments are already set")
+		}
+		o.RestartArguments = append([]string{}, arguments...)
+		return nil
+	}
+}
+
+var inherited struct {
+	sync.Once
+	sync.Mutex
+	listeners   []net.Listener
+	packetConns []net.PacketConn
+	ready       *os.File
+}
+
+func loadInherited() {
+	defer func() {
+		_ = os.Unsetenv(ListenerFDsEnvironmentVariable)
+		_ = os.Unsetenv(PacketConnFDsEnvironmentVariable)
+		_ = os.Unsetenv(ReadyFDEnvironmentVariable)
+	}()
+	count, err := strconv.Atoi(os.Getenv(ListenerFDsEnvironmentVariable))
+	if err != nil || count < 1 {
+		return
+	}
+	for i := range count {
+		f := os.NewFile(uintptr(firstInheritedFD+i), "listener")
+		l, err := net.FileListener(f)
+		_ = f.Close() // listener holds a duplicate
+		if err == nil {
+			inherited.listeners = append(inherited.listeners, l)
+		}
+	}
+	packetConnCount, _ := strconv.Atoi(os.Getenv(PacketConnFDsEnvironmentVariable))
+	for i := range packetConnCount {
+		f := os.NewFile(uintptr(firstInheritedFD+count+i), "packet connection")
+		c, err := net.FilePacketConn(f)
+		_ = f.Close() // connection holds a duplicate
+		if err == nil {
+			inherited.packetConns = append(inherited.packetConns, c)
+		}
+	}
+	if fd, err := strconv.Atoi(os.Getenv(ReadyFDEnvironmentVariable)); err == nil && fd >= firstInheritedFD {
+		inherited.ready = os.NewFile(uintptr(fd), "ready")
+	}
+}
+
+// takeInheritedListener returns the listener that the previous
+// process handed over for the address, or <nil> if there is none.
+func takeInheritedListener(network, address string) net.Listener {
+	inherited.Do(loadInherited)
+	inherited.Lock()
+	defer inherited.Unlock()
+	for i, l := range inherited.listeners {
+		if addressMatches(l.Addr(), network, address) {
+			inherited.listeners = append(inherited.listeners[:i], inherited.listeners[i+1:]...)
+			return l
+		}
+	}
+	return nil
+}
+
+// takeInheritedPacketConn returns the packet connection that
+// the previous process handed over for the address, or <nil>
+// if there is none.
+func takeInheritedPacketConn(network, address string) net.PacketConn {
+	inherited.Do(loadInherited)
+	inherited.Lock()
+	defer inherited.Unlock()
+	for i, c := range inherited.packetConns {
+		if addressMatches(c.LocalAddr(), network, address) {
+			inherited.packetConns = append(inherited.packetConns[:i], inherited.packetConns[i+1:]...)
+			return c
+		}
+	}
+	return nil
+}
+
+func addressMatches(addr net.Addr, network, address string) bool {
+	var ip, requested net.IP
+	switch network {
+	case "unix":
+		unix, ok := addr.(*net.UnixAddr)
+		return ok && unix.Name == address
+	case "tcp":
+		tcp, ok := addr.(*net.TCPAddr)
+		if !ok {
+			return false
+		}
+		resolved, err := net.ResolveTCPAddr(network, address)
+		if err != nil || resolved.Port != tcp.Port {
+			return false
+		}
+		ip, requested = tcp.IP, resolved.IP
+	case "udp":
+		udp, ok := addr.(*net.UDPAddr)
+		if !ok {
+			return false
+		}
+		resolved, err := net.ResolveUDPAddr(network, address)
+		if err != nil || resolved.Port != udp.Port {
+			return false
+		}
+		ip, requested = udp.IP, resolved.IP
+	default:
+		return false
+	}
+	if requested == nil || requested.IsUnspecified() {
+		return ip.IsUnspecified()
+	}
+	return requested.Equal(ip)
+}
+
+// closeUnclaimedInheritedListeners releases handed over listeners
+// that the new configuration no longer uses.
+func closeUnclaimedInheritedListeners(logger *slog.Logger) {
+	inherited.Do(loadInherited)
+	inherited.Lock()
+	defer inherited.Unlock()
+	for _, l := range inherited.listeners {
+		logger.Warn("closing unclaimed inherited listener", slog.String("address", l.Addr().String()))
+		_ = l.Close()
+	}
+	inherited.listeners = nil
+	for _, c := range inherited.packetConns {
+		logger.Warn("closing unclaimed inherited packet connection", slog.String("address", c.LocalAddr().String()))
+		_ = c.Close()
+	}
+	inherited.packetConns = nil
+}
+
+// signalInheritedReady tells the previous process that
+// it can drain and exit.
+func signalInheritedReady() error {
+	inherited.Do(loadInherited)
+	inherited.Lock()
+	defer inherited.Unlock()
+	if inherited.ready == nil {
+		return nil
+	}
+	_, err := inherited.ready.Write([]byte{1})
+	err = errors.Join(err, inherited.ready.Close())
+	inherited.ready = nil
+	return err
+}
+
+// watchRestartSignals starts a replacement process on SIGHUP or
+// SIGUSR2 and cancels the context once the replacement is ready.
+// The returned function reports whether the listeners were
+// handed off.
+func (o *options) watchRestartSignals(ctx context.Context, cancel context.CancelCauseFunc, logger *slog.Logger) (handedOff func() bool) {
+	if !o.GracefulRestart {
+		return func() bool { return false }
+	}
+	var done sync.WaitGroup
+	var restarted bool
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGUSR2)
+	done.Go(func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case received := <-signals:
+				logger.Info("restarting service", slog.String("signal", received.String()))
+				sdNotify(logger, daemon.SdNotifyReloading)
+				pid, err := o.restart()
+				if err != nil {
+					logger.Error("cannot restart service", slog.Any("error", err))
+					sdNotify(logger, daemon.SdNotifyReady)
+					continue
+				}
+				logger.Info("listeners handed off", slog.Int("pid", pid))
+				sdNotify(logger, "MAINPID="+strconv.Itoa(pid)+"\n"+daemon.SdNotifyReady)
+				restarted = true
+				cancel(errGracefulRestart)
+				return
+			}
+		}
+	})
+	return func() bool {
+		done.Wait()
+		return restarted
+	}
+}
+
+// restart starts the executable with the listeners as extra files
+// and waits for it to close the readiness pipe.
+func (o *options) restart() (pid int, err error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("cannot locate executable: %w", err)
+	}
+	files := make([]*os.File, 0, len(o.Listeners)+2)
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, l := range o.Listeners {
+		source, ok := l.Listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			return 0, fmt.Errorf("cannot hand off %s listener at %s", l.Addr().Network(), l.Addr())
+		}
+		f, err := source.File()
+		if err != nil {
+			return 0, fmt.Errorf("cannot hand off %s listener at %s: %w", l.Addr().Network(), l.Addr(), err)
+		}
+		files = append(files, f)
+	}
+	listenerCount := len(files)
+	if o.HTTP3 != nil {
+		source, ok := o.HTTP3.Conn.(interface{ File() (*os.File, error) })
+		if !ok {
+			return 0, fmt.Errorf("cannot hand off HTTP/3 packet connection at %s", o.HTTP3.Conn.LocalAddr())
+		}
+		f, err := source.File()
+		if err != nil {
+			return 0, fmt.Errorf("cannot hand off HTTP/3 packet connection at %s: %w", o.HTTP3.Conn.LocalAddr(), err)
+		}
+		files = append(files, f)
+	}
+	packetConnCount := len(files) - listenerCount
+	ready, readyWriter, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("cannot create readiness pipe: %w", err)
+	}
+	defer ready.Close()
+	inheritedCount := len(files)
+	files = append(files, readyWriter)
+
+	arguments := os.Args[1:]
+	if o.RestartArguments != nil {
+		arguments = o.RestartArguments
+	}
+	cmd := exec.Command(executable, arguments...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(restartEnvironment(),
+		ListenerFDsEnvironmentVariable+"="+strconv.Itoa(listenerCount),
+		PacketConnFDsEnvironmentVariable+"="+strconv.Itoa(packetConnCount),
+		ReadyFDEnvironmentVariable+"="+strconv.Itoa(firstInheritedFD+inheritedCount),
+	)
+	if err = cmd.Start(); err != nil {
+		return 0, fmt.Errorf("cannot start %q: %w", executable, err)
+	}
+	_ = readyWriter.Close() // only the child holds the write end now
+	files = files[:inheritedCount]
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	signaled := make(chan error, 1)
+	go func() {
+		_, err := ready.Read(make([]byte, 1))
+		signaled <- err
+	}()
+
+	timeout := time.NewTimer(o.RestartTimeout)
+	defer timeout.Stop()
+	select {
+	case err = <-signaled:
+		if err == nil {
+			break
+		}
+		err = fmt.Errorf("new process closed readiness pipe: %w", err)
+	case err = <-exited:
+		err = fmt.Errorf("new process exited before becoming ready: %w", err)
+	case <-timeout.C:
+		err = errors.New("new process did not become ready in time")
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return 0, err
+	}
+
+	for _, l := range o.Listeners {
+		if unix, ok := l.Listener.(*net.UnixListener); ok {
+			unix.SetUnlinkOnClose(false) // new process serves the socket file
+		}
+	}
+	return cmd.Process.Pid, nil
+}
+
+// restartEnvironment drops the variables that bind the watchdog and
+// the inherited descriptors to the current process.
+func restartEnvironment() []string {
+	environment := os.Environ()
+	filtered := environment[:0]
+	for _, variable := range environment {
+		name, _, _ := strings.Cut(variable, "=")
+		switch name {
+		case ListenerFDsEnvironmentVariable, PacketConnFDsEnvironmentVariable,
+			ReadyFDEnvironmentVariable, "WATCHDOG_PID":
+			continue
+		}
+		filtered = append(filtered, variable)
+	}
+	return filtered
+}
@@ -0,0 +1,182 @@
+//go:build unix
+
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const (
+	restartTestPortEnvironmentVariable  = "HTADAPTOR_TEST_RESTART_PORT"
+	restartTestHTTP3EnvironmentVariable = "HTADAPTOR_TEST_RESTART_HTTP3_PORT"
+)
+
+func respondWith(body string) Option {
+	return WithHandler(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, body)
+		},
+	))
+}
+
+// withRestartTestHTTP3 serves HTTP/3 on the port from the environment,
+// if there is one.
+func withRestartTestHTTP3(t *testing.T) []Option {
+	port := os.Getenv(restartTestHTTP3EnvironmentVariable)
+	if port == "" {
+		return nil
+	}
+	n, err := strconv.ParseUint(port, 10, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []Option{
+		WithSelfSignedCertificate(),
+		WithHTTP3(&testHTTP3Server{
+			handler: make(chan http.Handler, 1),
+			done:    make(chan struct{}),
+		}, "127.0.0.1", uint32(n)),
+	}
+}
+
+func freePort(t *testing.T, network string) int {
+	t.Helper()
+	if network == "udp" {
+		c, err := net.ListenPacket(network, "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		return c.LocalAddr().(*net.UDPAddr).Port
+	}
+	ln, err := net.Listen(network, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestGracefulRestart(t *testing.T) {
+	if port := os.Getenv(restartTestPortEnvironmentVariable); port != "" {
+		if os.Getenv(ListenerFDsEnvironmentVariable) == "" {
+			t.Skip("not started by a graceful restart")
+		}
+		// replacement process started by the tests below
+		n, err := strconv.ParseUint(port, 10, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+		defer cancel()
+		if err = Run(ctx, append(
+			withRestartTestHTTP3(t),
+			WithAddress("127.0.0.1", uint32(n)),
+			respondWith("replacement"),
+		)...); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	testGracefulRestart(t, "http")
+}
+
+func TestGracefulRestartWithHTTP3(t *testing.T) {
+	if os.Getenv(restartTestPortEnvironmentVariable) != "" {
+		t.Skip("replacement process runs TestGracefulRestart")
+	}
+	t.Setenv(restartTestHTTP3EnvironmentVariable, strconv.Itoa(freePort(t, "udp")))
+	testGracefulRestart(t, "https")
+}
+
+func testGracefulRestart(t *testing.T, scheme string) {
+	port := freePort(t, "tcp")
+	t.Setenv(restartTestPortEnvironmentVariable, strconv.Itoa(port))
+	URL := scheme + "://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+	readiness := &Readiness{}
+	done := make(chan error)
+	go func() {
+		done <- Run(context.Background(), append(
+			withRestartTestHTTP3(t),
+			WithAddress("127.0.0.1", uint32(port)),
+			WithReadiness(readiness),
+			WithGracefulRestart(),
+			WithGracefulRestartArguments("-test.run=^TestGracefulRestart$"),
+			respondWith("original"),
+		)...)
+	}()
+	for deadline := time.Now().Add(time.Second); !readiness.IsReady(); {
+		if time.Now().After(deadline) {
+			t.Fatal("service did not become ready")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if body := get(t, URL); body != "original" {
+		t.Fatal("unexpected response:", body)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second * 10):
+		t.Fatal("service did not hand off its listeners")
+	}
+	if body := get(t, URL); body != "replacement" {
+		t.Fatal("listener was not handed off:", body)
+	}
+}
+
+func get(t *testing.T, URL string) string {
+	t.Helper()
+	client := &http.Client{Transport: &http.Transport{
+		DisableKeepAlives: true,
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+	}}
+	response, err := client.Get(URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestAddressMatches(t *testing.T) {
+	for _, c := range []struct {
+		Addr    net.Addr
+		Network string
+		Address string
+		Matches bool
+	}{
+		{&net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "tcp", ":8080", true},
+		{&net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}, "tcp", ":8081", false},
+		{&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 80}, "tcp", "127.0.0.1:80", true},
+		{&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 80}, "tcp", ":80", false},
+		{&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}, "udp", "127.0.0.1:443", true},
+		{&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}, "tcp", "127.0.0.1:443", false},
+		{&net.UnixAddr{Name: "/run/app.sock", Net: "unix"}, "unix", "/run/app.sock", true},
+		{&net.UnixAddr{Name: "/run/app.sock", Net: "unix"}, "tcp", "/run/app.sock", false},
+	} {
+		if addressMatches(c.Addr, c.Network, c.Address) != c.Matches {
+			t.Fatalf("%s %s matching %s should be %t", c.Network, c.Address, c.Addr, c.Matches)
+		}
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+
+	"github.com/coreos/go-systemd/daemon"
 )
 
 func Run(ctx context.Context, withOptions ...Option) (err error) {
@@ -29,71 +31,168 @@ func Run(ctx context.Context, withOptions ...Option) (err error) {
 	for _, option := range append(
 		withOptions,
 		WithDefaultOptions(),
-		WithDefaultTraceIDGenerator(),
+		WithDefaultContextFactory(),
 		func(o *options) error { // validate
-			if o.Listener == nil {
+			if len(o.Listeners) == 0 {
 				return errors.New("cannot start a server without a network listener")
 			}
+			if o.HTTP3 != nil && o.TLSConfig == nil {
+				return errors.New("HTTP/3 requires TLS")
+			}
+			if !o.GracefulRestart && (o.RestartTimeout != 0 || o.RestartArguments != nil) {
+				return errors.New("graceful restart timeout and arguments require graceful restart")
+			}
 			return nil
 		},
 	) {
 		if err = option(o); err != nil {
-			return fmt.Errorf("cannot create an HTTP service: %w", err)
+			return errors.Join(
+				fmt.Errorf("cannot create an HTTP service: %w", err),
+				o.closeListeners(),
+			)
 		}
 	}
-	ln := o.Listener
-	defer func() {
-		if err := ln.Close(); err != nil {
-			o.Logger.Error("failed closing network listener", slog.Any("error", err))
-		}
-	}()
 
-	handler := o.Handler
-	contextFactory := o.ContextFactory
 	logger := o.Logger
+	closeUnclaimedInheritedListeners(logger)
+	for _, hook := range o.OnStart {
+		if err = hook(ctx); err != nil {
+			return errors.Join(
+				fmt.Errorf("start hook failed: %w", err),
+				o.closeListeners(),
+			)
+		}
+	}
+	if o.CertificateManager != nil {
+		go o.CertificateManager.Watch(ctx, DefaultCertificateReloadInterval, logger)
+	}
+
+	shutdownSignal := make(chan struct{})
+	baseContext := o.baseContext(shutdownSignal)
+	mainHandler := o.Handler
+	servers := make([]*http.Server, len(o.Listeners))
+	serveErrors := make(chan error, len(o.Listeners)+1)
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if o.HTTP3 != nil {
+		go func(h3 *http3Listener, handler http.Handler) {
+			err := h3.Server.ServeHTTP3(h3.Conn, handler, o.TLSConfig)
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				err = fmt.Errorf("cannot serve HTTP/3 at %s: %w", h3.Conn.LocalAddr(), err)
+				cancel(err)
+			}
+			serveErrors <- err
+		}(o.HTTP3, withBaseContext(mainHandler, baseContext(nil)))
+		mainHandler = newAltSvc(mainHandler, o.HTTP3.Conn)
+	}
+	for i, l := range o.Listeners {
+		handler := l.Handler
+		if l.Redirect {
+			handler = o.newSecureRedirect()
+		} else if handler == nil {
+			handler = mainHandler
+		}
+		server := o.newServer(handler, baseContext)
+		if l.Redirect {
+			server.Protocols = redirectProtocols()
+		}
+		servers[i] = server
+
+		go func(l *listener) {
+			var err error
+			if o.TLSConfig != nil && !l.Redirect {
+				err = server.ServeTLS(l, "", "")
+			} else {
+				err = server.Serve(l)
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				err = fmt.Errorf("cannot serve %s listener at %s: %w", l.Addr().Network(), l.Addr(), err)
+				cancel(err) // stop the other listeners
+			}
+			serveErrors <- err
+		}(l)
+	}
+	o.Readiness.set(true)
+	if err = signalInheritedReady(); err != nil {
+		logger.Error("cannot signal readiness to the previous process", slog.Any("error", err))
+		err = nil
+	}
+	sdNotify(logger, daemon.SdNotifyReady)
+	watchdogCtx, stopWatchdog := context.WithCancel(context.Background())
+	defer stopWatchdog()
+	startWatchdog(watchdogCtx, logger)
+	handedOff := o.watchRestartSignals(ctx, cancel, logger)
+
+	<-ctx.Done()
+	o.Readiness.set(false)
+	if !handedOff() {
+		sdNotify(logger, daemon.SdNotifyStopping)
+	}
+	close(shutdownSignal)
+	if o.DrainDuration > 0 {
+		logger.Info("draining connections", slog.Duration("duration", o.DrainDuration))
+		time.Sleep(o.DrainDuration)
+	}
+
+	timeoutCtx, cancelTimeout := context.WithTimeout(context.Background(), o.ShutdownTimeout)
+	defer cancelTimeout()
+	for _, server := range servers {
+		if shutdownError := server.Shutdown(timeoutCtx); shutdownError != nil {
+			err = errors.Join(err, fmt.Errorf("cannot shut down HTTP server: %w", shutdownError))
+		}
+	}
+	pending := len(servers)
+	if o.HTTP3 != nil {
+		if shutdownError := o.HTTP3.Server.Shutdown(timeoutCtx); shutdownError != nil {
+			err = errors.Join(err, fmt.Errorf("cannot shut down HTTP/3 server: %w", shutdownError))
+		}
+		_ = o.HTTP3.Conn.Close() // unblock servers that ignore shutdown
+		pending++
+	}
+	for range pending {
+		if serveError := <-serveErrors; serveError != nil && !errors.Is(serveError, http.ErrServerClosed) {
+			err = errors.Join(err, serveError)
+		}
+	}
+
+	hookCtx, cancelHooks := context.WithTimeout(context.Background(), o.ShutdownTimeout)
+	defer cancelHooks()
+	for i := len(o.OnShutdown) - 1; i >= 0; i-- {
+		if hookError := o.OnShutdown[i](hookCtx); hookError != nil {
+			err = errors.Join(err, fmt.Errorf("shutdown hook failed: %w", hookError))
+		}
+	}
+
+	if cause := context.Cause(ctx); err == nil && !isOrdinaryCancellation(cause) {
+		err = cause
+	}
+	if err != nil {
+		logger.Error("service shutdown", slog.Any("reason", err))
+		return err
+	}
+	logger.Info("service shutdown")
+	return nil
+}
+
+func (o *options) newServer(handler http.Handler, baseContext ContextFactory) *http.Server {
 	server := &http.Server{
-		// Addr:              o.Address,
 		ReadTimeout:       o.ReadTimeout,
 		ReadHeaderTimeout: o.ReadHeaderTimeout,
 		WriteTimeout:      o.WriteTimeout,
 		IdleTimeout:       o.IdleTimeout,
 		MaxHeaderBytes:    o.MaxHeaderBytes,
 		Handler:           handler,
-		BaseContext:       contextFactory,
+		BaseContext:       baseContext,
+		TLSConfig:         o.TLSConfig,
+		Protocols:         o.protocols(),
+		HTTP2:             o.HTTP2,
 		ErrorLog: log.New(&slogAdaptor{
 			level:  slog.LevelDebug,
-			logger: logger,
+			logger: o.Logger,
 		}, "HTTP: ", log.LstdFlags),
-		// TLSConfig *tls.Config // TODO: use Filippo's defaults?
 	}
-
-	go func(ctx context.Context, logger *slog.Logger) {
-		<-ctx.Done()
-		timeoutCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		// see join below
-		// if err := context.Cause(ctx); err != nil {
-		// 	logger.Error("context cancelled", slog.Any("reason", err))
-		// }
-		if err := server.Shutdown(timeoutCtx); err != nil {
-			logger.Error("error shutting down HTTP service", slog.Any("error", err))
-		}
-	}(ctx, logger)
-
-	if o.TLSCertificateFile != "" {
-		// err = server.ListenAndServeTLS(o.TLSCertificateFile, o.TLSKeyFile)
-		err = server.ServeTLS(ln, o.TLSCertificateFile, o.TLSKeyFile)
-	} else {
-		// if strings.HasSuffix(o.Address, ":443") || strings.HasSuffix(o.Address, ":8443") {
-		// 	return errors.New("must not expose a TLS server without its certificate file set")
-		// }
-		err = server.Serve(ln)
+	for _, f := range o.RegisterOnShutdown {
+		server.RegisterOnShutdown(f)
 	}
-
-	err = errors.Join(err, context.Cause(ctx))
-	if err != nil {
-		logger.Error("service shutdown", slog.Any("reason", err)) // handle
-	}
-	return nil
+	return server
 }
@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"net"
 
 	"github.com/coreos/go-systemd/activation"
 )
@@ -39,24 +40,59 @@ import (
 // sudo systemctl start myapp.socket
 // sudo systemctl status myapp.socket
 // sudo systemctl restart myapp.socket
+//
+// Extra sockets are closed. Use [WithSystemDSocketActivation]
+// to serve all of them.
 func WithFirstSystemDSocketActivationSocket() Option {
 	return func(o *options) error {
-		if o.Listener != nil {
-			return errors.New("server address is already set")
-		}
-		listeners, err := activation.Listeners()
+		listeners, err := systemdListeners()
 		if err != nil {
-			return fmt.Errorf("could not access systemd network listeners: %w", err)
+			return err
 		}
-		if len(listeners) == 0 {
-			return errors.New("systemd service has no associated network listeners")
+		for _, extra := range listeners[1:] {
+			if err = extra.Close(); err != nil {
+				return fmt.Errorf("could not close extra systemd socket: %w", err)
+			}
 		}
+		return WithListener(listeners[0])(o)
+	}
+}
 
-		o.Listener = listeners[0]
+// WithSystemDSocketActivation serves the main handler on every
+// stream socket passed by systemd. See
+// [WithFirstSystemDSocketActivationSocket] for configuration example.
+func WithSystemDSocketActivation() Option {
+	return func(o *options) error {
+		listeners, err := systemdListeners()
+		if err != nil {
+			return err
+		}
+		for _, l := range listeners {
+			if err = WithListener(l)(o); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 }
 
+func systemdListeners() ([]net.Listener, error) {
+	all, err := activation.Listeners()
+	if err != nil {
+		return nil, fmt.Errorf("could not access systemd network listeners: %w", err)
+	}
+	listeners := make([]net.Listener, 0, len(all))
+	for _, l := range all {
+		if l != nil { // datagram sockets are <nil>
+			listeners = append(listeners, l)
+		}
+	}
+	if len(listeners) == 0 {
+		return nil, errors.New("systemd service has no associated network listeners")
+	}
+	return listeners, nil
+}
+
 // // TODO: below seems to produce a list of listeners by file name, probably not useful differentiation.
 // func WithSystemDSocketActivationSocket(name string) Option {
 // 	return func(o *options) error {
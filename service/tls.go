@@ -0,0 +1,198 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// NewSelfSignedCertificate generates a certificate for local
+// development. Uses "localhost", "127.0.0.1", and "::1" when
+// no hosts are given. Browsers will warn about it, because it
+// is not signed by a trusted authority.
+func NewSelfSignedCertificate(hosts ...string) (*tls.Certificate, error) {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Development"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour * 24 * 30),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+type acmeOptions struct {
+	Hosts        []string
+	Email        string
+	Cache        autocert.Cache
+	DirectoryURL string
+	HTTPClient   *http.Client
+}
+
+type ACMEOption func(*acmeOptions) error
+
+// WithACMEHosts restricts certificate issuance to the given
+// host names. Required, because otherwise anyone could make
+// the service request certificates for arbitrary names.
+func WithACMEHosts(hosts ...string) ACMEOption {
+	return func(o *acmeOptions) error {
+		if len(hosts) == 0 {
+			return errors.New("provide at least one host name")
+		}
+		for _, host := range hosts {
+			if host == "" {
+				return errors.New("cannot use an empty host name")
+			}
+		}
+		o.Hosts = append(o.Hosts, hosts...)
+		return nil
+	}
+}
+
+func WithACMEEmail(email string) ACMEOption {
+	return func(o *acmeOptions) error {
+		if email == "" {
+			return errors.New("cannot use an empty contact email")
+		}
+		if o.Email != "" {
+			return errors.New("contact email is already set")
+		}
+		o.Email = email
+		return nil
+	}
+}
+
+// WithACMECacheDirectory stores account keys and certificates
+// in a directory, so that they survive restarts. Without it,
+// certificates are requested again on every start, which
+// quickly exhausts certificate authority rate limits.
+func WithACMECacheDirectory(p string) ACMEOption {
+	return func(o *acmeOptions) error {
+		if p == "" {
+			return errors.New("cannot use an empty cache directory path")
+		}
+		if o.Cache != nil {
+			return errors.New("certificate cache is already set")
+		}
+		o.Cache = autocert.DirCache(p)
+		return nil
+	}
+}
+
+// WithACMEDirectoryURL points the client to a certificate authority
+// other than Let's Encrypt, such as a local Pebble test server.
+func WithACMEDirectoryURL(URL string) ACMEOption {
+	return func(o *acmeOptions) error {
+		if URL == "" {
+			return errors.New("cannot use an empty directory URL")
+		}
+		if o.DirectoryURL != "" {
+			return errors.New("directory URL is already set")
+		}
+		o.DirectoryURL = URL
+		return nil
+	}
+}
+
+// WithACMEHTTPClient sets the client for talking to the certificate
+// authority. Useful for trusting the root certificate of a test
+// server.
+func WithACMEHTTPClient(c *http.Client) ACMEOption {
+	return func(o *acmeOptions) error {
+		if c == nil {
+			return errors.New("cannot use a <nil> HTTP client")
+		}
+		if o.HTTPClient != nil {
+			return errors.New("HTTP client is already set")
+		}
+		o.HTTPClient = c
+		return nil
+	}
+}
+
+func WithDefaultACMEDirectoryURL() ACMEOption {
+	return func(o *acmeOptions) error {
+		if o.DirectoryURL != "" {
+			return nil
+		}
+		o.DirectoryURL = autocert.DefaultACMEDirectory
+		return nil
+	}
+}
+
+// NewACMEManager creates a certificate manager that obtains
+// certificates automatically. Mount [autocert.Manager.HTTPHandler]
+// on port 80 to answer HTTP-01 challenges.
+func NewACMEManager(withOptions ...ACMEOption) (*autocert.Manager, error) {
+	o := &acmeOptions{}
+	var err error
+	for _, option := range append(
+		withOptions,
+		WithDefaultACMEDirectoryURL(),
+		func(o *acmeOptions) error {
+			if len(o.Hosts) == 0 {
+				return errors.New("at least one host name is required")
+			}
+			return nil
+		},
+	) {
+		if err = option(o); err != nil {
+			return nil, fmt.Errorf("cannot create ACME certificate manager: %w", err)
+		}
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(o.Hosts...),
+		Email:      o.Email,
+		Cache:      o.Cache,
+		Client: &acme.Client{
+			DirectoryURL: o.DirectoryURL,
+			HTTPClient:   o.HTTPClient,
+		},
+	}, nil
+}
@@ -0,0 +1,85 @@
+package staticfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Content codings recognized in the Accept-Encoding header.
+const (
+	EncodingBrotli    = "br"
+	EncodingZstandard = "zstd"
+	EncodingGzip      = "gzip"
+)
+
+// precompressed lists sibling file extensions of precompressed
+// assets in the order of server preference.
+var precompressed = []struct {
+	Encoding  string
+	Extension string
+}{
+	{Encoding: EncodingBrotli, Extension: ".br"},
+	{Encoding: EncodingZstandard, Extension: ".zst"},
+	{Encoding: EncodingGzip, Extension: ".gz"},
+}
+
+// Compressor encodes file contents using a content coding.
+type Compressor func([]byte) ([]byte, error)
+
+// CompressGzip is the default [Compressor] of [NewFastFileSystem].
+func CompressGzip(b []byte) ([]byte, error) {
+	out := &bytes.Buffer{}
+	w, err := gzip.NewWriterLevel(out, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(b); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// acceptedEncodings parses the Accept-Encoding header into
+// content codings and their quality values.
+func acceptedEncodings(r *http.Request) map[string]float64 {
+	header := r.Header.Values("Accept-Encoding")
+	if len(header) == 0 {
+		return nil
+	}
+	accepted := make(map[string]float64)
+	for _, line := range header {
+		for _, entry := range strings.Split(line, ",") {
+			coding, parameters, _ := strings.Cut(entry, ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding == "" {
+				continue
+			}
+			quality := 1.0
+			if parameter, ok := strings.CutPrefix(strings.TrimSpace(parameters), "q="); ok {
+				if q, err := strconv.ParseFloat(parameter, 64); err == nil {
+					quality = q
+				}
+			}
+			accepted[coding] = quality
+		}
+	}
+	return accepted
+}
+
+// accepts reports whether the client allows the content coding.
+// An explicit entry takes precedence over the "*" wildcard.
+func accepts(accepted map[string]float64, encoding string) bool {
+	if q, ok := accepted[encoding]; ok {
+		return q > 0
+	}
+	if q, ok := accepted["*"]; ok {
+		return q > 0
+	}
+	return false
+}
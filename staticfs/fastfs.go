@@ -5,15 +5,62 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
-	"io"
+	"io/fs"
+	"mime"
 	"net/http"
+	"path"
+	"strconv"
+	"time"
 
 	"github.com/dkotik/htadaptor"
 )
 
+// FastFile serves contents from memory with a strong ETag computed
+// at construction. Conditional, range, and HEAD requests are answered
+// by [http.ServeContent].
 type FastFile struct {
 	contentType string
 	contents    []byte
+	etag        string
+	modTime     time.Time
+	header      http.Header
+	encoded     []encodedContents
+}
+
+// encodedContents holds compressed file contents.
+type encodedContents struct {
+	encoding string
+	contents []byte
+	etag     string
+}
+
+func newFastFile(contentType string, contents []byte) *FastFile {
+	return &FastFile{
+		contentType: contentType,
+		contents:    contents,
+		etag:        strongETag(contents),
+		modTime:     time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// compress stores encoded variants of the contents in the order
+// of compressors. Variants that are not smaller are discarded.
+func (f *FastFile) compress(compressors []encodingCompressor) error {
+	f.encoded = nil
+	for _, c := range compressors {
+		b, err := c.Compressor(f.contents)
+		if err != nil {
+			return fmt.Errorf("cannot apply %s encoding: %w", c.Encoding, err)
+		}
+		if len(b) < len(f.contents) {
+			f.encoded = append(f.encoded, encodedContents{
+				encoding: c.Encoding,
+				contents: b,
+				etag:     strongETag(b),
+			})
+		}
+	}
+	return nil
 }
 
 func NewFastFileFromTemplate(t *template.Template, data any) (*FastFile, error) {
@@ -21,27 +68,82 @@ func NewFastFileFromTemplate(t *template.Template, data any) (*FastFile, error)
 	if err := t.Execute(b, data); err != nil {
 		return nil, err
 	}
-	return &FastFile{
-		contentType: http.DetectContentType(b.Bytes()),
-		contents:    b.Bytes(),
-	}, nil
+	return newFastFile(http.DetectContentType(b.Bytes()), b.Bytes()), nil
 }
 
+// HandleError serves the contents as an error page with the status
+// code of [htadaptor.Error] or [http.StatusInternalServerError].
 func (f *FastFile) HandleError(
 	w http.ResponseWriter,
 	r *http.Request,
 	err error,
 ) error {
-	f.ServeHTTP(w, r)
+	code := http.StatusInternalServerError
+	var httpError htadaptor.Error
+	if errors.As(err, &httpError) {
+		code = httpError.HyperTextStatusCode()
+	}
+	f.serveStatus(w, r, code)
 	return nil
 }
 
+// negotiate sets the content headers and picks the contents
+// encoded for the client.
+func (f *FastFile) negotiate(w http.ResponseWriter, r *http.Request) (contents []byte, etag string, encoded bool) {
+	copyHeaders(w, f.header)
+	header := w.Header()
+	header.Set("content-type", f.contentType)
+	if len(f.encoded) == 0 {
+		return f.contents, f.etag, false
+	}
+	header.Add("vary", "Accept-Encoding")
+	if accepted := acceptedEncodings(r); accepted != nil {
+		for _, e := range f.encoded {
+			if accepts(accepted, e.encoding) {
+				header.Set("content-encoding", e.encoding)
+				return e.contents, e.etag, true
+			}
+		}
+	}
+	return f.contents, f.etag, false
+}
+
 func (f *FastFile) ServeHTTP(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	w.Header().Set("content-type", f.contentType)
-	_, _ = io.Copy(w, bytes.NewReader(f.contents))
+	contents, etag, encoded := f.negotiate(w, r)
+	if encoded {
+		// [http.ServeContent] omits the length of encoded content,
+		// which is known here, because ranges are not served.
+		w.Header().Set("content-length", strconv.Itoa(len(contents)))
+		r = r.Clone(r.Context())
+		r.Header.Del("range")
+	}
+	w.Header().Set("etag", etag)
+	http.ServeContent(w, r, "", f.modTime, bytes.NewReader(contents))
+}
+
+// serveStatus writes the contents as an error page. Without
+// validators, conditional and range requests cannot turn
+// the error into [http.StatusNotModified] or
+// [http.StatusPartialContent].
+func (f *FastFile) serveStatus(w http.ResponseWriter, r *http.Request, code int) {
+	contents, _, _ := f.negotiate(w, r)
+	w.Header().Set("content-length", strconv.Itoa(len(contents)))
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(contents)
+	}
+}
+
+// notFoundPage serves a [FastFile] with [http.StatusNotFound].
+type notFoundPage struct {
+	*FastFile
+}
+
+func (p notFoundPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.serveStatus(w, r, http.StatusNotFound)
 }
 
 type fastFileSystem struct {
@@ -61,19 +163,28 @@ func (f *fastFileSystem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // NewFastFileSystemFile serves a single file from memory without logging.
 func NewFastFileSystemFile(contents []byte) *FastFile {
-	return &FastFile{
-		contentType: http.DetectContentType(contents),
-		contents:    contents,
-	}
+	return newFastFile(http.DetectContentType(contents), contents)
+}
+
+type encodingCompressor struct {
+	Encoding   string
+	Compressor Compressor
 }
 
 type fastFileSystemOptions struct {
 	Index       map[string]*FastFile
+	Compressors []encodingCompressor
+	Headers     []headerRule
 	Fallthrough http.Handler
 }
 
 type FastFileSystemOption func(*fastFileSystemOptions) error
 
+// NewFastFileSystem serves files from memory. Each file is
+// compressed once at construction by every [Compressor] and the
+// smallest acceptable variant is chosen by the Accept-Encoding
+// request header. Files are compressed using [CompressGzip],
+// unless [WithFastFileSystemCompressor] is provided.
 func NewFastFileSystem(withOptions ...FastFileSystemOption) (_ http.Handler, err error) {
 	o := &fastFileSystemOptions{
 		Index: make(map[string]*FastFile),
@@ -81,10 +192,17 @@ func NewFastFileSystem(withOptions ...FastFileSystemOption) (_ http.Handler, err
 	for _, option := range append(
 		withOptions,
 		WithDefaultFastFileSystemFallthrough(),
+		WithDefaultFastFileSystemCompressor(),
 		func(o *fastFileSystemOptions) (err error) {
 			if len(o.Index) < 1 {
 				return errors.New("provide at least one file")
 			}
+			for path, file := range o.Index {
+				if err = file.compress(o.Compressors); err != nil {
+					return fmt.Errorf("cannot compress %s: %w", path, err)
+				}
+				file.header = headersFor(o.Headers, path)
+			}
 			return nil
 		},
 	) {
@@ -115,14 +233,98 @@ func WithFastFileSystemFile(
 		}
 		b := make([]byte, len(contents))
 		copy(b, contents)
-		o.Index[path] = &FastFile{
-			contentType: http.DetectContentType(contents),
-			contents:    b,
+		o.Index[path] = newFastFile(http.DetectContentType(contents), b)
+		return nil
+	}
+}
+
+// WithFastFileSystemFS loads every file into memory. Files named
+// "index.html" are served at the path of their directory, which
+// matches the output of [Generate]. The content type is determined
+// by the file extension.
+func WithFastFileSystemFS(files fs.FS) FastFileSystemOption {
+	return func(o *fastFileSystemOptions) error {
+		if files == nil {
+			return errors.New("cannot use a <nil> file system")
+		}
+		return fs.WalkDir(files, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			contents, err := fs.ReadFile(files, p)
+			if err != nil {
+				return err
+			}
+			route := fileRoute(p)
+			if _, ok := o.Index[route]; ok {
+				return fmt.Errorf("file path is already set: %s", route)
+			}
+			contentType := mime.TypeByExtension(path.Ext(p))
+			if contentType == "" {
+				contentType = http.DetectContentType(contents)
+			}
+			o.Index[route] = newFastFile(contentType, contents)
+			return nil
+		})
+	}
+}
+
+// WithFastFileSystemCompressor adds a content coding, such as
+// [EncodingBrotli] or [EncodingZstandard], to [NewFastFileSystem].
+// Repeat the option in the order of preference.
+func WithFastFileSystemCompressor(encoding string, c Compressor) FastFileSystemOption {
+	return func(o *fastFileSystemOptions) error {
+		if encoding == "" {
+			return errors.New("cannot use an empty content encoding")
 		}
+		if c == nil {
+			return errors.New("cannot use a <nil> compressor")
+		}
+		for _, existing := range o.Compressors {
+			if existing.Encoding == encoding {
+				return fmt.Errorf("compressor for %s encoding is already set", encoding)
+			}
+		}
+		o.Compressors = append(o.Compressors, encodingCompressor{
+			Encoding:   encoding,
+			Compressor: c,
+		})
 		return nil
 	}
 }
 
+// WithDefaultFastFileSystemCompressor compresses files using
+// [CompressGzip], unless another compressor is set.
+func WithDefaultFastFileSystemCompressor() FastFileSystemOption {
+	return func(o *fastFileSystemOptions) error {
+		if len(o.Compressors) > 0 {
+			return nil
+		}
+		return WithFastFileSystemCompressor(EncodingGzip, CompressGzip)(o)
+	}
+}
+
+// WithFastFileSystemHeader sets a response header for files whose
+// request paths match any of the [path.Match] patterns, or for all
+// files, if no patterns are given. Later options replace the values
+// of earlier ones.
+func WithFastFileSystemHeader(name, value string, patterns ...string) FastFileSystemOption {
+	return func(o *fastFileSystemOptions) error {
+		rule, err := newHeaderRule(name, value, patterns)
+		if err != nil {
+			return err
+		}
+		o.Headers = append(o.Headers, rule)
+		return nil
+	}
+}
+
+// WithFastFileSystemCacheControl sets the Cache-Control header
+// for matching files. See [WithFastFileSystemHeader].
+func WithFastFileSystemCacheControl(value string, patterns ...string) FastFileSystemOption {
+	return WithFastFileSystemHeader("Cache-Control", value, patterns...)
+}
+
 func WithFastFileSystemFallthrough(h http.Handler) FastFileSystemOption {
 	return func(o *fastFileSystemOptions) error {
 		if h == nil {
@@ -150,10 +352,7 @@ func WithDefaultFastFileSystemFallthrough() FastFileSystemOption {
 			return fmt.Errorf("unable to render error message: %w", err)
 		}
 		return WithFastFileSystemFallthrough(
-			&FastFile{
-				contentType: "text/html",
-				contents:    b.Bytes(),
-			},
+			notFoundPage{newFastFile("text/html", b.Bytes())},
 		)(o)
 	}
 }
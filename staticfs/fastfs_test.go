@@ -0,0 +1,175 @@
+package staticfs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dkotik/htadaptor"
+)
+
+func TestFastFileCaching(t *testing.T) {
+	h, err := NewFastFileSystem(
+		WithFastFileSystemFile("/app.js", testScript),
+		WithFastFileSystemFile("/index.html", []byte("<html></html>")),
+		WithFastFileSystemCacheControl("no-cache"),
+		WithFastFileSystemCacheControl("public, max-age=3600", "/*.js"),
+		WithFastFileSystemHeader("X-Content-Type-Options", "nosniff"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(method, target string, header http.Header) *http.Response {
+		r := httptest.NewRequest(method, target, nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	response := serve(http.MethodGet, "/app.js", nil)
+	etag := response.Header.Get("ETag")
+	if response.StatusCode != http.StatusOK || len(etag) < 3 || etag[0] != '"' {
+		t.Fatal("strong entity tag is missing:", etag)
+	}
+	if response.Header.Get("Cache-Control") != "public, max-age=3600" || response.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatal("file headers were not applied:", response.Header)
+	}
+	if response := serve(http.MethodGet, "/index.html", nil); response.Header.Get("Cache-Control") != "no-cache" {
+		t.Fatal("default cache control was not applied:", response.Header)
+	}
+
+	for _, c := range []struct {
+		Method string
+		Header http.Header
+		Status int
+		Body   string
+	}{
+		{Method: http.MethodGet, Header: http.Header{"If-None-Match": {etag}}, Status: http.StatusNotModified},
+		{Method: http.MethodGet, Header: http.Header{"If-Modified-Since": {response.Header.Get("Last-Modified")}}, Status: http.StatusNotModified},
+		{Method: http.MethodGet, Header: http.Header{"Range": {"bytes=0-6"}}, Status: http.StatusPartialContent, Body: "console"},
+		{Method: http.MethodGet, Header: http.Header{"Range": {"bytes=100000-"}}, Status: http.StatusRequestedRangeNotSatisfiable},
+		{Method: http.MethodHead, Status: http.StatusOK},
+	} {
+		response := serve(c.Method, "/app.js", c.Header)
+		if response.StatusCode != c.Status {
+			t.Fatalf("%s %v: expected status %d, got %d", c.Method, c.Header, c.Status, response.StatusCode)
+		}
+		body, _ := io.ReadAll(response.Body)
+		if c.Body != "" && string(body) != c.Body {
+			t.Fatalf("%s %v: unexpected body %q", c.Method, c.Header, body)
+		}
+	}
+	if response := serve(http.MethodHead, "/app.js", nil); response.ContentLength != int64(len(testScript)) {
+		t.Fatal("HEAD response has no content length:", response.ContentLength)
+	}
+
+	compressed := serve(http.MethodGet, "/app.js", http.Header{
+		"Accept-Encoding": {"gzip"},
+		"Range":           {"bytes=0-6"},
+	})
+	if compressed.StatusCode != http.StatusOK || compressed.Header.Get("ETag") == etag {
+		t.Fatal("compressed representation must have its own entity tag and ignore ranges")
+	}
+	if response := serve(http.MethodGet, "/app.js", http.Header{
+		"Accept-Encoding": {"gzip"},
+		"If-None-Match":   {compressed.Header.Get("ETag")},
+	}); response.StatusCode != http.StatusNotModified {
+		t.Fatal("compressed representation was not validated:", response.StatusCode)
+	}
+
+	if _, err = NewFastFileSystem(
+		WithFastFileSystemFile("/app.js", testScript),
+		WithFastFileSystemHeader("Cache-Control", "no-cache", "[invalid"),
+	); err == nil {
+		t.Fatal("invalid path pattern was accepted")
+	}
+}
+
+func TestFastFileErrorPages(t *testing.T) {
+	page := NewFastFileSystemFile([]byte("<html>error</html>"))
+	h, err := NewFastFileSystem(WithFastFileSystemFile("/index.html", []byte("<html></html>")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{
+		"If-None-Match": {page.etag},
+		"Range":         {"bytes=0-5"},
+	}
+
+	for _, c := range []struct {
+		Name   string
+		Serve  func(http.ResponseWriter, *http.Request)
+		Status int
+	}{
+		{
+			Name: "not found error",
+			Serve: func(w http.ResponseWriter, r *http.Request) {
+				_ = page.HandleError(w, r, htadaptor.NewNotFoundError(r.URL.Path))
+			},
+			Status: http.StatusNotFound,
+		},
+		{
+			Name: "unexpected error",
+			Serve: func(w http.ResponseWriter, r *http.Request) {
+				_ = page.HandleError(w, r, io.ErrUnexpectedEOF)
+			},
+			Status: http.StatusInternalServerError,
+		},
+		{
+			Name:   "fall through",
+			Serve:  h.ServeHTTP,
+			Status: http.StatusNotFound,
+		},
+	} {
+		t.Run(c.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/missing", nil)
+			r.Header = header.Clone()
+			w := httptest.NewRecorder()
+			c.Serve(w, r)
+			if w.Code != c.Status {
+				t.Fatalf("expected status %d, got %d", c.Status, w.Code)
+			}
+			if etag := w.Header().Get("ETag"); etag != "" {
+				t.Fatal("error page has an entity tag:", etag)
+			}
+			if body := w.Body.String(); !strings.Contains(body, "</html>") {
+				t.Fatal("error page was not served whole:", body)
+			}
+		})
+	}
+}
+
+func TestHeaderPolicy(t *testing.T) {
+	fs, err := New(
+		WithFileSystem(fstest.MapFS{
+			"app.js":     {Data: testScript},
+			"index.html": {Data: []byte("<html></html>")},
+		}),
+		WithFingerprinting(),
+		WithCacheControl("no-cache"),
+		WithHeader("X-Frame-Options", "DENY", "/*.html"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := request(t, fs, "/index.html", "")
+	if response.Header.Get("Cache-Control") != "no-cache" || response.Header.Get("X-Frame-Options") != "DENY" {
+		t.Fatal("headers were not applied:", response.Header)
+	}
+	if response = request(t, fs, "/app.js", ""); response.Header.Get("X-Frame-Options") != "" {
+		t.Fatal("header applied to a path that does not match")
+	}
+	script, err := fs.Asset("app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response = request(t, fs, script, ""); response.Header.Get("Cache-Control") != ImmutableCacheControl {
+		t.Fatal("fingerprinted path lost immutable caching:", response.Header)
+	}
+}
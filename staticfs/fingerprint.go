@@ -0,0 +1,126 @@
+package staticfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ImmutableCacheControl is set on fingerprinted paths, because
+// their contents never change.
+const ImmutableCacheControl = "max-age=31536000, immutable"
+
+const fingerprintLength = 8
+
+// WithFingerprinting additionally serves every indexed file at a path
+// that includes a hash of its contents, so that "/app.js" is also
+// served as "/app.3f9a1c0b.js" with [ImmutableCacheControl].
+// Fingerprinting applies to the output of the [PathTranslator]s.
+// Use [FS.Asset] or the "asset" function of [FS.FuncMap] to
+// reference fingerprinted paths from templates.
+func WithFingerprinting() Option {
+	return func(o *options) error {
+		if o.Fingerprint {
+			return errors.New("fingerprinting is already set")
+		}
+		o.Fingerprint = true
+		return nil
+	}
+}
+
+// FingerprintPath inserts a hash of the contents before
+// the extension of the path.
+func FingerprintPath(p string, contents []byte) string {
+	hash := sha256.Sum256(contents)
+	return fingerprintPath(p, hash[:])
+}
+
+func fingerprintPath(p string, hash []byte) string {
+	fingerprint := hex.EncodeToString(hash)[:fingerprintLength]
+	extension := path.Ext(p)
+	if extension == "" {
+		return p + "." + fingerprint
+	}
+	return strings.TrimSuffix(p, extension) + "." + fingerprint + extension
+}
+
+// fingerprint adds a fingerprinted request path for the file.
+func (i *fileIndex) fingerprint(o *options, external, real string) error {
+	hash, err := hashFile(o.FileSystem, real)
+	if err != nil {
+		return fmt.Errorf("cannot fingerprint %q: %w", real, err)
+	}
+	fingerprinted := fingerprintPath(external, hash)
+	if err = i.addPath(o, fingerprinted, real); err != nil {
+		return err
+	}
+	i.assets[external] = fingerprinted
+	i.immutable[fingerprinted] = true
+	return nil
+}
+
+func hashFile(files fs.FS, p string) ([]byte, error) {
+	f, err := files.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// Asset returns the fingerprinted request path of an indexed file,
+// such as "/app.3f9a1c0b.js" for "app.js". Files that are not
+// fingerprinted are returned as they are.
+func (fs *FS) Asset(name string) (string, error) {
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	index := fs.index.Load()
+	if fingerprinted, ok := index.assets[name]; ok {
+		return fingerprinted, nil
+	}
+	if _, ok := index.paths[name]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("static asset %q does not exist", name)
+}
+
+// FuncMap provides the "asset", "script", "stylesheet", and "liveReload"
+// functions to templates. Add them before parsing the templates given
+// to [htadaptor.NewTemplateEncoder]:
+//
+//	t := template.New("").Funcs(static.FuncMap())
+//	// <img src="{{ asset "logo.png" }}">
+//	// {{ script "app.js" }}
+//	// {{ stylesheet "style.css" }}
+//	// {{ liveReload }}
+//
+// The script and stylesheet elements carry integrity attributes,
+// when [WithIntegrity] is set.
+func (fs *FS) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"asset":      fs.Asset,
+		"script":     fs.scriptTag,
+		"stylesheet": fs.stylesheetTag,
+		"liveReload": fs.LiveReloadScript,
+	}
+}
+
+// WriteManifest writes a JSON object that maps request paths
+// to fingerprinted request paths for external bundlers.
+func (fs *FS) WriteManifest(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(fs.index.Load().assets)
+}
@@ -0,0 +1,71 @@
+package staticfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFingerprinting(t *testing.T) {
+	fs, err := New(
+		WithFileSystem(fstest.MapFS{
+			"app.js":        {Data: testScript},
+			"app.js.gz":     {Data: []byte("gzip")},
+			"css/style.css": {Data: []byte("body{}")},
+		}),
+		WithFingerprinting(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := fs.Asset("app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script != FingerprintPath("/app.js", testScript) || !strings.HasPrefix(script, "/app.") || !strings.HasSuffix(script, ".js") {
+		t.Fatal("unexpected fingerprinted path:", script)
+	}
+	response := request(t, fs, script, "gzip")
+	if response.Header.Get("Cache-Control") != ImmutableCacheControl {
+		t.Fatal("fingerprinted path is not immutable")
+	}
+	if body, _ := io.ReadAll(response.Body); string(body) != "gzip" {
+		t.Fatal("precompressed sibling was not served for a fingerprinted path:", string(body))
+	}
+	response = request(t, fs, "/app.js", "")
+	if response.StatusCode != http.StatusOK || response.Header.Get("Cache-Control") != "" {
+		t.Fatal("original path must be served without immutable caching")
+	}
+	if _, err = fs.Asset("missing.js"); err == nil {
+		t.Fatal("missing asset was found")
+	}
+
+	b := &bytes.Buffer{}
+	if err = template.Must(template.New("").Funcs(fs.FuncMap()).Parse(
+		`<link href="{{ asset "/css/style.css" }}">`,
+	)).Execute(b, nil); err != nil {
+		t.Fatal(err)
+	}
+	style, _ := fs.Asset("css/style.css")
+	if b.String() != `<link href="`+style+`">` {
+		t.Fatal("unexpected template output:", b.String())
+	}
+
+	b.Reset()
+	if err = fs.WriteManifest(b); err != nil {
+		t.Fatal(err)
+	}
+	manifest := make(map[string]string)
+	if err = json.Unmarshal(b.Bytes(), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != 2 || manifest["/app.js"] != script || manifest["/css/style.css"] != style {
+		t.Fatal("unexpected manifest:", manifest)
+	}
+}
@@ -0,0 +1,267 @@
+package staticfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type generateOptions struct {
+	Routes    []string
+	Sitemaps  []string
+	Directory string
+	Host      string
+	Header    http.Header
+}
+
+type GenerateOption func(*generateOptions) error
+
+// WithRoutes adds request paths to render, such as "/" or "/about".
+func WithRoutes(paths ...string) GenerateOption {
+	return func(o *generateOptions) error {
+		for _, p := range paths {
+			if !strings.HasPrefix(p, "/") {
+				return fmt.Errorf("route %q must start with a slash", p)
+			}
+			if !slices.Contains(o.Routes, p) {
+				o.Routes = append(o.Routes, p)
+			}
+		}
+		return nil
+	}
+}
+
+// WithSitemap renders every location listed in the sitemap XML
+// that the handler serves at the request path. The sitemap itself
+// is also written to the output directory.
+func WithSitemap(p string) GenerateOption {
+	return func(o *generateOptions) error {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("sitemap path %q must start with a slash", p)
+		}
+		o.Sitemaps = append(o.Sitemaps, p)
+		return nil
+	}
+}
+
+// WithOutputDirectory sets where [Generate] writes rendered pages.
+func WithOutputDirectory(p string) GenerateOption {
+	return func(o *generateOptions) error {
+		if p == "" {
+			return errors.New("cannot use an empty output directory path")
+		}
+		if o.Directory != "" {
+			return errors.New("output directory is already set")
+		}
+		o.Directory = p
+		return nil
+	}
+}
+
+// WithHost sets the host name of the synthetic requests.
+func WithHost(host string) GenerateOption {
+	return func(o *generateOptions) error {
+		if host == "" {
+			return errors.New("cannot use an empty host name")
+		}
+		if o.Host != "" {
+			return errors.New("host name is already set")
+		}
+		o.Host = host
+		return nil
+	}
+}
+
+// WithDefaultHost sets the host name to "localhost", unless it is set.
+func WithDefaultHost() GenerateOption {
+	return func(o *generateOptions) error {
+		if o.Host != "" {
+			return nil
+		}
+		return WithHost("localhost")(o)
+	}
+}
+
+// WithRequestHeader adds a header to every synthetic request,
+// such as "Accept" or "Accept-Language" for content negotiation.
+func WithRequestHeader(name, value string) GenerateOption {
+	return func(o *generateOptions) error {
+		if name == "" {
+			return errors.New("cannot use an empty header name")
+		}
+		if o.Header == nil {
+			o.Header = make(http.Header)
+		}
+		o.Header.Add(name, value)
+		return nil
+	}
+}
+
+// Generate renders routes by calling the handler in-process with
+// synthetic GET requests and writes the responses to the output
+// directory. Any status other than [http.StatusOK] fails the build.
+// Routes without an extension are written as "index.html" files
+// inside a directory of the same name, so that "/about" becomes
+// "about/index.html". Load the output back using [NewFastFileSystem]
+// with [WithFastFileSystemFS] or [New] with [WithDirectoryIndex].
+func Generate(ctx context.Context, h http.Handler, withOptions ...GenerateOption) (err error) {
+	if h == nil {
+		return errors.New("cannot generate pages from a <nil> handler")
+	}
+	o := &generateOptions{}
+	for _, option := range append(
+		withOptions,
+		WithDefaultHost(),
+		func(o *generateOptions) error {
+			if o.Directory == "" {
+				return errors.New("output directory is required")
+			}
+			if len(o.Routes) == 0 && len(o.Sitemaps) == 0 {
+				return errors.New("provide at least one route or sitemap")
+			}
+			return nil
+		},
+	) {
+		if err = option(o); err != nil {
+			return fmt.Errorf("cannot generate static pages: %w", err)
+		}
+	}
+
+	routes := o.Routes
+	for _, sitemap := range o.Sitemaps {
+		b, err := o.render(ctx, h, sitemap)
+		if err != nil {
+			return err
+		}
+		locations, err := parseSitemap(b)
+		if err != nil {
+			return fmt.Errorf("cannot parse sitemap %q: %w", sitemap, err)
+		}
+		if err = writeRoute(o.Directory, sitemap, b); err != nil {
+			return err
+		}
+		for _, location := range locations {
+			if !slices.Contains(routes, location) {
+				routes = append(routes, location)
+			}
+		}
+	}
+	for _, route := range routes {
+		b, err := o.render(ctx, h, route)
+		if err != nil {
+			return err
+		}
+		if err = writeRoute(o.Directory, route, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *generateOptions) render(ctx context.Context, h http.Handler, route string) ([]byte, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+o.Host+route, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request for %q: %w", route, err)
+	}
+	r.RemoteAddr = "127.0.0.1:0"
+	for name, values := range o.Header {
+		r.Header[name] = slices.Clone(values)
+	}
+	w := &pageRecorder{header: make(http.Header)}
+	h.ServeHTTP(w, r)
+	if w.status != 0 && w.status != http.StatusOK {
+		return nil, fmt.Errorf("route %q responded with status %d", route, w.status)
+	}
+	return w.body.Bytes(), nil
+}
+
+// routeFile returns the relative file path for a request path.
+func routeFile(route string) string {
+	p, _, _ := strings.Cut(route, "?")
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		return "index.html"
+	}
+	if path.Ext(p) == "" {
+		return p + "/index.html"
+	}
+	return p
+}
+
+// fileRoute is the inverse of routeFile.
+func fileRoute(p string) string {
+	if p == "index.html" {
+		return "/"
+	}
+	if directory, ok := strings.CutSuffix(p, "/index.html"); ok {
+		return "/" + directory
+	}
+	return "/" + p
+}
+
+func writeRoute(directory, route string, b []byte) error {
+	p := filepath.Join(directory, filepath.FromSlash(routeFile(route)))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("cannot create directory for %q: %w", route, err)
+	}
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		return fmt.Errorf("cannot write %q: %w", route, err)
+	}
+	return nil
+}
+
+func parseSitemap(b []byte) (locations []string, err error) {
+	var sitemap struct {
+		URLs []struct {
+			Location string `xml:"loc"`
+		} `xml:"url"`
+	}
+	if err = xml.Unmarshal(b, &sitemap); err != nil {
+		return nil, err
+	}
+	for _, entry := range sitemap.URLs {
+		u, err := url.Parse(strings.TrimSpace(entry.Location))
+		if err != nil {
+			return nil, fmt.Errorf("invalid location %q: %w", entry.Location, err)
+		}
+		location := u.EscapedPath()
+		if location == "" {
+			location = "/"
+		}
+		locations = append(locations, location)
+	}
+	return locations, nil
+}
+
+// pageRecorder buffers a response rendered in-process.
+type pageRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (p *pageRecorder) Header() http.Header {
+	return p.header
+}
+
+func (p *pageRecorder) WriteHeader(code int) {
+	if p.status == 0 {
+		p.status = code
+	}
+}
+
+func (p *pageRecorder) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+	return p.body.Write(b)
+}
@@ -0,0 +1,74 @@
+package staticfs
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dkotik/htadaptor"
+)
+
+func TestGenerate(t *testing.T) {
+	page := func(title string) http.Handler {
+		return htadaptor.Must(htadaptor.New(
+			htadaptor.WithTemplate(template.Must(template.New("page").Parse(`<h1>{{ . }}</h1>`))),
+		).AdaptNullaryFunc(func(ctx context.Context) (string, error) {
+			return title, nil
+		}))
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /{$}", page("Home"))
+	mux.Handle("GET /about", page("About"))
+	mux.Handle("GET /pricing", page("Pricing"))
+	mux.HandleFunc("GET /sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/xml")
+		_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc></url>
+  <url><loc>https://example.com/pricing</loc></url>
+</urlset>`)
+	})
+
+	directory := t.TempDir()
+	if err := Generate(context.Background(), mux,
+		WithOutputDirectory(directory),
+		WithRoutes("/about"),
+		WithSitemap("/sitemap.xml"),
+	); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"index.html", "about/index.html", "pricing/index.html", "sitemap.xml"} {
+		if _, err := os.Stat(filepath.Join(directory, p)); err != nil {
+			t.Fatal("page was not written:", err)
+		}
+	}
+
+	h, err := NewFastFileSystem(WithFastFileSystemFS(os.DirFS(directory)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for route, expected := range map[string]string{
+		"/":        "<h1>Home</h1>",
+		"/about":   "<h1>About</h1>",
+		"/pricing": "<h1>Pricing</h1>",
+	} {
+		response := request(t, h, route, "")
+		if body, _ := io.ReadAll(response.Body); string(body) != expected {
+			t.Fatalf("%s: unexpected body %q", route, body)
+		}
+	}
+	if response := request(t, h, "/sitemap.xml", ""); response.Header.Get("Content-Type") != "application/xml" && response.Header.Get("Content-Type") != "text/xml; charset=utf-8" {
+		t.Fatal("unexpected sitemap content type:", response.Header.Get("Content-Type"))
+	}
+
+	if err = Generate(context.Background(), mux,
+		WithOutputDirectory(directory),
+		WithRoutes("/missing"),
+	); err == nil {
+		t.Fatal("missing route did not fail the build")
+	}
+}
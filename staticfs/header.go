@@ -0,0 +1,78 @@
+package staticfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+)
+
+// headerRule sets a response header for request paths that match
+// any of the [path.Match] patterns or for all paths, if there are none.
+type headerRule struct {
+	Name     string
+	Value    string
+	Patterns []string
+}
+
+func newHeaderRule(name, value string, patterns []string) (headerRule, error) {
+	if name == "" {
+		return headerRule{}, errors.New("cannot use an empty header name")
+	}
+	if value == "" {
+		return headerRule{}, fmt.Errorf("cannot use an empty %s header value", name)
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return headerRule{}, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+		}
+	}
+	return headerRule{
+		Name:     http.CanonicalHeaderKey(name),
+		Value:    value,
+		Patterns: patterns,
+	}, nil
+}
+
+func (r headerRule) matches(p string) bool {
+	if len(r.Patterns) == 0 {
+		return true
+	}
+	for _, pattern := range r.Patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// headersFor collects the headers of matching rules. Later rules
+// replace the values of earlier ones. Returns <nil> if none match.
+func headersFor(rules []headerRule, p string) (h http.Header) {
+	for _, rule := range rules {
+		if !rule.matches(p) {
+			continue
+		}
+		if h == nil {
+			h = make(http.Header)
+		}
+		h.Set(rule.Name, rule.Value)
+	}
+	return h
+}
+
+func copyHeaders(w http.ResponseWriter, h http.Header) {
+	header := w.Header()
+	for name, values := range h {
+		header[name] = slices.Clone(values)
+	}
+}
+
+// strongETag quotes a content hash as a strong entity tag.
+func strongETag(contents []byte) string {
+	hash := sha256.Sum256(contents)
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
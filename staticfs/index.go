@@ -0,0 +1,251 @@
+package staticfs
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+// fileIndex maps request paths to files. It is never modified after
+// it is published, so that requests read it without locking. Live
+// reload publishes an updated copy instead.
+type fileIndex struct {
+	paths     map[string]string            // request path to file path
+	encoded   map[string]map[string]string // file path to encoding to sibling file path
+	assets    map[string]string            // request path to fingerprinted request path
+	immutable map[string]bool
+	headers   map[string]http.Header
+	integrity map[string]string // file path to Subresource Integrity hash
+}
+
+func newFileIndex() *fileIndex {
+	return &fileIndex{
+		paths:     make(map[string]string),
+		encoded:   make(map[string]map[string]string),
+		assets:    make(map[string]string),
+		immutable: make(map[string]bool),
+		headers:   make(map[string]http.Header),
+		integrity: make(map[string]string),
+	}
+}
+
+// build walks the whole file system.
+func (o *options) build() (*fileIndex, error) {
+	i := newFileIndex()
+	for external, real := range o.Index {
+		if err := i.addManual(o, external, real); err != nil {
+			return nil, err
+		}
+	}
+	if err := fs.WalkDir(o.FileSystem, ".",
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil // skip directories
+			}
+			return i.add(o, path)
+		},
+	); err != nil {
+		return nil, fmt.Errorf("cannot index files from the file system: %w", err)
+	}
+	return i, nil
+}
+
+func (i *fileIndex) clone() *fileIndex {
+	c := &fileIndex{
+		paths:     maps.Clone(i.paths),
+		encoded:   make(map[string]map[string]string, len(i.encoded)),
+		assets:    maps.Clone(i.assets),
+		immutable: maps.Clone(i.immutable),
+		headers:   maps.Clone(i.headers), // values are never modified
+		integrity: maps.Clone(i.integrity),
+	}
+	for real, siblings := range i.encoded {
+		c.encoded[real] = maps.Clone(siblings)
+	}
+	return c
+}
+
+func (i *fileIndex) addPath(o *options, external, real string) error {
+	if current, ok := i.paths[external]; ok && current != real {
+		return fmt.Errorf("request path %q already points to %q", external, current)
+	}
+	i.paths[external] = real
+	if h := headersFor(o.Headers, external); h != nil {
+		i.headers[external] = h
+	}
+	return nil
+}
+
+// addManual indexes a request path set by [WithPath].
+func (i *fileIndex) addManual(o *options, external, real string) error {
+	if err := i.addPath(o, external, real); err != nil {
+		return err
+	}
+	if err := i.addIntegrity(o, real); err != nil {
+		return err
+	}
+	if o.Fingerprint {
+		return i.fingerprint(o, external, real)
+	}
+	return nil
+}
+
+// add indexes a file using the [PathTranslator]s or attaches
+// it to the original file as a precompressed sibling.
+func (i *fileIndex) add(o *options, real string) (err error) {
+	if original, encoding, ok := precompressedSibling(o.FileSystem, real); ok {
+		if i.encoded[original] == nil {
+			i.encoded[original] = make(map[string]string)
+		}
+		i.encoded[original][encoding] = real
+		return nil
+	}
+
+	external := real
+	accept := false
+	for _, translator := range o.Translators {
+		external, accept, err = translator(external)
+		if err != nil {
+			return err
+		}
+		if !accept {
+			return nil // skip, choice of the translator
+		}
+		if err = i.addPath(o, external, real); err != nil {
+			return err
+		}
+	}
+	if err = i.addIntegrity(o, real); err != nil {
+		return err
+	}
+	if o.Fingerprint {
+		return i.fingerprint(o, external, real)
+	}
+	return nil
+}
+
+// remove drops every request path that points to the file.
+// Returns precompressed siblings that lost their original file.
+func (i *fileIndex) remove(real string) (orphans []string) {
+	for external, p := range i.paths {
+		if p != real {
+			continue
+		}
+		delete(i.paths, external)
+		delete(i.assets, external)
+		delete(i.immutable, external)
+		delete(i.headers, external)
+	}
+	if original, encoding, ok := siblingOf(real); ok {
+		if siblings := i.encoded[original]; siblings[encoding] == real {
+			delete(siblings, encoding)
+			if len(siblings) == 0 {
+				delete(i.encoded, original)
+			}
+		}
+	}
+	delete(i.integrity, real)
+	for _, sibling := range i.encoded[real] {
+		orphans = append(orphans, sibling)
+	}
+	delete(i.encoded, real)
+	return orphans
+}
+
+// update returns a copy of the index with the changed files
+// and directories indexed again.
+func (i *fileIndex) update(o *options, changed []string) (*fileIndex, error) {
+	next := i.clone()
+	var pending []string
+	for _, p := range changed {
+		info, err := fs.Stat(o.FileSystem, p)
+		if err == nil && info.IsDir() {
+			if err = fs.WalkDir(o.FileSystem, p, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					pending = append(pending, path)
+				}
+				return err
+			}); err != nil {
+				return nil, fmt.Errorf("cannot index directory %q: %w", p, err)
+			}
+			continue
+		}
+		if err != nil { // removed file or directory
+			for _, real := range next.paths {
+				if strings.HasPrefix(real, p+"/") {
+					pending = append(pending, real)
+				}
+			}
+		}
+		pending = append(pending, p)
+	}
+
+	var added []string
+	for len(pending) > 0 {
+		real := pending[0]
+		pending = pending[1:]
+		pending = append(pending, next.remove(real)...)
+		info, err := fs.Stat(o.FileSystem, real)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		for _, c := range precompressed { // standalone siblings become variants
+			if sibling := real + c.Extension; next.isIndexed(sibling) {
+				next.remove(sibling)
+				pending = append(pending, sibling)
+			}
+		}
+		added = append(added, real)
+		if err = next.add(o, real); err != nil {
+			return nil, err
+		}
+	}
+	for external, real := range o.Index {
+		for _, p := range added {
+			if p == real {
+				if err := next.addManual(o, external, real); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return next, nil
+}
+
+func (i *fileIndex) isIndexed(real string) bool {
+	for _, p := range i.paths {
+		if p == real {
+			return true
+		}
+	}
+	return false
+}
+
+// siblingOf returns the original file path and encoding
+// for a path with a precompressed extension.
+func siblingOf(p string) (original, encoding string, ok bool) {
+	for _, c := range precompressed {
+		if original, ok = strings.CutSuffix(p, c.Extension); ok {
+			return original, c.Encoding, true
+		}
+	}
+	return "", "", false
+}
+
+// precompressedSibling reports whether the file is a compressed
+// variant of another file in the same file system.
+func precompressedSibling(files fs.FS, p string) (original, encoding string, ok bool) {
+	original, encoding, ok = siblingOf(p)
+	if !ok {
+		return "", "", false
+	}
+	if info, err := fs.Stat(files, original); err == nil && !info.IsDir() {
+		return original, encoding, true
+	}
+	return "", "", false
+}
@@ -0,0 +1,63 @@
+package staticfs
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"html/template"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIntegrity(t *testing.T) {
+	fs, err := New(
+		WithFileSystem(fstest.MapFS{
+			"app.js":    {Data: testScript},
+			"style.css": {Data: []byte("body{}")},
+		}),
+		WithFingerprinting(),
+		WithIntegrity(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha512.Sum384(testScript)
+	expected := "sha384-" + base64.StdEncoding.EncodeToString(hash[:])
+	integrity, err := fs.Integrity("app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if integrity != expected {
+		t.Fatal("unexpected integrity hash:", integrity)
+	}
+
+	b := &bytes.Buffer{}
+	if err = template.Must(template.New("").Funcs(fs.FuncMap()).Parse(
+		`{{ script "app.js" }}{{ stylesheet "style.css" }}`,
+	)).Execute(b, nil); err != nil {
+		t.Fatal(err)
+	}
+	script, _ := fs.Asset("app.js")
+	style, _ := fs.Asset("style.css")
+	styleIntegrity, _ := fs.Integrity("style.css")
+	if b.String() != `<script src="`+script+`" integrity="`+expected+`" crossorigin="anonymous"></script>`+
+		`<link rel="stylesheet" href="`+style+`" integrity="`+styleIntegrity+`" crossorigin="anonymous">` {
+		t.Fatal("unexpected elements:", b.String())
+	}
+
+	sources := fs.ContentSecurityPolicySources()
+	if len(sources) != 2 || (sources[0] != "'"+expected+"'" && sources[1] != "'"+expected+"'") {
+		t.Fatal("unexpected policy sources:", sources)
+	}
+
+	plain, err := New(WithFileSystem(fstest.MapFS{"app.js": {Data: testScript}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag, err := plain.scriptTag("app.js"); err != nil || tag != `<script src="/app.js"></script>` {
+		t.Fatal("unexpected element without integrity:", tag, err)
+	}
+	if _, err = plain.Integrity("app.js"); err == nil {
+		t.Fatal("integrity hash found without computing it")
+	}
+}
@@ -0,0 +1,139 @@
+package staticfs
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// LiveReloadPath serves server-sent events that tell browsers
+// to reload when a file changes. See [WithLiveReload].
+const LiveReloadPath = "/.live-reload"
+
+const liveReloadDebounce = time.Millisecond * 100
+
+// WithLiveReload watches the directory of [WithDirectory] for
+// development until the context is done. Changed files are indexed
+// again without restarting and browsers that run the script from
+// [FS.LiveReloadScript] reload the page. Do not use it in production,
+// where the index is built once and never changes.
+func WithLiveReload(ctx context.Context) Option {
+	return func(o *options) error {
+		if ctx == nil {
+			return errors.New("cannot use a <nil> context")
+		}
+		if o.LiveReload != nil {
+			return errors.New("live reload is already set")
+		}
+		o.LiveReload = ctx
+		return nil
+	}
+}
+
+// LiveReloadScript returns a script element that reloads the page
+// when the files change. Returns an empty string unless
+// [WithLiveReload] is set, so templates can always include it.
+func (fs *FS) LiveReloadScript() template.HTML {
+	if fs.liveReload == nil {
+		return ""
+	}
+	return `<script>new EventSource("` + LiveReloadPath + `").addEventListener("reload", () => location.reload())</script>`
+}
+
+// watch indexes changed files again and notifies browsers.
+func (fs *FS) watch(o *options) {
+	changes := make(chan string, 64)
+	go func() {
+		if err := watchDirectory(o.LiveReload, o.Directory, changes); err != nil {
+			slog.Error("cannot watch static files", slog.String("directory", o.Directory), slog.Any("error", err))
+		}
+	}()
+
+	var changed []string
+	debounce := time.NewTimer(liveReloadDebounce)
+	debounce.Stop()
+	for {
+		select {
+		case <-o.LiveReload.Done():
+			debounce.Stop()
+			return
+		case p := <-changes:
+			if !slices.Contains(changed, p) {
+				changed = append(changed, p)
+			}
+			debounce.Reset(liveReloadDebounce)
+		case <-debounce.C:
+			next, err := fs.index.Load().update(o, changed)
+			if err != nil {
+				slog.Error("cannot index changed static files", slog.Any("files", changed), slog.Any("error", err))
+			} else {
+				fs.index.Store(next)
+				fs.liveReload.broadcast()
+			}
+			changed = nil
+		}
+	}
+}
+
+// liveReload streams reload events to subscribed browsers.
+type liveReload struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+func newLiveReload() *liveReload {
+	return &liveReload{subscribers: make(map[chan struct{}]struct{})}
+}
+
+func (l *liveReload) broadcast() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for subscriber := range l.subscribers {
+		select {
+		case subscriber <- struct{}{}:
+		default: // reload is already pending
+		}
+	}
+}
+
+func (l *liveReload) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reload := make(chan struct{}, 1)
+	l.mu.Lock()
+	l.subscribers[reload] = struct{}{}
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.subscribers, reload)
+		l.mu.Unlock()
+	}()
+
+	header := w.Header()
+	header.Set("content-type", "text/event-stream")
+	header.Set("cache-control", "no-cache")
+	controller := http.NewResponseController(w)
+	if _, err := io.WriteString(w, "retry: 1000\n\n"); err != nil {
+		return
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-reload:
+			if _, err := io.WriteString(w, "event: reload\ndata: reload\n\n"); err != nil {
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package staticfs
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLiveReload(t *testing.T) {
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, "index.html"), []byte("<html></html>"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs, err := New(WithDirectory(directory), WithLiveReload(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(fs.LiveReloadScript()), LiveReloadPath) {
+		t.Fatal("live reload script is missing")
+	}
+	server := httptest.NewServer(fs)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + LiveReloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	events := bufio.NewScanner(response.Body)
+	events.Scan() // retry interval
+
+	waitForReload := func() {
+		t.Helper()
+		reloaded := make(chan bool)
+		go func() {
+			for events.Scan() {
+				if events.Text() == "event: reload" {
+					reloaded <- true
+					return
+				}
+			}
+			reloaded <- false
+		}()
+		select {
+		case ok := <-reloaded:
+			if !ok {
+				t.Fatal("event stream closed")
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("browser was not told to reload")
+		}
+	}
+
+	if err = os.MkdirAll(filepath.Join(directory, "js"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(directory, "js", "app.js"), testScript, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	waitForReload()
+	if response := request(t, fs, "/js/app.js", ""); response.StatusCode != http.StatusOK {
+		t.Fatal("new file was not indexed:", response.StatusCode)
+	}
+
+	if err = os.RemoveAll(filepath.Join(directory, "js")); err != nil {
+		t.Fatal(err)
+	}
+	waitForReload()
+	if _, ok := fs.index.Load().paths["/js/app.js"]; ok {
+		t.Fatal("removed file is still indexed")
+	}
+}
+
+func TestPollDirectory(t *testing.T) {
+	directory := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan string, 8)
+	go func() { _ = pollDirectory(ctx, directory, changes) }()
+	time.Sleep(pollInterval / 2)
+
+	if err := os.WriteFile(filepath.Join(directory, "app.js"), testScript, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case p := <-changes:
+		if p != "app.js" {
+			t.Fatal("unexpected change:", p)
+		}
+	case <-time.After(pollInterval * 4):
+		t.Fatal("change was not detected")
+	}
+}
+
+func TestIndexUpdate(t *testing.T) {
+	files := fstest.MapFS{
+		"app.js.gz": {Data: []byte("gzip")},
+	}
+	o := &options{
+		Index:       make(map[string]string),
+		FileSystem:  files,
+		Fingerprint: true,
+	}
+	if err := WithPathTranslators(func(real string) (string, bool, error) {
+		return "/" + real, true, nil
+	})(o); err != nil {
+		t.Fatal(err)
+	}
+	index, err := o.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := index.paths["/app.js.gz"]; !ok {
+		t.Fatal("sibling without an original must be indexed")
+	}
+
+	files["app.js"] = &fstest.MapFile{Data: testScript}
+	if index, err = index.update(o, []string{"app.js"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := index.paths["/app.js.gz"]; ok {
+		t.Fatal("sibling of a new original is still indexed by itself")
+	}
+	if index.encoded["app.js"][EncodingGzip] != "app.js.gz" || index.assets["/app.js"] == "" {
+		t.Fatalf("new original was not indexed: %+v", index)
+	}
+
+	delete(files, "app.js")
+	if index, err = index.update(o, []string{"app.js"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.encoded) != 0 || len(index.immutable) != 1 || index.paths["/app.js.gz"] != "app.js.gz" {
+		t.Fatalf("removed original was not cleaned up: %+v", index)
+	}
+}
@@ -1,10 +1,12 @@
 package staticfs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
+	"strings"
 )
 
 type PathTranslator func(real string) (external string, accept bool, err error)
@@ -13,6 +15,16 @@ type options struct {
 	Index       map[string]string
 	Translators []PathTranslator
 	FileSystem  fs.FS
+	Fingerprint bool
+	Integrity   bool
+	Headers     []headerRule
+	Directory   string
+	LiveReload  context.Context
+
+	DirectoryIndex   string
+	Fallback         string
+	FallbackExcluded []string
+	NotFoundPage     string
 }
 
 type Option func(*options) error
@@ -57,5 +69,91 @@ func WithFileSystem(fs fs.FS) Option {
 }
 
 func WithDirectory(p string) Option {
-	return WithFileSystem(os.DirFS(p))
+	return func(o *options) error {
+		if p == "" {
+			return errors.New("cannot use an empty directory path")
+		}
+		if err := WithFileSystem(os.DirFS(p))(o); err != nil {
+			return err
+		}
+		o.Directory = p
+		return nil
+	}
+}
+
+// WithHeader sets a response header for files whose request paths
+// match any of the [path.Match] patterns, or for all files, if no
+// patterns are given. Later options replace the values of earlier
+// ones. Fingerprinted paths always keep [ImmutableCacheControl].
+func WithHeader(name, value string, patterns ...string) Option {
+	return func(o *options) error {
+		rule, err := newHeaderRule(name, value, patterns)
+		if err != nil {
+			return err
+		}
+		o.Headers = append(o.Headers, rule)
+		return nil
+	}
+}
+
+// WithCacheControl sets the Cache-Control header for matching files.
+// See [WithHeader].
+func WithCacheControl(value string, patterns ...string) Option {
+	return WithHeader("Cache-Control", value, patterns...)
+}
+
+// WithDirectoryIndex serves the named file, such as "index.html",
+// for request paths that end with a slash. Requests for the directory
+// without the trailing slash and requests that name the file are
+// redirected to the canonical path that ends with a slash.
+func WithDirectoryIndex(name string) Option {
+	return func(o *options) error {
+		if name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("invalid directory index file name %q", name)
+		}
+		if o.DirectoryIndex != "" {
+			return errors.New("directory index is already set")
+		}
+		o.DirectoryIndex = name
+		return nil
+	}
+}
+
+// WithSinglePageApplication serves the file, such as "index.html",
+// for GET and HEAD requests to unindexed paths, so that a browser
+// application can handle its history routes. Paths that look like
+// assets, because they have an extension, and paths that start with
+// any of the excluded prefixes, such as "/api/", are not found.
+func WithSinglePageApplication(file string, excludedPrefixes ...string) Option {
+	return func(o *options) error {
+		if file == "" {
+			return errors.New("cannot use an empty single-page application file path")
+		}
+		if o.Fallback != "" {
+			return errors.New("single-page application is already set")
+		}
+		for _, prefix := range excludedPrefixes {
+			if !strings.HasPrefix(prefix, "/") {
+				return fmt.Errorf("excluded path prefix %q must start with a slash", prefix)
+			}
+		}
+		o.Fallback = file
+		o.FallbackExcluded = excludedPrefixes
+		return nil
+	}
+}
+
+// WithNotFoundPage serves the file from the file system with
+// [http.StatusNotFound] to GET and HEAD requests for unindexed paths.
+func WithNotFoundPage(file string) Option {
+	return func(o *options) error {
+		if file == "" {
+			return errors.New("cannot use an empty not found page file path")
+		}
+		if o.NotFoundPage != "" {
+			return errors.New("not found page is already set")
+		}
+		o.NotFoundPage = file
+		return nil
+	}
 }
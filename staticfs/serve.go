@@ -1,8 +1,16 @@
 package staticfs
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	iofs "io/fs"
 	"log/slog"
+	"mime"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
 
 	"github.com/dkotik/htadaptor"
 )
@@ -11,14 +19,36 @@ func (fs *FS) ServeHyperText(
 	w http.ResponseWriter,
 	r *http.Request,
 ) (err error) {
-	real, ok := fs.index[r.URL.Path]
-	if !ok {
-		return htadaptor.NewNotFoundError(r.URL.Path)
+	if fs.liveReload != nil && r.URL.Path == LiveReloadPath {
+		fs.liveReload.ServeHTTP(w, r)
+		return nil
 	}
-	r.URL.Path = real // TODO: not kosher.
-	// r.URL.Path = "main.go"
-	fs.source.ServeHTTP(w, r)
-	return nil
+	index := fs.index.Load()
+	p := r.URL.Path
+	if real, ok := index.paths[p]; ok {
+		if fs.directoryIndex != "" && path.Base(p) == fs.directoryIndex {
+			localRedirect(w, r, "./")
+			return nil
+		}
+		return fs.serveFile(w, r, index, p, real, http.StatusOK)
+	}
+	if fs.directoryIndex != "" {
+		if strings.HasSuffix(p, "/") {
+			if real, ok := index.paths[p+fs.directoryIndex]; ok {
+				return fs.serveFile(w, r, index, p+fs.directoryIndex, real, http.StatusOK)
+			}
+		} else if _, ok := index.paths[p+"/"+fs.directoryIndex]; ok {
+			localRedirect(w, r, path.Base(p)+"/")
+			return nil
+		}
+	}
+	if fs.fallback != "" && fs.isApplicationRoute(r) {
+		return fs.serveFile(w, r, index, "", fs.fallback, http.StatusOK)
+	}
+	if fs.notFoundPage != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		return fs.serveFile(w, r, index, "", fs.notFoundPage, http.StatusNotFound)
+	}
+	return htadaptor.NewNotFoundError(p)
 }
 
 func (fs *FS) ServeHTTP(
@@ -29,23 +59,154 @@ func (fs *FS) ServeHTTP(
 	if err == nil {
 		return
 	}
-	// var httpError Error
-	// if errors.As(err, &httpError) {
-	// 	msg := err.Error()
-	// 	http.Error(w, msg, httpError.HyperTextStatusCode())
-	// 	slog.Log(
-	// 		r.Context(),
-	// 		slog.LevelWarn,
-	// 		msg,
-	// 		slog.Any("error", err),
-	// 	)
-	// 	return
-	// }
-	http.Error(w, err.Error(), http.StatusInternalServerError)
+	status := htadaptor.GetHyperTextStatusCode(err)
+	http.Error(w, err.Error(), status)
+	level := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
 	slog.Log(
 		r.Context(),
-		slog.LevelError,
+		level,
 		err.Error(),
 		slog.String("path", r.URL.Path),
 	)
 }
+
+// isApplicationRoute reports whether a single-page application
+// should render the request path in the browser.
+func (fs *FS) isApplicationRoute(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if path.Ext(r.URL.Path) != "" {
+		return false // missing assets must not receive the page
+	}
+	for _, prefix := range fs.fallbackExcluded {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// localRedirect uses a relative location, so that it remains
+// correct when the file system is mounted with [http.StripPrefix].
+func localRedirect(w http.ResponseWriter, r *http.Request, location string) {
+	if q := r.URL.RawQuery; q != "" {
+		location += "?" + q
+	}
+	w.Header().Set("location", location)
+	w.WriteHeader(http.StatusPermanentRedirect)
+}
+
+// serveFile writes the file with the headers of its request path,
+// which is empty for fallback and error pages.
+func (fs *FS) serveFile(
+	w http.ResponseWriter,
+	r *http.Request,
+	index *fileIndex,
+	external string,
+	real string,
+	status int,
+) error {
+	if external != "" {
+		copyHeaders(w, index.headers[external])
+		if index.immutable[external] {
+			w.Header().Set("cache-control", ImmutableCacheControl)
+		}
+	}
+	if status == http.StatusOK {
+		if encoded, ok := index.encoded[real]; ok {
+			w.Header().Add("vary", "Accept-Encoding")
+			if fs.serveEncoded(w, r, real, encoded) {
+				return nil
+			}
+		}
+	}
+
+	f, err := fs.files.Open(real)
+	if err != nil {
+		if errors.Is(err, iofs.ErrNotExist) {
+			return htadaptor.NewNotFoundError(r.URL.Path) // removed since indexing
+		}
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		header := w.Header()
+		if contentType := mime.TypeByExtension(path.Ext(real)); contentType != "" {
+			header.Set("content-type", contentType)
+		}
+		header.Set("content-length", strconv.FormatInt(info.Size(), 10))
+		w.WriteHeader(status)
+		if r.Method != http.MethodHead {
+			_, _ = io.Copy(w, f)
+		}
+		return nil
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(b)
+	}
+	http.ServeContent(w, r, real, info.ModTime(), content)
+	return nil
+}
+
+// serveEncoded serves the most preferred precompressed sibling
+// that the client accepts. Returns false if none was served.
+func (fs *FS) serveEncoded(
+	w http.ResponseWriter,
+	r *http.Request,
+	real string,
+	encoded map[string]string,
+) bool {
+	contentType := mime.TypeByExtension(path.Ext(real))
+	if contentType == "" {
+		return false // compressed bytes cannot be sniffed
+	}
+	accepted := acceptedEncodings(r)
+	if accepted == nil {
+		return false
+	}
+	for _, c := range precompressed {
+		p, ok := encoded[c.Encoding]
+		if !ok || !accepts(accepted, c.Encoding) {
+			continue
+		}
+		f, err := fs.files.Open(p)
+		if err != nil {
+			continue
+		}
+		content, ok := f.(io.ReadSeeker)
+		if !ok {
+			_ = f.Close()
+			continue
+		}
+		info, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			continue
+		}
+		defer f.Close()
+		header := w.Header()
+		header.Set("content-type", contentType)
+		header.Set("content-encoding", c.Encoding)
+		// [http.ServeContent] omits the length of encoded content,
+		// which is known here, because ranges are not served.
+		header.Set("content-length", strconv.FormatInt(info.Size(), 10))
+		r = r.Clone(r.Context())
+		r.Header.Del("range")
+		http.ServeContent(w, r, real, info.ModTime(), content)
+		return true
+	}
+	return false
+}
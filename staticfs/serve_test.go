@@ -0,0 +1,71 @@
+package staticfs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRequestResolution(t *testing.T) {
+	fs, err := New(
+		WithFileSystem(fstest.MapFS{
+			"index.html":      {Data: []byte("application")},
+			"docs/index.html": {Data: []byte("documentation")},
+			"404.html":        {Data: []byte("missing")},
+			"app.js":          {Data: testScript},
+		}),
+		WithDirectoryIndex("index.html"),
+		WithSinglePageApplication("index.html", "/api/"),
+		WithNotFoundPage("404.html"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		Method   string
+		Target   string
+		Status   int
+		Body     string
+		Location string
+	}{
+		{Target: "/", Status: http.StatusOK, Body: "application"},
+		{Target: "/docs/", Status: http.StatusOK, Body: "documentation"},
+		{Target: "/docs?page=2", Status: http.StatusPermanentRedirect, Location: "docs/?page=2"},
+		{Target: "/docs/index.html", Status: http.StatusPermanentRedirect, Location: "./"},
+		{Target: "/settings/profile", Status: http.StatusOK, Body: "application"},
+		{Target: "/missing.js", Status: http.StatusNotFound, Body: "missing"},
+		{Target: "/api/users", Status: http.StatusNotFound, Body: "missing"},
+		{Method: http.MethodPost, Target: "/settings", Status: http.StatusNotFound},
+	} {
+		method := c.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		r := httptest.NewRequest(method, c.Target, nil)
+		w := httptest.NewRecorder()
+		fs.ServeHTTP(w, r)
+		response := w.Result()
+		if response.StatusCode != c.Status {
+			t.Fatalf("%s %s: expected status %d, got %d", method, c.Target, c.Status, response.StatusCode)
+		}
+		if body, _ := io.ReadAll(response.Body); c.Body != "" && string(body) != c.Body {
+			t.Fatalf("%s %s: unexpected body %q", method, c.Target, body)
+		}
+		if location := response.Header.Get("Location"); location != c.Location {
+			t.Fatalf("%s %s: unexpected location %q", method, c.Target, location)
+		}
+		if r.URL.Path != c.Target && r.URL.RequestURI() != c.Target {
+			t.Fatalf("%s %s: request was modified to %q", method, c.Target, r.URL.Path)
+		}
+	}
+
+	if _, err = New(
+		WithFileSystem(fstest.MapFS{"app.js": {Data: testScript}}),
+		WithSinglePageApplication("index.html"),
+	); err == nil {
+		t.Fatal("missing single-page application file was accepted")
+	}
+}
@@ -4,15 +4,26 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"net/http"
+	"sync/atomic"
 )
 
 type FS struct {
-	index  map[string]string
-	source http.Handler
+	index            atomic.Pointer[fileIndex]
+	files            fs.FS
+	directoryIndex   string
+	fallback         string
+	fallbackExcluded []string
+	notFoundPage     string
+	liveReload       *liveReload
 }
 
+// New indexes the files of [WithFileSystem]. Precompressed siblings,
+// such as "app.js.br", "app.js.zst", and "app.js.gz", are not indexed
+// by themselves, but served in place of "app.js" to clients that
+// accept the content encoding. The index does not change after
+// construction, unless [WithLiveReload] is set.
 func New(withOptions ...Option) (_ *FS, err error) {
+	var index *fileIndex
 	o := &options{Index: make(map[string]string)}
 	for _, option := range append(
 		withOptions,
@@ -20,6 +31,17 @@ func New(withOptions ...Option) (_ *FS, err error) {
 			if o.FileSystem == nil {
 				return errors.New("file system is required")
 			}
+			if o.LiveReload != nil && o.Directory == "" {
+				return errors.New("live reload requires a directory")
+			}
+			for _, page := range []string{o.Fallback, o.NotFoundPage} {
+				if page == "" {
+					continue
+				}
+				if info, err := fs.Stat(o.FileSystem, page); err != nil || info.IsDir() {
+					return fmt.Errorf("page %q is not a file in the file system", page)
+				}
+			}
 			if len(o.Translators) == 0 {
 				if err := WithPathTranslators(
 					func(real string) (external string, accept bool, err error) {
@@ -29,36 +51,8 @@ func New(withOptions ...Option) (_ *FS, err error) {
 					return err
 				}
 			}
-
-			if err = fs.WalkDir(o.FileSystem, ".",
-				func(path string, d fs.DirEntry, err error) error {
-					if err != nil {
-						return err
-					}
-					if d.IsDir() {
-						return nil // skip directories
-					}
-
-					external := path
-					accept := false
-					for _, translator := range o.Translators {
-						external, accept, err = translator(external)
-						if err != nil {
-							return err
-						}
-						if !accept {
-							return nil // skip, choice of the translator
-						}
-						if err = WithPath(external, path)(o); err != nil {
-							return err
-						}
-					}
-					return nil
-				},
-			); err != nil {
-				return fmt.Errorf("cannot index files from the file system: %w", err)
-			}
-			return nil
+			index, err = o.build()
+			return err
 		},
 	) {
 		if err = option(o); err != nil {
@@ -66,12 +60,21 @@ func New(withOptions ...Option) (_ *FS, err error) {
 		}
 	}
 
-	return &FS{
-		index:  o.Index,
-		source: http.FileServer(http.FS(o.FileSystem)),
-	}, nil
+	fs := &FS{
+		files:            o.FileSystem,
+		directoryIndex:   o.DirectoryIndex,
+		fallback:         o.Fallback,
+		fallbackExcluded: o.FallbackExcluded,
+		notFoundPage:     o.NotFoundPage,
+	}
+	fs.index.Store(index)
+	if o.LiveReload != nil {
+		fs.liveReload = newLiveReload()
+		go fs.watch(o)
+	}
+	return fs, nil
 }
 
 func (fs *FS) String() string {
-	return fmt.Sprintf("%+v", fs.index)
+	return fmt.Sprintf("%+v", fs.index.Load().paths)
 }
@@ -0,0 +1,114 @@
+package staticfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var testScript = []byte(strings.Repeat("console.log('compressible');\n", 64))
+
+func request(t *testing.T, h http.Handler, target, acceptEncoding string) *http.Response {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func TestPrecompressedSiblings(t *testing.T) {
+	fs, err := New(WithFileSystem(fstest.MapFS{
+		"app.js":     {Data: testScript},
+		"app.js.br":  {Data: []byte("brotli")},
+		"app.js.gz":  {Data: []byte("gzip")},
+		"orphan.gz":  {Data: []byte("archive")},
+		"index.html": {Data: []byte("<html></html>")},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fs.index.Load().paths["/app.js.br"]; ok {
+		t.Fatal("precompressed sibling was indexed")
+	}
+	if _, ok := fs.index.Load().paths["/orphan.gz"]; !ok {
+		t.Fatal("file without an original was not indexed")
+	}
+
+	for acceptEncoding, expected := range map[string]string{
+		"gzip, deflate, br": "brotli",
+		"gzip, br;q=0":      "gzip",
+		"*":                 "brotli",
+		"identity":          string(testScript),
+		"":                  string(testScript),
+	} {
+		response := request(t, fs, "/app.js", acceptEncoding)
+		body, _ := io.ReadAll(response.Body)
+		if string(body) != expected {
+			t.Fatalf("%q: unexpected body %q", acceptEncoding, body)
+		}
+		if response.Header.Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("%q: vary header is missing", acceptEncoding)
+		}
+		if !strings.HasPrefix(response.Header.Get("Content-Type"), "text/javascript") {
+			t.Fatalf("%q: unexpected content type %q", acceptEncoding, response.Header.Get("Content-Type"))
+		}
+		if response.Header.Get("Content-Length") != strconv.Itoa(len(expected)) {
+			t.Fatalf("%q: unexpected content length %q", acceptEncoding, response.Header.Get("Content-Length"))
+		}
+	}
+	if response := request(t, fs, "/index.html", "gzip"); response.Header.Get("Vary") != "" {
+		t.Fatal("vary header set for a file without variants")
+	}
+}
+
+func TestFastFileSystemCompression(t *testing.T) {
+	h, err := NewFastFileSystem(
+		WithFastFileSystemFile("/app.js", testScript),
+		WithFastFileSystemFile("/tiny.txt", []byte("tiny")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := request(t, h, "/app.js", "br, gzip")
+	body, _ := io.ReadAll(response.Body)
+	if response.Header.Get("Content-Encoding") != EncodingGzip {
+		t.Fatal("response was not compressed")
+	}
+	if response.Header.Get("Content-Length") != strconv.Itoa(len(body)) || len(body) >= len(testScript) {
+		t.Fatalf("unexpected content length %q for %d bytes", response.Header.Get("Content-Length"), len(body))
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, _ := io.ReadAll(reader); !bytes.Equal(decoded, testScript) {
+		t.Fatal("compressed contents do not match")
+	}
+
+	response = request(t, h, "/app.js", "gzip;q=0")
+	if response.Header.Get("Content-Encoding") != "" || response.Header.Get("Vary") != "Accept-Encoding" {
+		t.Fatal("refused encoding was used")
+	}
+	response = request(t, h, "/tiny.txt", "gzip")
+	if response.Header.Get("Content-Encoding") != "" || response.Header.Get("Vary") != "" {
+		t.Fatal("incompressible file was compressed")
+	}
+
+	if _, err = NewFastFileSystem(
+		WithFastFileSystemFile("/app.js", testScript),
+		WithFastFileSystemCompressor(EncodingGzip, CompressGzip),
+		WithFastFileSystemCompressor(EncodingGzip, CompressGzip),
+	); err == nil {
+		t.Fatal("duplicate compressor was accepted")
+	}
+}
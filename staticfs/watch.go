@@ -0,0 +1,90 @@
+package staticfs
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const pollInterval = time.Millisecond * 500
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// pollDirectory reports changed files by comparing directory
+// listings. It is the fallback where file events are not available.
+func pollDirectory(ctx context.Context, root string, changes chan<- string) error {
+	previous, err := listFiles(root)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		current, err := listFiles(root)
+		if err != nil {
+			return err
+		}
+		for p, state := range current {
+			if before, ok := previous[p]; !ok || before != state {
+				if !send(ctx, changes, p) {
+					return nil
+				}
+			}
+		}
+		for p := range previous {
+			if _, ok := current[p]; !ok {
+				if !send(ctx, changes, p) {
+					return nil
+				}
+			}
+		}
+		previous = current
+	}
+}
+
+func listFiles(root string) (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	return files, filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // removed while walking
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		relative, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relative)] = fileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		}
+		return nil
+	})
+}
+
+func send(ctx context.Context, changes chan<- string, p string) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case changes <- p:
+		return true
+	}
+}
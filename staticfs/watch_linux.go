@@ -0,0 +1,94 @@
+//go:build linux
+
+package staticfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// watchDirectory reports changed files relative to the root
+// using inotify until the context is done. Falls back to polling,
+// if inotify is not available.
+func watchDirectory(ctx context.Context, root string, changes chan<- string) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return pollDirectory(ctx, root, changes)
+	}
+	events := os.NewFile(uintptr(fd), "inotify") // non-blocking descriptor is pollable
+	defer events.Close()
+	go func() {
+		<-ctx.Done()
+		_ = events.Close() // interrupts reading
+	}()
+
+	watches := make(map[int32]string)
+	watch := func(directory string) error {
+		return filepath.WalkDir(filepath.Join(root, filepath.FromSlash(directory)),
+			func(p string, d fs.DirEntry, err error) error {
+				if err != nil || !d.IsDir() {
+					return nil
+				}
+				wd, err := syscall.InotifyAddWatch(fd, p, inotifyMask)
+				if err != nil {
+					return err
+				}
+				relative, err := filepath.Rel(root, p)
+				if err != nil {
+					return err
+				}
+				watches[int32(wd)] = filepath.ToSlash(relative)
+				return nil
+			},
+		)
+	}
+	if err = watch("."); err != nil {
+		return err
+	}
+
+	buffer := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := events.Read(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			wd := int32(binary.NativeEndian.Uint32(buffer[offset:]))
+			mask := binary.NativeEndian.Uint32(buffer[offset+4:])
+			length := int(binary.NativeEndian.Uint32(buffer[offset+12:]))
+			start := offset + syscall.SizeofInotifyEvent
+			name := string(bytes.TrimRight(buffer[start:start+length], "\x00"))
+			offset = start + length
+
+			directory, ok := watches[wd]
+			if !ok {
+				continue
+			}
+			if mask&syscall.IN_IGNORED != 0 {
+				delete(watches, wd) // directory was removed
+				continue
+			}
+			p := path.Join(directory, name)
+			if mask&syscall.IN_ISDIR != 0 && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				if err = watch(p); err != nil {
+					return err
+				}
+			}
+			if !send(ctx, changes, p) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package staticfs
+
+import "context"
+
+// watchDirectory reports changed files relative to the root
+// until the context is done.
+func watchDirectory(ctx context.Context, root string, changes chan<- string) error {
+	return pollDirectory(ctx, root, changes)
+}
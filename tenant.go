@@ -0,0 +1,126 @@
+package htadaptor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/dkotik/htadaptor/middleware/tenant"
+)
+
+// TenantOptions returns the adaptor options of a tenant,
+// such as [WithTemplate] with the templates of its brand.
+type TenantOptions func(*tenant.Tenant) ([]Option, error)
+
+// AdaptPerTenant adapts a domain call separately for each tenant
+// resolved by [tenant.New] middleware. Tenant options are applied
+// after the adaptor options. The adapt function is usually
+// a closure over one of the adaptor methods:
+//
+//	handler, err := htadaptor.New().AdaptPerTenant(
+//		func(t *tenant.Tenant) ([]htadaptor.Option, error) {
+//			return []htadaptor.Option{
+//				htadaptor.WithTemplate(templates[t.ID]),
+//			}, nil
+//		},
+//		func(a htadaptor.Adaptor) (http.Handler, error) {
+//			return a.AdaptFunc(domainCall)
+//		},
+//	)
+//
+// Handlers are created on the first request of each tenant and
+// reused while the middleware resolves the same [tenant.Tenant].
+// A tenant resolved anew, once its cache entry expires, gets a new
+// handler with fresh tenant options. At most
+// [tenant.DefaultCacheCapacity] handlers are kept. Requests without
+// a tenant in their context fail with [tenant.ErrUnidentifiedTenant].
+func (a Adaptor) AdaptPerTenant(
+	tenantOptions TenantOptions,
+	adapt func(Adaptor) (http.Handler, error),
+) (http.Handler, error) {
+	if tenantOptions == nil {
+		return nil, errors.New("nil tenant options")
+	}
+	if adapt == nil {
+		return nil, errors.New("nil adapt function")
+	}
+	o, err := a.initialize(nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = adapt(a); err != nil {
+		return nil, err
+	}
+	return &tenantAdaptor{
+		adaptor:       a,
+		tenantOptions: tenantOptions,
+		adapt:         adapt,
+		errorHandler:  o.ErrorHandler,
+		handlers:      make(map[string]tenantHandler),
+	}, nil
+}
+
+type tenantAdaptor struct {
+	adaptor       Adaptor
+	tenantOptions TenantOptions
+	adapt         func(Adaptor) (http.Handler, error)
+	errorHandler  ErrorHandler
+
+	mu       sync.Mutex
+	handlers map[string]tenantHandler // by tenant ID
+}
+
+type tenantHandler struct {
+	tenant  *tenant.Tenant // that the handler was adapted for
+	handler http.Handler
+}
+
+func (t *tenantAdaptor) handler(current *tenant.Tenant) (http.Handler, error) {
+	t.mu.Lock()
+	existing, ok := t.handlers[current.ID]
+	t.mu.Unlock()
+	if ok && existing.tenant == current {
+		return existing.handler, nil
+	}
+	withOptions, err := t.tenantOptions(current)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get options of tenant %q: %w", current.ID, err)
+	}
+	h, err := t.adapt(Adaptor{
+		options: append(slices.Clip(t.adaptor.options), withOptions...),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot adapt for tenant %q: %w", current.ID, err)
+	}
+	if h == nil {
+		return nil, fmt.Errorf("cannot adapt for tenant %q: <nil> handler", current.ID)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok = t.handlers[current.ID]; !ok && len(t.handlers) >= tenant.DefaultCacheCapacity {
+		for ID := range t.handlers { // any, since handlers are cheap to adapt again
+			delete(t.handlers, ID)
+			break
+		}
+	}
+	t.handlers[current.ID] = tenantHandler{tenant: current, handler: h}
+	return h, nil
+}
+
+// ServeHTTP satisfies [http.Handler] interface.
+func (t *tenantAdaptor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	current, ok := tenant.TenantFromContext(r.Context())
+	if !ok {
+		_ = t.errorHandler.HandleError(w, r, tenant.ErrUnidentifiedTenant)
+		return
+	}
+	h, err := t.handler(current)
+	if err != nil {
+		_ = t.errorHandler.HandleError(w, r, err)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+
+	"github.com/dkotik/htadaptor/middleware/authorize"
 )
 
 // AdaptFunc creates a new adaptor for a
@@ -20,11 +22,13 @@ func (a Adaptor) AdaptFunc[T any, V Validatable[T], O any](
 		return nil, err
 	}
 	return &UnaryFuncAdaptor[T, V, O]{
-		domainCall:   domainCall,
-		statusCode:   o.StatusCode,
-		encoder:      o.Encoder,
-		decoder:      o.Decoder,
-		errorHandler: o.ErrorHandler,
+		domainCall:    domainCall,
+		statusCode:    o.StatusCode,
+		encoder:       o.Encoder,
+		decoder:       o.Decoder,
+		errorHandler:  o.ErrorHandler,
+		authorization: o.Authorization,
+		instrument:    o.Instrument,
 	}, nil
 }
 
@@ -32,34 +36,48 @@ func (a Adaptor) AdaptFunc[T any, V Validatable[T], O any](
 // and calls a domain function with it expecting
 // a struct response.
 type UnaryFuncAdaptor[T any, V Validatable[T], O any] struct {
-	domainCall   func(context.Context, V) (O, error)
-	statusCode   int
-	decoder      Decoder
-	encoder      Encoder
-	errorHandler ErrorHandler
+	domainCall    func(context.Context, V) (O, error)
+	statusCode    int
+	decoder       Decoder
+	encoder       Encoder
+	errorHandler  ErrorHandler
+	authorization authorize.Policy
+	instrument    Instrument
 }
 
 func (a *UnaryFuncAdaptor[T, V, O]) executeDomainCall(
 	w http.ResponseWriter,
 	r *http.Request,
 ) (err error) {
+	ctx := r.Context()
 	var request V = new(T)
+	_, end := beginStage(ctx, a.instrument, StageDecode)
 	if err = a.decoder.Decode(request, r); err != nil {
-		return NewDecodingError(err)
+		err = NewDecodingError(err)
+	}
+	if end(err); err != nil {
+		return err
 	}
 
-	ctx := r.Context()
-	if err = request.Validate(ctx); err != nil {
+	_, end = beginStage(ctx, a.instrument, StageValidate)
+	err = request.Validate(ctx)
+	if end(err); err != nil {
 		return err
 	}
-	response, err := a.domainCall(ctx, request)
-	if err != nil {
+	if err = authorizeRequest(ctx, a.instrument, a.authorization, request); err != nil {
+		return err
+	}
+	domainCtx, end := beginStage(ctx, a.instrument, StageDomainCall)
+	response, err := a.domainCall(domainCtx, request)
+	if end(err); err != nil {
 		return err
 	}
+	_, end = beginStage(ctx, a.instrument, StageEncode)
 	if err = a.encoder.Encode(w, r, a.statusCode, response); err != nil {
-		return NewEncodingError(err)
+		err = NewEncodingError(err)
 	}
-	return nil
+	end(err)
+	return err
 }
 
 // ServeHTTP satisfies [http.Handler] interface.
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/dkotik/htadaptor/extract"
+	"github.com/dkotik/htadaptor/middleware/authorize"
 )
 
 // AdaptStringFunc creates a new adaptor for a
@@ -31,6 +32,8 @@ func (a Adaptor) AdaptStringFunc[O any](
 		statusCode:      o.StatusCode,
 		encoder:         o.Encoder,
 		errorHandler:    o.ErrorHandler,
+		authorization:   o.Authorization,
+		instrument:      o.Instrument,
 	}, nil
 }
 
@@ -43,24 +46,37 @@ type UnaryStringFuncAdaptor[O any] struct {
 	statusCode      int
 	encoder         Encoder
 	errorHandler    ErrorHandler
+	authorization   authorize.Policy
+	instrument      Instrument
 }
 
 func (a *UnaryStringFuncAdaptor[O]) executeDomainCall(
 	w http.ResponseWriter,
 	r *http.Request,
 ) (err error) {
+	ctx := r.Context()
+	_, end := beginStage(ctx, a.instrument, StageDecode)
 	value, err := a.stringExtractor.ExtractStringValue(r)
 	if err != nil {
-		return NewDecodingError(err)
+		err = NewDecodingError(err)
 	}
-	response, err := a.domainCall(r.Context(), value)
-	if err != nil {
+	if end(err); err != nil {
+		return err
+	}
+	if err = authorizeRequest(ctx, a.instrument, a.authorization, value); err != nil {
+		return err
+	}
+	domainCtx, end := beginStage(ctx, a.instrument, StageDomainCall)
+	response, err := a.domainCall(domainCtx, value)
+	if end(err); err != nil {
 		return err
 	}
+	_, end = beginStage(ctx, a.instrument, StageEncode)
 	if err = a.encoder.Encode(w, r, a.statusCode, response); err != nil {
-		return NewEncodingError(err)
+		err = NewEncodingError(err)
 	}
-	return nil
+	end(err)
+	return err
 }
 
 // ServeHTTP satisfies [http.Handler] interface.
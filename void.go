@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+
+	"github.com/dkotik/htadaptor/middleware/authorize"
 )
 
 // AdaptVoidFunc creates a new adaptor for a
@@ -23,33 +25,48 @@ func (a Adaptor) AdaptVoidFunc[T any, V Validatable[T]](
 		domainCall: domainCall,
 		// statusCode:   a.statusCode,
 		// encoder:      a.encoder,
-		decoder:      o.Decoder,
-		errorHandler: o.ErrorHandler,
+		decoder:       o.Decoder,
+		errorHandler:  o.ErrorHandler,
+		authorization: o.Authorization,
+		instrument:    o.Instrument,
 	}, nil
 }
 
 // VoidStringFuncAdaptor calls a domain function with decoded
 // request without returning no response other than an error.
 type VoidFuncAdaptor[T any, V Validatable[T]] struct {
-	domainCall   func(context.Context, V) error
-	decoder      Decoder
-	errorHandler ErrorHandler
+	domainCall    func(context.Context, V) error
+	decoder       Decoder
+	errorHandler  ErrorHandler
+	authorization authorize.Policy
+	instrument    Instrument
 }
 
 func (a *VoidFuncAdaptor[T, V]) executeDomainCall(
 	w http.ResponseWriter,
 	r *http.Request,
 ) (err error) {
+	ctx := r.Context()
 	var request V = new(T)
-	if err := a.decoder.Decode(request, r); err != nil {
-		return NewDecodingError(err)
+	_, end := beginStage(ctx, a.instrument, StageDecode)
+	if err = a.decoder.Decode(request, r); err != nil {
+		err = NewDecodingError(err)
+	}
+	if end(err); err != nil {
+		return err
 	}
 
-	ctx := r.Context()
-	if err = request.Validate(ctx); err != nil {
+	_, end = beginStage(ctx, a.instrument, StageValidate)
+	err = request.Validate(ctx)
+	if end(err); err != nil {
+		return err
+	}
+	if err = authorizeRequest(ctx, a.instrument, a.authorization, request); err != nil {
 		return err
 	}
-	if err = a.domainCall(ctx, request); err != nil {
+	domainCtx, end := beginStage(ctx, a.instrument, StageDomainCall)
+	err = a.domainCall(domainCtx, request)
+	if end(err); err != nil {
 		return err
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/dkotik/htadaptor/extract"
+	"github.com/dkotik/htadaptor/middleware/authorize"
 )
 
 // AdaptStringFunc creates a new adaptor for a
@@ -29,6 +30,8 @@ func (a Adaptor) AdaptVoidStringFunc(
 		domainCall:      domainCall,
 		stringExtractor: stringExtractor,
 		errorHandler:    o.ErrorHandler,
+		authorization:   o.Authorization,
+		instrument:      o.Instrument,
 	}, nil
 }
 
@@ -39,17 +42,29 @@ type VoidStringFuncAdaptor struct {
 	domainCall      func(context.Context, string) error
 	stringExtractor extract.StringValueExtractor
 	errorHandler    ErrorHandler
+	authorization   authorize.Policy
+	instrument      Instrument
 }
 
 func (a *VoidStringFuncAdaptor) executeDomainCall(
 	w http.ResponseWriter,
 	r *http.Request,
 ) (err error) {
+	ctx := r.Context()
+	_, end := beginStage(ctx, a.instrument, StageDecode)
 	value, err := a.stringExtractor.ExtractStringValue(r)
 	if err != nil {
-		return NewDecodingError(err)
+		err = NewDecodingError(err)
 	}
-	if err = a.domainCall(r.Context(), value); err != nil {
+	if end(err); err != nil {
+		return err
+	}
+	if err = authorizeRequest(ctx, a.instrument, a.authorization, value); err != nil {
+		return err
+	}
+	domainCtx, end := beginStage(ctx, a.instrument, StageDomainCall)
+	err = a.domainCall(domainCtx, value)
+	if end(err); err != nil {
 		return err
 	}
 	w.WriteHeader(http.StatusNoContent)